@@ -25,6 +25,7 @@ import (
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/update"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/version"
 	"github.com/pkg/errors"
@@ -52,6 +53,7 @@ func NewSkaffoldCommand(out, err io.Writer) *cobra.Command {
 			return err
 		}
 		rootCmd.SilenceUsage = true
+		opts.Command = cmd.Name()
 		logrus.Infof("Skaffold %+v", version.Get())
 		go func() {
 			if err := updateCheck(updateMsg); err != nil {
@@ -74,6 +76,7 @@ func NewSkaffoldCommand(out, err io.Writer) *cobra.Command {
 	rootCmd.AddCommand(NewCmdVersion(out))
 	rootCmd.AddCommand(NewCmdRun(out))
 	rootCmd.AddCommand(NewCmdDev(out))
+	rootCmd.AddCommand(NewCmdDebug(out))
 	rootCmd.AddCommand(NewCmdBuild(out))
 	rootCmd.AddCommand(NewCmdDeploy(out))
 	rootCmd.AddCommand(NewCmdDelete(out))
@@ -81,6 +84,8 @@ func NewSkaffoldCommand(out, err io.Writer) *cobra.Command {
 	rootCmd.AddCommand(NewCmdConfig(out))
 	rootCmd.AddCommand(NewCmdInit(out))
 	rootCmd.AddCommand(NewCmdDiagnose(out))
+	rootCmd.AddCommand(NewCmdSchema(out))
+	rootCmd.AddCommand(NewCmdInspect(out))
 
 	rootCmd.PersistentFlags().StringVarP(&v, "verbosity", "v", constants.DefaultLogLevel.String(), "Log level (debug, info, warn, error, fatal, panic)")
 
@@ -126,23 +131,85 @@ func setFlagsFromEnvVariables(commands []*cobra.Command) {
 
 			envVar := fmt.Sprintf("SKAFFOLD_%s", strings.Replace(strings.ToUpper(f.Name), "-", "_", -1))
 			if val, present := os.LookupEnv(envVar); present {
-				cmd.Flags().Set(f.Name, val)
+				if err := cmd.Flags().Set(f.Name, val); err != nil {
+					logrus.Warnf("Ignoring %s: %s", envVar, err)
+				}
 			}
 		})
 	}
 }
 
+// enumFlag is a string flag that only accepts a set of values, ignoring case.
+type enumFlag struct {
+	value   *string
+	allowed []string
+}
+
+func newEnumFlag(value *string, defaultValue string, allowed ...string) *enumFlag {
+	*value = defaultValue
+	return &enumFlag{
+		value:   value,
+		allowed: allowed,
+	}
+}
+
+func (f *enumFlag) String() string {
+	return *f.value
+}
+
+func (f *enumFlag) Set(value string) error {
+	for _, allowed := range f.allowed {
+		if strings.EqualFold(value, allowed) {
+			*f.value = allowed
+			return nil
+		}
+	}
+	return fmt.Errorf("%q should be one of %s", value, strings.Join(f.allowed, ", "))
+}
+
+func (f *enumFlag) Type() string {
+	return "string"
+}
+
 func AddRunDeployFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&opts.Tail, "tail", false, "Stream logs from deployed objects")
 	cmd.Flags().StringArrayVarP(&opts.CustomLabels, "label", "l", nil, "Add custom labels to deployed objects. Set multiple times for multiple labels.")
+	AddLogFlags(cmd)
 }
 
 func AddRunDevFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&opts.ConfigurationFile, "filename", "f", "skaffold.yaml", "Filename or URL to the pipeline file")
 	cmd.Flags().BoolVar(&opts.Notification, "toot", false, "Emit a terminal beep after the deploy is complete")
-	cmd.Flags().StringArrayVarP(&opts.Profiles, "profile", "p", nil, "Activate profiles by name")
+	cmd.Flags().StringArrayVarP(&opts.Profiles, "profile", "p", nil, "Activate profiles by name. Prefix a profile name with '-' to deactivate it")
 	cmd.Flags().StringVarP(&opts.Namespace, "namespace", "n", "", "Run deployments in the specified namespace")
 	cmd.Flags().StringVarP(&opts.DefaultRepo, "default-repo", "d", "", "Default repository value (overrides global config)")
+	cmd.Flags().StringArrayVar(&opts.Modules, "module", nil, "Only merge the required pipeline files of these modules. Set multiple times for multiple modules")
+	cmd.Flags().StringVar(&opts.VarFile, "var-file", "", "YAML file of values available to the templates of the pipeline file")
+}
+
+// AddDevDebugFlags adds the flags shared by `dev` and `debug`.
+func AddDevDebugFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&opts.TailDev, "tail", true, "Stream logs from deployed objects")
+	cmd.Flags().Var(newEnumFlag(&opts.Trigger, "polling", "polling", "manual"), "trigger", "How are changes detected? (polling or manual)")
+	cmd.Flags().BoolVar(&opts.Cleanup, "cleanup", true, "Delete deployments after dev mode is interrupted")
+	cmd.Flags().StringArrayVarP(&opts.Watch, "watch-image", "w", nil, "Choose which artifacts to watch. Artifacts with image names that contain the expression will be watched only. Default is to watch sources for all artifacts")
+	cmd.Flags().IntVarP(&opts.WatchPollInterval, "watch-poll-interval", "i", 1000, "Interval (in ms) between two checks for file changes")
+	cmd.Flags().BoolVar(&opts.PortForward, "port-forward", true, "Port-forward exposed container ports within pods and the resources listed in the pipeline's portForward section")
+	cmd.Flags().StringArrayVarP(&opts.CustomLabels, "label", "l", nil, "Add custom labels to deployed objects. Set multiple times for multiple labels")
+	AddLogFlags(cmd)
+}
+
+// AddLogFlags adds the flags that configure how logs are printed.
+func AddLogFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&opts.Logs.IncludePods, "log-include-pod", nil, "Only print the logs of pods matching this regular expression. Set multiple times for multiple expressions")
+	cmd.Flags().StringArrayVar(&opts.Logs.ExcludePods, "log-exclude-pod", nil, "Don't print the logs of pods matching this regular expression. Set multiple times for multiple expressions")
+	cmd.Flags().StringArrayVar(&opts.Logs.IncludeContainers, "log-include-container", nil, "Only print the logs of containers matching this regular expression. Set multiple times for multiple expressions")
+	cmd.Flags().StringArrayVar(&opts.Logs.ExcludeContainers, "log-exclude-container", nil, "Don't print the logs of containers matching this regular expression. Set multiple times for multiple expressions")
+	cmd.Flags().StringArrayVar(&opts.Logs.IncludeLines, "log-include", nil, "Only print the log lines matching this regular expression. Set multiple times for multiple expressions")
+	cmd.Flags().StringArrayVar(&opts.Logs.ExcludeLines, "log-exclude", nil, "Don't print the log lines matching this regular expression. Set multiple times for multiple expressions")
+	cmd.Flags().BoolVar(&opts.Logs.JSON, "log-json", false, "Pretty-print JSON log lines")
+	cmd.Flags().StringSliceVar(&opts.Logs.JSONFields, "log-json-fields", kubernetes.DefaultJSONLogFields, "Fields of JSON log lines to print")
+	cmd.Flags().StringVar(&opts.Logs.File, "log-file", "", "Also write the logs to this file")
 }
 
 func SetUpLogs(out io.Writer, level string) error {
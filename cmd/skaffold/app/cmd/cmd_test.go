@@ -57,3 +57,26 @@ func TestReadConfiguration(t *testing.T) {
 		testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expectedCfg, cfg)
 	}
 }
+
+func TestEnumFlag(t *testing.T) {
+	var tests = []struct {
+		description string
+		value       string
+		shouldErr   bool
+		expected    string
+	}{
+		{description: "allowed value", value: "manual", expected: "manual"},
+		{description: "case is ignored", value: "Manual", expected: "manual"},
+		{description: "unknown value", value: "notify", shouldErr: true, expected: "polling"},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var trigger string
+			flag := newEnumFlag(&trigger, "polling", "polling", "manual")
+
+			err := flag.Set(test.value)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, trigger)
+		})
+	}
+}
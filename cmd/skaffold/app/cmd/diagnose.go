@@ -18,53 +18,207 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path/filepath"
 	"time"
 
+	configutil "github.com/GoogleContainerTools/skaffold/cmd/skaffold/app/cmd/config"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/diagnose"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/version"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/watch"
 
+	units "github.com/docker/go-units"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+var (
+	diagnoseOutput    string
+	diagnoseContext   bool
+	writeDockerignore bool
+)
+
+// contextTop is the number of files and directories listed by the context analysis.
+const contextTop = 10
+
 // NewCmdDiagnose describes the CLI command to diagnose skaffold.
 func NewCmdDiagnose(out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "diagnose",
-		Short: "Run a diagnostic on Skaffold",
+		Short: "Run a diagnostic on Skaffold and its environment",
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return doDiagnose(out)
 		},
 	}
 	cmd.Flags().StringVarP(&opts.ConfigurationFile, "filename", "f", "skaffold.yaml", "Filename or URL to the pipeline file")
+	cmd.Flags().StringArrayVarP(&opts.Profiles, "profile", "p", nil, "Activate profiles by name. Prefix a profile name with '-' to deactivate it")
+	cmd.Flags().StringVarP(&opts.DefaultRepo, "default-repo", "d", "", "Default repository value (overrides global config)")
+	cmd.Flags().StringVarP(&diagnoseOutput, "output", "o", "text", "Output format: text or json")
+	cmd.Flags().BoolVar(&diagnoseContext, "context", false, "Explain what is in the build context of each Docker artifact and suggest .dockerignore entries")
+	cmd.Flags().BoolVar(&writeDockerignore, "write-dockerignore", false, "With --context, add the suggested entries to the .dockerignore files")
 	return cmd
 }
 
 func doDiagnose(out io.Writer) error {
-	_, config, err := newRunner(out, opts)
+	if diagnoseOutput != "text" && diagnoseOutput != "json" {
+		return fmt.Errorf("unknown output format %s, should be text or json", diagnoseOutput)
+	}
+	if writeDockerignore && !diagnoseContext {
+		return errors.New("--write-dockerignore requires --context")
+	}
+
+	config, err := schema.LoadPipeline(out, opts.ConfigurationFile, opts)
 	if err != nil {
-		return errors.Wrap(err, "creating runner")
+		return errors.Wrap(err, "loading skaffold config")
+	}
+
+	defaultRepo, err := configutil.GetDefaultRepo(opts.DefaultRepo)
+	if err != nil {
+		return errors.Wrap(err, "getting default repo")
+	}
+
+	env := &diagnose.Environment{
+		Pipeline:    config,
+		DefaultRepo: defaultRepo,
+	}
+	ctx := context.Background()
+	results := diagnose.Run(ctx, env, diagnose.DefaultChecks())
+
+	var contexts []contextAnalysis
+	if diagnoseContext {
+		if contexts, err = analyzeContexts(ctx, config.Build.Artifacts); err != nil {
+			return errors.Wrap(err, "analyzing build contexts")
+		}
 	}
 
-	fmt.Fprintln(out, "Skaffold version:", version.Get().GitCommit)
-	fmt.Fprintln(out, "Configuration version:", config.APIVersion)
-	fmt.Fprintln(out, "Number of artifacts:", len(config.Build.Artifacts))
+	if diagnoseOutput == "json" {
+		encoded, err := json.MarshalIndent(struct {
+			Version       string            `json:"version"`
+			ConfigVersion string            `json:"configVersion"`
+			Checks        []diagnose.Result `json:"checks"`
+			Contexts      []contextAnalysis `json:"contexts,omitempty"`
+		}{version.Get().Version, config.APIVersion, results, contexts}, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "marshalling diagnostic")
+		}
+		fmt.Fprintln(out, string(encoded))
+	} else {
+		fmt.Fprintln(out, "Skaffold version:", version.Get().GitCommit)
+		fmt.Fprintln(out, "Configuration version:", config.APIVersion)
+		fmt.Fprintln(out, "Number of artifacts:", len(config.Build.Artifacts))
 
-	if err := diagnoseArtifacts(out, config.Build.Artifacts); err != nil {
-		return errors.Wrap(err, "running diagnostic on artifacts")
+		printResults(out, results)
+
+		if diagnoseContext {
+			printContexts(out, contexts)
+		} else if err := diagnoseArtifacts(out, config.Build.Artifacts); err != nil {
+			return errors.Wrap(err, "running diagnostic on artifacts")
+		}
+	}
+
+	if writeDockerignore {
+		for _, c := range contexts {
+			if len(c.Suggestions) == 0 {
+				continue
+			}
+			var entries []string
+			for _, suggestion := range c.Suggestions {
+				entries = append(entries, suggestion.Path)
+			}
+			if err := docker.AppendDockerignore(c.Workspace, entries); err != nil {
+				return errors.Wrapf(err, "writing .dockerignore of %s", c.Image)
+			}
+			if diagnoseOutput == "text" {
+				fmt.Fprintf(out, "\nAdded %d entries to %s\n", len(entries), filepath.Join(c.Workspace, ".dockerignore"))
+			}
+		}
 	}
 
+	if diagnose.Failed(results) {
+		return errors.New("some checks of the environment failed")
+	}
 	return nil
 }
 
+// printResults prints one line per check, with the hint on how to fix it below.
+func printResults(out io.Writer, results []diagnose.Result) {
+	color.Default.Fprintln(out, "\nEnvironment:")
+	for _, result := range results {
+		switch result.Status {
+		case diagnose.Pass:
+			color.Green.Fprint(out, " ✓ ")
+		case diagnose.Warn:
+			color.Yellow.Fprint(out, " ! ")
+		default:
+			color.Red.Fprint(out, " ✗ ")
+		}
+		fmt.Fprintf(out, "%s: %s\n", result.Check, result.Message)
+		if result.Hint != "" {
+			fmt.Fprintf(out, "   hint: %s\n", result.Hint)
+		}
+	}
+}
+
+// contextAnalysis is the analysis of the build context of a Docker artifact.
+type contextAnalysis struct {
+	Image     string `json:"image"`
+	Workspace string `json:"workspace"`
+	*docker.ContextAnalysis
+}
+
+func analyzeContexts(ctx context.Context, artifacts []*latest.Artifact) ([]contextAnalysis, error) {
+	var contexts []contextAnalysis
+	for _, artifact := range artifacts {
+		if artifact.DockerArtifact == nil {
+			continue
+		}
+
+		analysis, err := docker.AnalyzeContext(ctx, artifact.Workspace, artifact.DockerArtifact, contextTop)
+		if err != nil {
+			return nil, errors.Wrapf(err, "analyzing context of %s", artifact.ImageName)
+		}
+
+		contexts = append(contexts, contextAnalysis{
+			Image:           artifact.ImageName,
+			Workspace:       artifact.Workspace,
+			ContextAnalysis: analysis,
+		})
+	}
+	return contexts, nil
+}
+
+func printContexts(out io.Writer, contexts []contextAnalysis) {
+	for _, c := range contexts {
+		color.Default.Fprintf(out, "\nDocker artifact: %s\n", c.Image)
+		fmt.Fprintf(out, " - Context sent by skaffold: %s in %d files\n", units.HumanSize(float64(c.Size)), c.Files)
+		fmt.Fprintf(out, " - Context sent by docker build: %s in %d files\n", units.HumanSize(float64(c.FullSize)), c.FullFiles)
+		printFileSizes(out, "Largest files", c.LargestFiles)
+		printFileSizes(out, "Largest directories", c.LargestDirectories)
+		printFileSizes(out, fmt.Sprintf("Files never referenced by COPY or ADD: %s in %d files", units.HumanSize(float64(c.UnreferencedSize)), c.UnreferencedFiles), c.Unreferenced)
+		printFileSizes(out, "Suggested .dockerignore entries", c.Suggestions)
+	}
+}
+
+func printFileSizes(out io.Writer, title string, files []docker.FileSize) {
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, " - %s:\n", title)
+	for _, file := range files {
+		fmt.Fprintf(out, "   %10s  %s\n", units.HumanSize(float64(file.Size)), filepath.ToSlash(file.Path))
+	}
+}
+
 func diagnoseArtifacts(out io.Writer, artifacts []*latest.Artifact) error {
 	ctx := context.Background()
 
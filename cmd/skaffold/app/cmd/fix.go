@@ -24,6 +24,7 @@ import (
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	schemautil "github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -47,7 +48,7 @@ func NewCmdFix(out io.Writer) *cobra.Command {
 				return
 			}
 
-			if err := runFix(out, cfg); err != nil {
+			if err := runFix(out, opts.ConfigurationFile, cfg); err != nil {
 				logrus.Errorf("fix: %s", err)
 			}
 		},
@@ -58,22 +59,33 @@ func NewCmdFix(out io.Writer) *cobra.Command {
 	return cmd
 }
 
-func runFix(out io.Writer, cfg schemautil.VersionedConfig) error {
+func runFix(out io.Writer, configFile string, cfg schemautil.VersionedConfig) error {
 	cfg, err := schema.UpgradeToLatest(out, cfg)
 	if err != nil {
 		return err
 	}
 
-	newCfg, err := yaml.Marshal(cfg)
+	upgraded, err := yaml.Marshal(cfg)
 	if err != nil {
 		return errors.Wrap(err, "marshaling new config")
 	}
 
+	original, err := util.ReadConfiguration(configFile)
+	if err != nil {
+		return errors.Wrap(err, "reading config file")
+	}
+
+	// Only the parts of the config that changed are rewritten.
+	newCfg, err := schema.RewriteConfig(original, upgraded)
+	if err != nil {
+		return errors.Wrap(err, "rewriting config")
+	}
+
 	if overwrite {
-		if err := ioutil.WriteFile(opts.ConfigurationFile, newCfg, 0644); err != nil {
+		if err := ioutil.WriteFile(configFile, newCfg, 0644); err != nil {
 			return errors.Wrap(err, "writing config file")
 		}
-		color.Default.Fprintf(out, "New config at version %s generated and written to %s\n", cfg.GetVersion(), opts.ConfigurationFile)
+		color.Default.Fprintf(out, "New config at version %s generated and written to %s\n", cfg.GetVersion(), configFile)
 	} else {
 		out.Write(newCfg)
 	}
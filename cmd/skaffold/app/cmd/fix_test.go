@@ -93,6 +93,42 @@ deploy:
   kubectl:
     manifests:
     - k8s/deployment.yaml
+`, latest.Version),
+		},
+		{
+			name: "comments and ordering are preserved",
+			inputYaml: `# Pipeline of the app
+apiVersion: skaffold/v1alpha1
+kind: Config
+build:
+  # Artifacts are built with docker
+  artifacts:
+  - imageName: docker/image # main image
+    workspace: app
+  tagPolicy: sha256
+deploy:
+  kubectl:
+    manifests:
+    - paths:
+      - k8s/deployment.yaml
+# Local builds only
+`,
+			outputYaml: fmt.Sprintf(`# Pipeline of the app
+apiVersion: %s
+kind: Config
+build:
+  # Artifacts are built with docker
+  artifacts:
+  - image: docker/image # main image
+    context: app
+    docker: {}
+  tagPolicy:
+    sha256: {}
+deploy:
+  kubectl:
+    manifests:
+    - k8s/deployment.yaml
+# Local builds only
 `, latest.Version),
 		},
 	}
@@ -106,7 +142,7 @@ deploy:
 			t.Fatalf(err.Error())
 		}
 		var b bytes.Buffer
-		err = runFix(&b, cfg)
+		err = runFix(&b, cfgFile, cfg)
 
 		testutil.CheckErrorAndDeepEqual(t, tt.shouldErr, err, tt.outputYaml, b.String())
 	}
@@ -22,11 +22,9 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
-	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
@@ -41,15 +39,17 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
-// NoDockerfile allows users to specify they don't want to build
+// NoBuilder allows users to specify they don't want to build
 // an image we parse out from a kubernetes manifest
-const NoDockerfile = "None (image not built from these sources)"
+const NoBuilder = "None (image not built from these sources)"
 
 var (
 	composeFile  string
 	cliArtifacts []string
 	skipBuild    bool
 	force        bool
+	generateK8s  bool
+	analyze      bool
 )
 
 // NewCmdInit describes the CLI command to generate a skaffold configuration.
@@ -66,95 +66,69 @@ func NewCmdInit(out io.Writer) *cobra.Command {
 	cmd.Flags().BoolVar(&skipBuild, "skip-build", false, "Skip generating build artifacts in skaffold config")
 	cmd.Flags().BoolVar(&force, "force", false, "Force the generation of the skaffold config")
 	cmd.Flags().StringVar(&composeFile, "compose-file", "", "Initialize from a docker-compose file")
-	cmd.Flags().StringArrayVarP(&cliArtifacts, "artifact", "a", nil, "'='-delimited dockerfile/image pair to generate build artifact\n(example: --artifact=/web/Dockerfile.web=gcr.io/web-project/image)")
+	cmd.Flags().BoolVar(&generateK8s, "generate-manifests", false, "Generate a Deployment and a Service in k8s/ for each Dockerfile if no kubernetes manifests are found")
+	cmd.Flags().StringArrayVarP(&cliArtifacts, "artifact", "a", nil, "'='-delimited dockerfile/image pair, or a JSON builder with an image, to generate build artifact\n(example: --artifact=/web/Dockerfile.web=gcr.io/web-project/image)\n(example: --artifact='{\"type\":\"jibMaven\",\"path\":\"pom.xml\",\"image\":\"gcr.io/web-project/image\"}')")
+	cmd.Flags().BoolVar(&analyze, "analyze", false, "Print the builders, manifests and images found in the project as JSON, without generating the skaffold config")
 	return cmd
 }
 
 func doInit(out io.Writer) error {
+	if analyze && composeFile != "" {
+		return errors.New("--analyze can't be used with --compose-file")
+	}
+
 	rootDir := "."
 
+	var pairs []builderPair
+	var manifests []generatedManifest
+	var deploy latest.DeployConfig
 	if composeFile != "" {
-		// run kompose first to generate k8s manifests, then run skaffold init
-		logrus.Infof("running 'kompose convert' for file %s", composeFile)
-		komposeCmd := exec.Command("kompose", "convert", "-f", composeFile)
-		if err := util.RunCmd(komposeCmd); err != nil {
-			return errors.Wrap(err, "running kompose")
-		}
-	}
-
-	var potentialConfigs, k8sConfigs, dockerfiles, images []string
-	err := filepath.Walk(rootDir, func(path string, f os.FileInfo, e error) error {
-		if f.IsDir() {
-			return nil
-		}
-		if strings.HasPrefix(path, ".") {
-			return nil
-		}
-		if util.IsSupportedKubernetesFormat(path) {
-			potentialConfigs = append(potentialConfigs, path)
+		logrus.Infof("converting docker-compose file %s", composeFile)
+		var err error
+		manifests, pairs, err = convertComposeFile(composeFile)
+		if err != nil {
+			return errors.Wrap(err, "converting docker-compose file")
 		}
-		// try and parse dockerfile
-		if docker.ValidateDockerfile(path) {
-			logrus.Infof("existing dockerfile found: %s", path)
-			dockerfiles = append(dockerfiles, path)
+		if skipBuild {
+			pairs = nil
 		}
-		return nil
-	})
-	if err != nil {
-		return err
-	}
 
-	for _, file := range potentialConfigs {
-		if !force {
-			config, err := schema.ParseConfig(file, true)
-			if err == nil && config != nil {
-				return fmt.Errorf("pre-existing %s found", file)
-			}
+		var k8sConfigs []string
+		for _, manifest := range manifests {
+			k8sConfigs = append(k8sConfigs, manifest.Path)
 		}
-
-		logrus.Debugf("%s is not a valid skaffold configuration: continuing", file)
-		imgs, err := parseKubernetesYaml(file)
-		if err == nil {
-			logrus.Infof("found valid k8s yaml: %s", file)
-			k8sConfigs = append(k8sConfigs, file)
-			images = append(images, imgs...)
-		} else {
-			logrus.Infof("invalid k8s yaml %s: %s", file, err.Error())
-		}
-	}
-
-	var pairs []dockerfilePair
-	// conditionally generate build artifacts
-	if !skipBuild {
-		if len(dockerfiles) == 0 {
-			return errors.New("one or more valid Dockerfiles must be present to run skaffold; please provide at least one Dockerfile and try again")
+		deploy = deployConfig(k8sConfigs, nil, nil)
+	} else {
+		project, err := analyzeProject(rootDir)
+		if err != nil {
+			return err
 		}
 
-		if len(k8sConfigs) == 0 {
-			return errors.New("one or more valid kubernetes manifests is required to run skaffold")
+		if analyze {
+			return printAnalysis(out, project)
 		}
 
-		if cliArtifacts != nil {
-			pairs, err = processCliArtifacts(cliArtifacts)
-			if err != nil {
-				return errors.Wrap(err, "processing cli artifacts")
-			}
-		} else {
-			pairs = resolveDockerfileImages(dockerfiles, images)
+		pairs, manifests, err = resolveProject(project)
+		if err != nil {
+			return err
 		}
+		deploy = deployConfig(project.k8sConfigs, project.charts, project.kustomizations)
 	}
 
-	pipeline, err := generateSkaffoldPipeline(k8sConfigs, pairs)
+	pipeline, err := generateSkaffoldPipeline(deploy, pairs)
 	if err != nil {
 		return err
 	}
 
 	if opts.ConfigurationFile == "-" {
 		out.Write(pipeline)
-		return nil
+		return writeManifests(manifests)
 	}
 
 	if !force {
+		for _, manifest := range manifests {
+			fmt.Fprintf(out, "# %s\n%s\n", manifest.Path, manifest.Content)
+		}
 		fmt.Fprintln(out, string(pipeline))
 
 		reader := bufio.NewReader(os.Stdin)
@@ -177,6 +151,10 @@ func doInit(out io.Writer) error {
 		}
 	}
 
+	if err := writeManifests(manifests); err != nil {
+		return err
+	}
+
 	if err := ioutil.WriteFile(opts.ConfigurationFile, pipeline, 0644); err != nil {
 		return errors.Wrap(err, "writing config to file")
 	}
@@ -186,94 +164,283 @@ func doInit(out io.Writer) error {
 	return nil
 }
 
-func processCliArtifacts(artifacts []string) ([]dockerfilePair, error) {
-	var pairs []dockerfilePair
+// resolveProject pairs the builders with images. If no kubernetes manifests
+// were found, it generates them and adds them to the project.
+func resolveProject(project *projectAnalysis) ([]builderPair, []generatedManifest, error) {
+	// conditionally generate build artifacts
+	if skipBuild {
+		return nil, nil, nil
+	}
+
+	if len(project.builders) == 0 {
+		return nil, nil, errors.New("one or more valid Dockerfiles, Jib projects or Bazel container_image targets must be present to run skaffold; please provide at least one and try again")
+	}
+
+	noManifests := len(project.k8sConfigs) == 0 && len(project.charts) == 0 && len(project.kustomizations) == 0
+	if noManifests && !generateK8s {
+		return nil, nil, errors.New("one or more valid kubernetes manifests, helm charts or kustomizations is required to run skaffold; use --generate-manifests to generate them")
+	}
+
+	var pairs []builderPair
+	var manifests []generatedManifest
+	var err error
+	switch {
+	case cliArtifacts != nil:
+		pairs, err = processCliArtifacts(cliArtifacts)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "processing cli artifacts")
+		}
+	case noManifests:
+		pairs, err = promptUserForImages(project.builders)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "choosing image names")
+		}
+	default:
+		pairs = resolveBuilderImages(project.builders, project.images)
+	}
+
+	if noManifests {
+		manifests, err = generateManifests(pairs)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "generating kubernetes manifests")
+		}
+		if len(manifests) == 0 {
+			return nil, nil, errors.New("kubernetes manifests can only be generated for images built from Dockerfiles")
+		}
+		for _, manifest := range manifests {
+			project.k8sConfigs = append(project.k8sConfigs, manifest.Path)
+		}
+	}
+
+	return pairs, manifests, nil
+}
+
+// projectAnalysis is what `skaffold init` finds in a project.
+type projectAnalysis struct {
+	k8sConfigs     []string
+	images         []string
+	builders       []initBuilder
+	charts         []helmChart
+	kustomizations []string
+}
+
+// analyzeProject looks for builders, kubernetes manifests, helm charts
+// and kustomizations in a project.
+func analyzeProject(rootDir string) (*projectAnalysis, error) {
+	var potentialConfigs, k8sConfigs, images []string
+	var builders []initBuilder
+	var workspaces, buildFiles, chartFiles, kustomizations []string
+	err := filepath.Walk(rootDir, func(path string, f os.FileInfo, e error) error {
+		if f.IsDir() {
+			return nil
+		}
+		if strings.HasPrefix(path, ".") {
+			return nil
+		}
+
+		switch filepath.Base(path) {
+		case "pom.xml", "build.gradle", "build.gradle.kts":
+			if isJibProject(path) {
+				logrus.Infof("existing jib project found: %s", path)
+				builders = append(builders, jibBuilder{BuildFile: path, Gradle: filepath.Base(path) != "pom.xml"})
+			}
+			return nil
+		case "WORKSPACE":
+			workspaces = append(workspaces, filepath.Dir(path))
+			return nil
+		case "BUILD", "BUILD.bazel":
+			buildFiles = append(buildFiles, path)
+			return nil
+		case "Chart.yaml":
+			chartFiles = append(chartFiles, path)
+			return nil
+		case "kustomization.yaml":
+			kustomizations = append(kustomizations, filepath.Dir(path))
+			return nil
+		}
+
+		if util.IsSupportedKubernetesFormat(path) {
+			potentialConfigs = append(potentialConfigs, path)
+		}
+		// try and parse dockerfile
+		if docker.ValidateDockerfile(path) {
+			logrus.Infof("existing dockerfile found: %s", path)
+			builders = append(builders, dockerBuilder{Dockerfile: path})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range bazelTargets(workspaces, buildFiles) {
+		logrus.Infof("existing bazel target found: %s", b.Target)
+		builders = append(builders, b)
+	}
+
+	var charts []helmChart
+	for _, file := range chartFiles {
+		chart, err := parseChart(file)
+		if err != nil {
+			logrus.Warnf("invalid helm chart %s: %s", file, err)
+			continue
+		}
+		logrus.Infof("existing helm chart found: %s", chart.Path)
+		charts = append(charts, chart)
+		for _, key := range chart.imageKeys() {
+			images = append(images, chart.Images[key])
+		}
+	}
+
+	for _, file := range potentialConfigs {
+		if inChart(file, charts) {
+			continue
+		}
+
+		if !force {
+			config, err := schema.ParseConfig(file, true)
+			if err == nil && config != nil {
+				return nil, fmt.Errorf("pre-existing %s found", file)
+			}
+		}
+
+		logrus.Debugf("%s is not a valid skaffold configuration: continuing", file)
+		imgs, err := parseKubernetesYaml(file)
+		if err == nil {
+			logrus.Infof("found valid k8s yaml: %s", file)
+			k8sConfigs = append(k8sConfigs, file)
+			images = append(images, imgs...)
+		} else {
+			logrus.Infof("invalid k8s yaml %s: %s", file, err.Error())
+		}
+	}
+
+	return &projectAnalysis{
+		k8sConfigs:     k8sConfigs,
+		images:         images,
+		builders:       builders,
+		charts:         charts,
+		kustomizations: kustomizations,
+	}, nil
+}
+
+// processCliArtifacts reads the `--artifact` flags. Each one is either a
+// `path=image` pair or a JSON builder configuration, as printed by `--analyze`,
+// with an image name.
+func processCliArtifacts(artifacts []string) ([]builderPair, error) {
+	var pairs []builderPair
 	for _, artifact := range artifacts {
+		if strings.HasPrefix(strings.TrimSpace(artifact), "{") {
+			pair, err := parseJSONArtifact(artifact)
+			if err != nil {
+				return nil, errors.Wrapf(err, "malformed artifact provided: %s", artifact)
+			}
+			pairs = append(pairs, pair)
+			continue
+		}
+
 		parts := strings.Split(artifact, "=")
 		if len(parts) != 2 {
 			return nil, fmt.Errorf("malformed artifact provided: %s", artifact)
 		}
-		pairs = append(pairs, dockerfilePair{
-			Dockerfile: parts[0],
-			ImageName:  parts[1],
+		pairs = append(pairs, builderPair{
+			Builder:   builderForPath(parts[0]),
+			ImageName: parts[1],
 		})
 	}
 	return pairs, nil
 }
 
-// For each image parsed from all k8s manifests, prompt the user for
-// the dockerfile that builds the referenced image
-func resolveDockerfileImages(dockerfiles []string, images []string) []dockerfilePair {
-	// if we only have 1 image and 1 dockerfile, don't bother prompting
-	if len(images) == 1 && len(dockerfiles) == 1 {
-		return []dockerfilePair{{
-			Dockerfile: dockerfiles[0],
-			ImageName:  images[0],
+// builderForPath guesses how to build an image from the file given on the command line.
+func builderForPath(path string) initBuilder {
+	switch filepath.Base(path) {
+	case "pom.xml":
+		return jibBuilder{BuildFile: path}
+	case "build.gradle", "build.gradle.kts":
+		return jibBuilder{BuildFile: path, Gradle: true}
+	default:
+		return dockerBuilder{Dockerfile: path}
+	}
+}
+
+// For each image parsed from all k8s manifests and helm charts, prompt the user for
+// the builder that builds the referenced image
+func resolveBuilderImages(builders []initBuilder, images []string) []builderPair {
+	// if we only have 1 image and 1 builder, don't bother prompting
+	if len(images) == 1 && len(builders) == 1 {
+		return []builderPair{{
+			Builder:   builders[0],
+			ImageName: images[0],
 		}}
 	}
-	pairs := []dockerfilePair{}
-	for {
-		if len(images) == 0 {
-			break
+	pairs := []builderPair{}
+	seen := map[string]bool{}
+	for _, image := range images {
+		if seen[image] {
+			continue
 		}
-		image := images[0]
-		pair := promptUserForDockerfile(image, dockerfiles)
-		if pair.Dockerfile != NoDockerfile {
-			pairs = append(pairs, pair)
-			dockerfiles = util.RemoveFromSlice(dockerfiles, pair.Dockerfile)
+		seen[image] = true
+
+		builder := promptUserForBuilder(image, builders)
+		if builder != nil {
+			pairs = append(pairs, builderPair{
+				Builder:   builder,
+				ImageName: image,
+			})
+			builders = removeBuilder(builders, builder)
 		}
-		images = util.RemoveFromSlice(images, pair.ImageName)
 	}
-	if len(dockerfiles) > 0 {
-		logrus.Warnf("unused dockerfiles found in repository: %v", dockerfiles)
+	if len(builders) > 0 {
+		var unused []string
+		for _, b := range builders {
+			unused = append(unused, b.Describe())
+		}
+		logrus.Warnf("unused builders found in repository: %v", unused)
 	}
 	return pairs
 }
 
-func promptUserForDockerfile(image string, dockerfiles []string) dockerfilePair {
-	var selectedDockerfile string
-	options := append(dockerfiles, NoDockerfile)
+func promptUserForBuilder(image string, builders []initBuilder) initBuilder {
+	var selected string
+	var options []string
+	for _, b := range builders {
+		options = append(options, b.Describe())
+	}
+	options = append(options, NoBuilder)
 	prompt := &survey.Select{
-		Message:  fmt.Sprintf("Choose the dockerfile to build image %s", image),
+		Message:  fmt.Sprintf("Choose the builder to build image %s", image),
 		Options:  options,
 		PageSize: 15,
 	}
-	survey.AskOne(prompt, &selectedDockerfile, nil)
-	return dockerfilePair{
-		Dockerfile: selectedDockerfile,
-		ImageName:  image,
+	survey.AskOne(prompt, &selected, nil)
+	for _, b := range builders {
+		if b.Describe() == selected {
+			return b
+		}
 	}
+	return nil
 }
 
-func processBuildArtifacts(pairs []dockerfilePair) latest.BuildConfig {
+func removeBuilder(builders []initBuilder, builder initBuilder) []initBuilder {
+	var remaining []initBuilder
+	for _, b := range builders {
+		if b.Describe() != builder.Describe() {
+			remaining = append(remaining, b)
+		}
+	}
+	return remaining
+}
+
+func processBuildArtifacts(pairs []builderPair) latest.BuildConfig {
 	var config latest.BuildConfig
 
-	if len(pairs) > 0 {
-		var artifacts []*latest.Artifact
-		for _, pair := range pairs {
-			workspace := filepath.Dir(pair.Dockerfile)
-			dockerfilePath := filepath.Base(pair.Dockerfile)
-			a := &latest.Artifact{
-				ImageName: pair.ImageName,
-			}
-			if workspace != "." {
-				a.Workspace = workspace
-			}
-			if dockerfilePath != constants.DefaultDockerfilePath {
-				a.ArtifactType = latest.ArtifactType{
-					DockerArtifact: &latest.DockerArtifact{
-						DockerfilePath: dockerfilePath,
-					},
-				}
-			}
-			artifacts = append(artifacts, a)
-		}
-		config.Artifacts = artifacts
+	for _, pair := range pairs {
+		config.Artifacts = append(config.Artifacts, pair.Builder.CreateArtifact(pair.ImageName))
 	}
 	return config
 }
 
-func generateSkaffoldPipeline(k8sConfigs []string, dockerfilePairs []dockerfilePair) ([]byte, error) {
+func generateSkaffoldPipeline(deploy latest.DeployConfig, pairs []builderPair) ([]byte, error) {
 	// if we're here, the user has no skaffold yaml so we need to generate one
 	// if the user doesn't have any k8s yamls, generate one for each dockerfile
 	logrus.Info("generating skaffold config")
@@ -286,14 +453,8 @@ func generateSkaffoldPipeline(k8sConfigs []string, dockerfilePairs []dockerfileP
 		return nil, errors.Wrap(err, "generating default pipeline")
 	}
 
-	pipeline.Build = processBuildArtifacts(dockerfilePairs)
-	pipeline.Deploy = latest.DeployConfig{
-		DeployType: latest.DeployType{
-			KubectlDeploy: &latest.KubectlDeploy{
-				Manifests: k8sConfigs,
-			},
-		},
-	}
+	pipeline.Build = processBuildArtifacts(pairs)
+	pipeline.Deploy = deploy
 
 	pipelineStr, err := yaml.Marshal(pipeline)
 	if err != nil {
@@ -354,18 +515,19 @@ func parseImagesFromYaml(doc interface{}) []string {
 		}
 	case map[interface{}]interface{}:
 		for k, v := range t {
-			if k.(string) != "image" {
+			image, ok := v.(string)
+			if k != "image" || !ok {
 				images = append(images, parseImagesFromYaml(v)...)
 				continue
 			}
 
-			images = append(images, v.(string))
+			images = append(images, image)
 		}
 	}
 	return images
 }
 
-type dockerfilePair struct {
-	Dockerfile string
-	ImageName  string
+type builderPair struct {
+	Builder   initBuilder
+	ImageName string
 }
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// analysis is printed by `skaffold init --analyze`, for tools that
+// present their own UI before generating the skaffold config.
+type analysis struct {
+	Builders       []builderConfig `json:"builders"`
+	Dockerfiles    []string        `json:"dockerfiles"`
+	Manifests      []string        `json:"manifests"`
+	Charts         []helmChart     `json:"charts"`
+	Kustomizations []string        `json:"kustomizations"`
+	Images         []string        `json:"images"`
+}
+
+func newAnalysis(project *projectAnalysis) analysis {
+	a := analysis{
+		Builders:       []builderConfig{},
+		Dockerfiles:    []string{},
+		Manifests:      nonNil(project.k8sConfigs),
+		Charts:         project.charts,
+		Kustomizations: nonNil(project.kustomizations),
+		Images:         []string{},
+	}
+	if a.Charts == nil {
+		a.Charts = []helmChart{}
+	}
+
+	for _, b := range project.builders {
+		config := b.Config()
+		a.Builders = append(a.Builders, config)
+		if config.Type == dockerBuilderType {
+			a.Dockerfiles = append(a.Dockerfiles, config.Path)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, image := range project.images {
+		if !seen[image] {
+			seen[image] = true
+			a.Images = append(a.Images, image)
+		}
+	}
+
+	return a
+}
+
+func printAnalysis(out io.Writer, project *projectAnalysis) error {
+	encoded, err := json.MarshalIndent(newAnalysis(project), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshalling analysis")
+	}
+
+	fmt.Fprintln(out, string(encoded))
+	return nil
+}
+
+// parseJSONArtifact reads a builder configuration with an image name.
+func parseJSONArtifact(artifact string) (builderPair, error) {
+	var config builderConfig
+	if err := json.Unmarshal([]byte(artifact), &config); err != nil {
+		return builderPair{}, err
+	}
+	if config.Image == "" {
+		return builderPair{}, errors.New("missing image")
+	}
+
+	builder, err := config.builder()
+	if err != nil {
+		return builderPair{}, err
+	}
+
+	return builderPair{
+		Builder:   builder,
+		ImageName: config.Image,
+	}, nil
+}
+
+// nonNil makes sure empty lists are printed as `[]` rather than `null`.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
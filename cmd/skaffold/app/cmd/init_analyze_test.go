@@ -0,0 +1,124 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestPrintAnalysis(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("web/Dockerfile", "FROM nginx").
+		Write("backend/pom.xml", "<artifactId>jib-maven-plugin</artifactId>").
+		Write("k8s/pod.yaml", `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+  - name: web
+    image: gcr.io/k8s-skaffold/web
+  - name: sidecar
+    image: gcr.io/k8s-skaffold/web`)
+
+	project, err := analyzeProject(tmpDir.Root())
+	testutil.CheckError(t, false, err)
+
+	var out bytes.Buffer
+	err = printAnalysis(&out, project)
+	testutil.CheckError(t, false, err)
+
+	var printed analysis
+	err = json.Unmarshal(out.Bytes(), &printed)
+	testutil.CheckErrorAndDeepEqual(t, false, err, analysis{
+		Builders: []builderConfig{
+			{Type: jibMavenBuilderType, Path: tmpDir.Path("backend/pom.xml")},
+			{Type: dockerBuilderType, Path: tmpDir.Path("web/Dockerfile")},
+		},
+		Dockerfiles:    []string{tmpDir.Path("web/Dockerfile")},
+		Manifests:      []string{tmpDir.Path("k8s/pod.yaml")},
+		Charts:         []helmChart{},
+		Kustomizations: []string{},
+		Images:         []string{"gcr.io/k8s-skaffold/web"},
+	}, printed)
+}
+
+func TestProcessCliArtifacts(t *testing.T) {
+	var tests = []struct {
+		description string
+		artifacts   []string
+		expected    []builderPair
+		shouldErr   bool
+	}{
+		{
+			description: "dockerfile and image pair",
+			artifacts:   []string{"web/Dockerfile.web=gcr.io/web-project/image"},
+			expected: []builderPair{{
+				Builder:   dockerBuilder{Dockerfile: "web/Dockerfile.web"},
+				ImageName: "gcr.io/web-project/image",
+			}},
+		},
+		{
+			description: "json builders",
+			artifacts: []string{
+				`{"type":"jibGradle","path":"build.gradle","image":"gcr.io/web-project/java"}`,
+				`{"type":"bazel","path":".","target":"//:app.tar","image":"gcr.io/web-project/bazel"}`,
+			},
+			expected: []builderPair{{
+				Builder:   jibBuilder{BuildFile: "build.gradle", Gradle: true},
+				ImageName: "gcr.io/web-project/java",
+			}, {
+				Builder:   bazelBuilder{Workspace: ".", Target: "//:app.tar"},
+				ImageName: "gcr.io/web-project/bazel",
+			}},
+		},
+		{
+			description: "malformed pair",
+			artifacts:   []string{"Dockerfile"},
+			shouldErr:   true,
+		},
+		{
+			description: "missing image",
+			artifacts:   []string{`{"type":"docker","path":"Dockerfile"}`},
+			shouldErr:   true,
+		},
+		{
+			description: "unknown builder",
+			artifacts:   []string{`{"type":"maven","path":"pom.xml","image":"image"}`},
+			shouldErr:   true,
+		},
+		{
+			description: "bazel without target",
+			artifacts:   []string{`{"type":"bazel","path":".","image":"image"}`},
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			pairs, err := processCliArtifacts(test.artifacts)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, pairs)
+		})
+	}
+}
@@ -0,0 +1,464 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// initBuilder is a way of building an image, found in the project.
+type initBuilder interface {
+	// Describe is shown to the user when choosing how to build an image.
+	Describe() string
+
+	// CreateArtifact creates the artifact that builds an image.
+	CreateArtifact(image string) *latest.Artifact
+
+	// Config describes the builder to tools that drive `skaffold init`.
+	Config() builderConfig
+}
+
+// Builder types, as printed by `skaffold init --analyze`.
+const (
+	dockerBuilderType    = "docker"
+	jibMavenBuilderType  = "jibMaven"
+	jibGradleBuilderType = "jibGradle"
+	bazelBuilderType     = "bazel"
+)
+
+// builderConfig is the JSON representation of a builder. Paired with an
+// image name, it can be given back to `skaffold init` with `--artifact`.
+type builderConfig struct {
+	Type   string `json:"type"`
+	Path   string `json:"path"`
+	Target string `json:"target,omitempty"`
+	Image  string `json:"image,omitempty"`
+}
+
+// builder creates the builder described by the configuration.
+func (c builderConfig) builder() (initBuilder, error) {
+	if c.Path == "" {
+		return nil, fmt.Errorf("missing path for builder %q", c.Type)
+	}
+
+	switch c.Type {
+	case dockerBuilderType:
+		return dockerBuilder{Dockerfile: c.Path}, nil
+	case jibMavenBuilderType:
+		return jibBuilder{BuildFile: c.Path}, nil
+	case jibGradleBuilderType:
+		return jibBuilder{BuildFile: c.Path, Gradle: true}, nil
+	case bazelBuilderType:
+		if c.Target == "" {
+			return nil, errors.New("missing target for bazel builder")
+		}
+		return bazelBuilder{Workspace: c.Path, Target: c.Target}, nil
+	default:
+		return nil, fmt.Errorf("unknown builder type %q", c.Type)
+	}
+}
+
+// dockerBuilder builds an image from a Dockerfile. When Workspace is set,
+// the Dockerfile is relative to it, like the build context of docker-compose.
+// Otherwise, the workspace is the folder of the Dockerfile.
+type dockerBuilder struct {
+	Workspace  string
+	Dockerfile string
+	BuildArgs  map[string]*string
+}
+
+// path is the path of the Dockerfile, including the workspace.
+func (b dockerBuilder) path() string {
+	return filepath.Join(b.Workspace, b.Dockerfile)
+}
+
+func (b dockerBuilder) Describe() string {
+	return b.path()
+}
+
+func (b dockerBuilder) Config() builderConfig {
+	return builderConfig{Type: dockerBuilderType, Path: b.path()}
+}
+
+func (b dockerBuilder) CreateArtifact(image string) *latest.Artifact {
+	workspace, dockerfile := b.Workspace, b.Dockerfile
+	if workspace == "" {
+		workspace, dockerfile = filepath.Dir(b.Dockerfile), filepath.Base(b.Dockerfile)
+	}
+
+	a := &latest.Artifact{ImageName: image}
+	if workspace != "." {
+		a.Workspace = workspace
+	}
+	if dockerfile != constants.DefaultDockerfilePath || len(b.BuildArgs) > 0 {
+		if dockerfile == constants.DefaultDockerfilePath {
+			dockerfile = ""
+		}
+		a.ArtifactType = latest.ArtifactType{
+			DockerArtifact: &latest.DockerArtifact{
+				DockerfilePath: dockerfile,
+				BuildArgs:      b.BuildArgs,
+			},
+		}
+	}
+	return a
+}
+
+// jibBuilder builds an image with the Jib plugin of Maven or Gradle.
+type jibBuilder struct {
+	BuildFile string
+	Gradle    bool
+}
+
+func (b jibBuilder) Describe() string {
+	if b.Gradle {
+		return fmt.Sprintf("Jib Gradle (%s)", b.BuildFile)
+	}
+	return fmt.Sprintf("Jib Maven (%s)", b.BuildFile)
+}
+
+func (b jibBuilder) Config() builderConfig {
+	if b.Gradle {
+		return builderConfig{Type: jibGradleBuilderType, Path: b.BuildFile}
+	}
+	return builderConfig{Type: jibMavenBuilderType, Path: b.BuildFile}
+}
+
+func (b jibBuilder) CreateArtifact(image string) *latest.Artifact {
+	a := &latest.Artifact{ImageName: image}
+	if workspace := filepath.Dir(b.BuildFile); workspace != "." {
+		a.Workspace = workspace
+	}
+	if b.Gradle {
+		a.ArtifactType.JibGradleArtifact = &latest.JibGradleArtifact{}
+	} else {
+		a.ArtifactType.JibMavenArtifact = &latest.JibMavenArtifact{}
+	}
+	return a
+}
+
+// bazelBuilder builds an image with a Bazel `container_image` target.
+type bazelBuilder struct {
+	Workspace string
+	Target    string
+}
+
+func (b bazelBuilder) Describe() string {
+	return fmt.Sprintf("Bazel %s (%s)", b.Target, filepath.Join(b.Workspace, "WORKSPACE"))
+}
+
+func (b bazelBuilder) Config() builderConfig {
+	return builderConfig{Type: bazelBuilderType, Path: b.Workspace, Target: b.Target}
+}
+
+func (b bazelBuilder) CreateArtifact(image string) *latest.Artifact {
+	a := &latest.Artifact{
+		ImageName: image,
+		ArtifactType: latest.ArtifactType{
+			BazelArtifact: &latest.BazelArtifact{
+				BuildTarget: b.Target,
+			},
+		},
+	}
+	if b.Workspace != "." {
+		a.Workspace = b.Workspace
+	}
+	return a
+}
+
+var containerImageRule = regexp.MustCompile(`\bcontainer_image\s*\(`)
+var ruleName = regexp.MustCompile(`\bname\s*=\s*"([^"]+)"`)
+
+// isJibProject checks that a Maven or Gradle build file uses the Jib plugin.
+func isJibProject(path string) bool {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		logrus.Warnf("reading %s: %s", path, err)
+		return false
+	}
+
+	switch filepath.Base(path) {
+	case "pom.xml":
+		return strings.Contains(string(content), "jib-maven-plugin")
+	default:
+		return strings.Contains(string(content), "com.google.cloud.tools.jib")
+	}
+}
+
+// bazelTargets lists the `container_image` targets of BUILD files, relative
+// to the innermost Bazel workspace that contains them.
+func bazelTargets(workspaces []string, buildFiles []string) []bazelBuilder {
+	var builders []bazelBuilder
+	for _, buildFile := range buildFiles {
+		workspace := innermostWorkspace(workspaces, filepath.Dir(buildFile))
+		if workspace == "" {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(buildFile)
+		if err != nil {
+			logrus.Warnf("reading %s: %s", buildFile, err)
+			continue
+		}
+
+		pkg, err := filepath.Rel(workspace, filepath.Dir(buildFile))
+		if err != nil {
+			continue
+		}
+		if pkg == "." {
+			pkg = ""
+		}
+
+		for _, args := range containerImageRules(string(content)) {
+			if name := ruleName.FindStringSubmatch(args); name != nil {
+				builders = append(builders, bazelBuilder{
+					Workspace: workspace,
+					Target:    fmt.Sprintf("//%s:%s.tar", filepath.ToSlash(pkg), name[1]),
+				})
+			}
+		}
+	}
+	return builders
+}
+
+// containerImageRules returns the arguments of the `container_image` rules of a BUILD file.
+// Parentheses are balanced, ignoring those in strings and comments, and the content of nested
+// calls is blanked, so that only the rule's own arguments are kept.
+func containerImageRules(content string) []string {
+	var rules []string
+	for _, loc := range containerImageRule.FindAllStringIndex(content, -1) {
+		lineStart := strings.LastIndexByte(content[:loc[0]], '\n') + 1
+		if strings.Contains(content[lineStart:loc[0]], "#") {
+			continue
+		}
+
+		var args []byte
+		depth := 1
+		keep := func(c byte) {
+			if depth > 1 {
+				c = ' '
+			}
+			args = append(args, c)
+		}
+
+		var quote byte
+	scan:
+		for i := loc[1]; i < len(content); i++ {
+			c := content[i]
+			switch {
+			case quote != 0:
+				if c == '\\' && i+1 < len(content) {
+					keep(c)
+					i++
+					c = content[i]
+				} else if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '#':
+				for i+1 < len(content) && content[i+1] != '\n' {
+					i++
+				}
+				continue
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth--; depth == 0 {
+					rules = append(rules, string(args))
+					break scan
+				}
+			}
+			keep(c)
+		}
+	}
+	return rules
+}
+
+func innermostWorkspace(workspaces []string, dir string) string {
+	var found string
+	for _, workspace := range workspaces {
+		if isInDirectory(dir, workspace) && len(workspace) > len(found) {
+			found = workspace
+		}
+	}
+	return found
+}
+
+// isInDirectory checks that a path is a directory or is inside it.
+func isInDirectory(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// helmChart is a chart found in the project.
+type helmChart struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Images maps the keys of values.yaml to images.
+	Images map[string]string `json:"images,omitempty"`
+	// Convention is true if images are set with `image.repository` and `image.tag`.
+	Convention bool `json:"-"`
+}
+
+// imageKeys returns the sorted keys of the images.
+func (c helmChart) imageKeys() []string {
+	var keys []string
+	for key := range c.Images {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// inChart checks that a file belongs to one of the charts.
+func inChart(path string, charts []helmChart) bool {
+	for _, chart := range charts {
+		if isInDirectory(path, chart.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChart reads the name of a chart and the images of its values.yaml.
+func parseChart(chartFile string) (helmChart, error) {
+	chart := helmChart{
+		Path:   filepath.Dir(chartFile),
+		Images: map[string]string{},
+	}
+
+	content, err := ioutil.ReadFile(chartFile)
+	if err != nil {
+		return chart, err
+	}
+	var metadata struct {
+		Name string `yaml:"name"`
+	}
+	if err := yaml.Unmarshal(content, &metadata); err != nil {
+		return chart, err
+	}
+	chart.Name = metadata.Name
+	if chart.Name == "" {
+		chart.Name = filepath.Base(chart.Path)
+	}
+
+	values, err := ioutil.ReadFile(filepath.Join(chart.Path, "values.yaml"))
+	if err != nil {
+		return chart, nil
+	}
+	m := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(values, &m); err != nil {
+		logrus.Warnf("parsing values of chart %s: %s", chart.Name, err)
+		return chart, nil
+	}
+	chart.Convention = findValuesImages(m, "", chart.Images)
+
+	return chart, nil
+}
+
+// findValuesImages looks for `image` keys. It returns true if they all
+// are maps with a `repository` key.
+func findValuesImages(values map[interface{}]interface{}, prefix string, images map[string]string) bool {
+	convention := true
+	for k, v := range values {
+		key := prefix + fmt.Sprint(k)
+		switch value := v.(type) {
+		case string:
+			if fmt.Sprint(k) == "image" {
+				images[key] = value
+				convention = false
+			}
+		case map[interface{}]interface{}:
+			if repository, ok := value["repository"].(string); ok && fmt.Sprint(k) == "image" {
+				images[key] = repository
+				continue
+			}
+			convention = findValuesImages(value, key+".", images) && convention
+		}
+	}
+	return convention
+}
+
+// deployConfig chooses the deployer: helm if charts are found,
+// then kustomize, then kubectl.
+func deployConfig(k8sConfigs []string, charts []helmChart, kustomizations []string) latest.DeployConfig {
+	if len(charts) > 0 {
+		if len(kustomizations) > 0 {
+			logrus.Warnf("deploying with helm, ignoring the kustomizations in %v", kustomizations)
+		}
+		if len(k8sConfigs) > 0 {
+			logrus.Warnf("deploying with helm, ignoring the Kubernetes manifests %v", k8sConfigs)
+		}
+
+		helm := &latest.HelmDeploy{}
+		for _, chart := range charts {
+			release := latest.HelmRelease{
+				Name:      chart.Name,
+				ChartPath: chart.Path,
+			}
+			if len(chart.Images) > 0 {
+				release.Values = map[string]string{}
+				for key, image := range chart.Images {
+					release.Values[key] = image
+				}
+				if chart.Convention {
+					release.ImageStrategy.HelmConventionConfig = &latest.HelmConventionConfig{}
+				}
+			}
+			helm.Releases = append(helm.Releases, release)
+		}
+		return latest.DeployConfig{DeployType: latest.DeployType{HelmDeploy: helm}}
+	}
+
+	if len(kustomizations) > 0 {
+		// Prefer the outermost kustomization.
+		sort.Slice(kustomizations, func(i, j int) bool {
+			if len(kustomizations[i]) != len(kustomizations[j]) {
+				return len(kustomizations[i]) < len(kustomizations[j])
+			}
+			return kustomizations[i] < kustomizations[j]
+		})
+		if len(kustomizations) > 1 {
+			logrus.Warnf("only the kustomization in %s is deployed, ignoring %v", kustomizations[0], kustomizations[1:])
+		}
+		kustomize := &latest.KustomizeDeploy{}
+		if kustomizations[0] != "." {
+			kustomize.KustomizePath = kustomizations[0]
+		}
+		return latest.DeployConfig{DeployType: latest.DeployType{KustomizeDeploy: kustomize}}
+	}
+
+	return latest.DeployConfig{
+		DeployType: latest.DeployType{
+			KubectlDeploy: &latest.KubectlDeploy{
+				Manifests: k8sConfigs,
+			},
+		},
+	}
+}
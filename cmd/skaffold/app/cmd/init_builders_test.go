@@ -0,0 +1,297 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestCreateArtifact(t *testing.T) {
+	var tests = []struct {
+		description string
+		builder     initBuilder
+		expected    *latest.Artifact
+	}{
+		{
+			description: "default Dockerfile",
+			builder:     dockerBuilder{Dockerfile: "Dockerfile"},
+			expected:    &latest.Artifact{ImageName: "image"},
+		},
+		{
+			description: "Dockerfile in a sub folder",
+			builder:     dockerBuilder{Dockerfile: "web/Dockerfile.dev"},
+			expected: &latest.Artifact{
+				ImageName: "image",
+				Workspace: "web",
+				ArtifactType: latest.ArtifactType{
+					DockerArtifact: &latest.DockerArtifact{DockerfilePath: "Dockerfile.dev"},
+				},
+			},
+		},
+		{
+			description: "Dockerfile relative to the workspace",
+			builder:     dockerBuilder{Workspace: "api", Dockerfile: "docker/Dockerfile"},
+			expected: &latest.Artifact{
+				ImageName: "image",
+				Workspace: "api",
+				ArtifactType: latest.ArtifactType{
+					DockerArtifact: &latest.DockerArtifact{DockerfilePath: "docker/Dockerfile"},
+				},
+			},
+		},
+		{
+			description: "build args",
+			builder:     dockerBuilder{Dockerfile: "Dockerfile", BuildArgs: map[string]*string{"DEBUG": nil}},
+			expected: &latest.Artifact{
+				ImageName: "image",
+				ArtifactType: latest.ArtifactType{
+					DockerArtifact: &latest.DockerArtifact{BuildArgs: map[string]*string{"DEBUG": nil}},
+				},
+			},
+		},
+		{
+			description: "jib maven",
+			builder:     jibBuilder{BuildFile: "backend/pom.xml"},
+			expected: &latest.Artifact{
+				ImageName: "image",
+				Workspace: "backend",
+				ArtifactType: latest.ArtifactType{
+					JibMavenArtifact: &latest.JibMavenArtifact{},
+				},
+			},
+		},
+		{
+			description: "jib gradle",
+			builder:     jibBuilder{BuildFile: "build.gradle", Gradle: true},
+			expected: &latest.Artifact{
+				ImageName: "image",
+				ArtifactType: latest.ArtifactType{
+					JibGradleArtifact: &latest.JibGradleArtifact{},
+				},
+			},
+		},
+		{
+			description: "bazel",
+			builder:     bazelBuilder{Workspace: "bazel", Target: "//app:image.tar"},
+			expected: &latest.Artifact{
+				ImageName: "image",
+				Workspace: "bazel",
+				ArtifactType: latest.ArtifactType{
+					BazelArtifact: &latest.BazelArtifact{BuildTarget: "//app:image.tar"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			artifact := test.builder.CreateArtifact("image")
+
+			testutil.CheckDeepEqual(t, test.expected, artifact)
+		})
+	}
+}
+
+func TestIsJibProject(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("maven/pom.xml", "<plugin><artifactId>jib-maven-plugin</artifactId></plugin>").
+		Write("gradle/build.gradle", "plugins { id 'com.google.cloud.tools.jib' version '0.10.0' }").
+		Write("other/pom.xml", "<plugin><artifactId>maven-compiler-plugin</artifactId></plugin>")
+
+	testutil.CheckDeepEqual(t, true, isJibProject(tmpDir.Path("maven/pom.xml")))
+	testutil.CheckDeepEqual(t, true, isJibProject(tmpDir.Path("gradle/build.gradle")))
+	testutil.CheckDeepEqual(t, false, isJibProject(tmpDir.Path("other/pom.xml")))
+}
+
+func TestBazelTargets(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("WORKSPACE", "").
+		Write("BUILD", `container_image(
+    name = "root",
+    base = "@java_base//image",
+)`).
+		Write("app/BUILD.bazel", `java_binary(name = "bin")
+
+container_image(name = "app", base = ":bin")`).
+		Write("nested/WORKSPACE", "").
+		Write("nested/web/BUILD", `container_image(name = "web")`).
+		Write("nested/api/BUILD", `# container_image(name = "commented")
+container_image(
+    # Parentheses in comments ) and strings are ignored
+    cmd = ["echo", "(api)"],
+    env = select({":dev": dict(name = "dev")}),
+    name = "api",
+)`)
+
+	workspaces := []string{tmpDir.Root(), tmpDir.Path("nested")}
+	buildFiles := []string{tmpDir.Path("BUILD"), tmpDir.Path("app/BUILD.bazel"), tmpDir.Path("nested/web/BUILD"), tmpDir.Path("nested/api/BUILD")}
+
+	builders := bazelTargets(workspaces, buildFiles)
+
+	testutil.CheckDeepEqual(t, []bazelBuilder{
+		{Workspace: tmpDir.Root(), Target: "//:root.tar"},
+		{Workspace: tmpDir.Root(), Target: "//app:app.tar"},
+		{Workspace: tmpDir.Path("nested"), Target: "//web:web.tar"},
+		{Workspace: tmpDir.Path("nested"), Target: "//api:api.tar"},
+	}, builders)
+}
+
+func TestParseChart(t *testing.T) {
+	var tests = []struct {
+		description string
+		values      string
+		expected    helmChart
+	}{
+		{
+			description: "helm convention",
+			values: `image:
+  repository: gcr.io/k8s-skaffold/web
+  tag: latest
+replicas: 2`,
+			expected: helmChart{
+				Name:       "web",
+				Images:     map[string]string{"image": "gcr.io/k8s-skaffold/web"},
+				Convention: true,
+			},
+		},
+		{
+			description: "fully qualified images",
+			values: `frontend:
+  image: gcr.io/k8s-skaffold/frontend
+backend:
+  image: gcr.io/k8s-skaffold/backend`,
+			expected: helmChart{
+				Name: "web",
+				Images: map[string]string{
+					"frontend.image": "gcr.io/k8s-skaffold/frontend",
+					"backend.image":  "gcr.io/k8s-skaffold/backend",
+				},
+			},
+		},
+		{
+			description: "no images",
+			values:      "replicas: 2",
+			expected: helmChart{
+				Name:       "web",
+				Images:     map[string]string{},
+				Convention: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			tmpDir.Write("charts/web/Chart.yaml", "name: web\nversion: 0.1.0").
+				Write("charts/web/values.yaml", test.values)
+
+			chart, err := parseChart(tmpDir.Path("charts/web/Chart.yaml"))
+
+			test.expected.Path = tmpDir.Path("charts/web")
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, chart)
+		})
+	}
+}
+
+func TestDeployConfig(t *testing.T) {
+	var tests = []struct {
+		description    string
+		k8sConfigs     []string
+		charts         []helmChart
+		kustomizations []string
+		expected       latest.DeployConfig
+	}{
+		{
+			description: "kubectl",
+			k8sConfigs:  []string{"k8s/deployment.yaml"},
+			expected: latest.DeployConfig{
+				DeployType: latest.DeployType{
+					KubectlDeploy: &latest.KubectlDeploy{Manifests: []string{"k8s/deployment.yaml"}},
+				},
+			},
+		},
+		{
+			description:    "kustomize",
+			k8sConfigs:     []string{"base/deployment.yaml"},
+			kustomizations: []string{"overlays/dev", "base"},
+			expected: latest.DeployConfig{
+				DeployType: latest.DeployType{
+					KustomizeDeploy: &latest.KustomizeDeploy{KustomizePath: "base"},
+				},
+			},
+		},
+		{
+			description:    "kustomization at the root",
+			kustomizations: []string{"."},
+			expected: latest.DeployConfig{
+				DeployType: latest.DeployType{
+					KustomizeDeploy: &latest.KustomizeDeploy{},
+				},
+			},
+		},
+		{
+			description: "helm",
+			k8sConfigs:  []string{"k8s/deployment.yaml"},
+			charts: []helmChart{{
+				Name:       "web",
+				Path:       "charts/web",
+				Images:     map[string]string{"image": "gcr.io/k8s-skaffold/web"},
+				Convention: true,
+			}, {
+				Name:   "db",
+				Path:   "charts/db",
+				Images: map[string]string{},
+			}},
+			expected: latest.DeployConfig{
+				DeployType: latest.DeployType{
+					HelmDeploy: &latest.HelmDeploy{
+						Releases: []latest.HelmRelease{{
+							Name:      "web",
+							ChartPath: "charts/web",
+							Values:    map[string]string{"image": "gcr.io/k8s-skaffold/web"},
+							ImageStrategy: latest.HelmImageStrategy{
+								HelmImageConfig: latest.HelmImageConfig{
+									HelmConventionConfig: &latest.HelmConventionConfig{},
+								},
+							},
+						}, {
+							Name:      "db",
+							ChartPath: "charts/db",
+						}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			deploy := deployConfig(test.k8sConfigs, test.charts, test.kustomizations)
+
+			testutil.CheckDeepEqual(t, test.expected, deploy)
+		})
+	}
+}
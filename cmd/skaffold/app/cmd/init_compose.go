@@ -0,0 +1,613 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	ghodssyaml "github.com/ghodss/yaml"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// composeProject is a docker-compose file, version 2 or 3.
+type composeProject struct {
+	Version  string                    `yaml:"version"`
+	Services map[string]composeService `yaml:"services"`
+	Volumes  map[string]interface{}    `yaml:"volumes"`
+}
+
+// composeService is a service of a docker-compose file. Fields that accept
+// several syntaxes are decoded as interface{}.
+type composeService struct {
+	Image       string        `yaml:"image"`
+	Build       interface{}   `yaml:"build"`
+	Command     interface{}   `yaml:"command"`
+	Entrypoint  interface{}   `yaml:"entrypoint"`
+	Environment interface{}   `yaml:"environment"`
+	EnvFile     interface{}   `yaml:"env_file"`
+	Ports       []interface{} `yaml:"ports"`
+	Expose      []interface{} `yaml:"expose"`
+	Volumes     []interface{} `yaml:"volumes"`
+}
+
+// composePort maps a port of the Service to a port of the container.
+type composePort struct {
+	Published int
+	Target    int
+	Protocol  string
+}
+
+// convertComposeFile converts a docker-compose file into Kubernetes manifests,
+// one file per service, and into artifacts for the services that are built.
+func convertComposeFile(composeFile string) ([]generatedManifest, []builderPair, error) {
+	content, err := ioutil.ReadFile(composeFile)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading docker-compose file")
+	}
+
+	var project composeProject
+	if err := yaml.Unmarshal(content, &project); err != nil {
+		return nil, nil, errors.Wrap(err, "parsing docker-compose file")
+	}
+	if project.Services == nil {
+		return nil, nil, fmt.Errorf("no services found in %s: only version 2 and 3 of docker-compose files are supported", composeFile)
+	}
+
+	baseDir := filepath.Dir(composeFile)
+
+	var names []string
+	for name := range project.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var manifests []generatedManifest
+	var pairs []builderPair
+	claimed := map[string]bool{}
+	used := map[string]bool{}
+	for _, name := range names {
+		service := project.Services[name]
+
+		image := service.Image
+		if image == "" {
+			image = name
+		}
+
+		if service.Build != nil {
+			builder, err := composeBuilder(baseDir, service.Build)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "converting build of service %s", name)
+			}
+			pairs = append(pairs, builderPair{
+				Builder:   builder,
+				ImageName: image,
+			})
+		} else if service.Image == "" {
+			return nil, nil, fmt.Errorf("service %s has neither an image nor a build", name)
+		}
+
+		// Services whose names normalize the same, like my_app and my-app, get a numeric suffix.
+		objectName := uniqueManifestName(name, used)
+		objects, err := convertService(baseDir, objectName, image, service, project.Volumes, claimed)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "converting service %s", name)
+		}
+
+		content, err := marshalObjects(objects)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		manifests = append(manifests, generatedManifest{
+			Path:    filepath.Join(manifestsDir, objectName+".yaml"),
+			Content: content,
+		})
+	}
+
+	return manifests, pairs, nil
+}
+
+// composeBuilder maps the `build` section of a service, either a context
+// or a map with `context`, `dockerfile` and `args`, onto a Docker builder.
+// The context becomes the workspace and the Dockerfile stays relative to it.
+func composeBuilder(baseDir string, build interface{}) (dockerBuilder, error) {
+	context := "."
+	dockerfile := "Dockerfile"
+	var args map[string]*string
+
+	switch b := build.(type) {
+	case string:
+		context = b
+	case map[interface{}]interface{}:
+		if v, present := b["context"]; present {
+			context = fmt.Sprint(v)
+		}
+		if v, present := b["dockerfile"]; present {
+			dockerfile = fmt.Sprint(v)
+		}
+		if v, present := b["args"]; present {
+			values, err := composeMapping(v)
+			if err != nil {
+				return dockerBuilder{}, errors.Wrap(err, "reading build args")
+			}
+			args = map[string]*string{}
+			for key, value := range values {
+				args[key] = value
+			}
+		}
+	default:
+		return dockerBuilder{}, fmt.Errorf("invalid build: %v", build)
+	}
+
+	return dockerBuilder{
+		Workspace:  filepath.Join(baseDir, context),
+		Dockerfile: dockerfile,
+		BuildArgs:  args,
+	}, nil
+}
+
+// convertService creates a Deployment, a Service if ports are published or exposed,
+// a ConfigMap for the env files and a PersistentVolumeClaim for each named volume
+// that isn't claimed by a previous service. The objects are named after the given,
+// already normalized, name.
+func convertService(baseDir, name, image string, service composeService, namedVolumes map[string]interface{}, claimed map[string]bool) ([]runtime.Object, error) {
+	labels := map[string]string{"app": name}
+
+	container := v1.Container{
+		Name:  name,
+		Image: image,
+	}
+	var objects []runtime.Object
+
+	var err error
+	if container.Command, err = composeCommand(service.Entrypoint); err != nil {
+		return nil, errors.Wrap(err, "reading entrypoint")
+	}
+	if container.Args, err = composeCommand(service.Command); err != nil {
+		return nil, errors.Wrap(err, "reading command")
+	}
+
+	// Environment
+	env, err := composeMapping(service.Environment)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading environment")
+	}
+	for _, key := range sortedKeys(env) {
+		value := ""
+		if env[key] != nil {
+			value = *env[key]
+		} else {
+			logrus.Warnf("environment variable %s of service %s has no value", key, name)
+		}
+		container.Env = append(container.Env, v1.EnvVar{Name: key, Value: value})
+	}
+
+	envFiles, err := composeStrings(service.EnvFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading env_file")
+	}
+	if len(envFiles) > 0 {
+		data := map[string]string{}
+		for _, envFile := range envFiles {
+			if err := readEnvFile(filepath.Join(baseDir, envFile), data); err != nil {
+				return nil, err
+			}
+		}
+		configMap := name + "-env"
+		objects = append(objects, &v1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Name: configMap, Labels: labels},
+			Data:       data,
+		})
+		container.EnvFrom = append(container.EnvFrom, v1.EnvFromSource{
+			ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: configMap}},
+		})
+	}
+
+	// Ports
+	var ports []composePort
+	for _, p := range service.Ports {
+		parsed, err := parseComposePort(p)
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, parsed...)
+	}
+	for _, p := range service.Expose {
+		parsed, err := parseComposePort(fmt.Sprint(p))
+		if err != nil {
+			return nil, err
+		}
+		ports = append(ports, parsed...)
+	}
+
+	var servicePorts []v1.ServicePort
+	seenTargets := map[string]bool{}
+	seenPublished := map[string]bool{}
+	for _, p := range ports {
+		protocol := strings.ToUpper(p.Protocol)
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		// TCP is the default protocol and is left out of the manifests.
+		manifestProtocol := protocol
+		if protocol == "TCP" {
+			manifestProtocol = ""
+		}
+
+		target := fmt.Sprintf("%d/%s", p.Target, protocol)
+		if !seenTargets[target] {
+			seenTargets[target] = true
+			container.Ports = append(container.Ports, v1.ContainerPort{ContainerPort: int32(p.Target), Protocol: v1.Protocol(manifestProtocol)})
+		}
+
+		published := p.Published
+		if published == 0 {
+			published = p.Target
+		}
+		// A port of the Service can only be mapped once per protocol.
+		portName := fmt.Sprintf("%s-%d", strings.ToLower(protocol), published)
+		if seenPublished[portName] {
+			logrus.Warnf("port %d/%s of service %s is published more than once, skipping", published, protocol, name)
+			continue
+		}
+		seenPublished[portName] = true
+		servicePorts = append(servicePorts, v1.ServicePort{
+			Name:       portName,
+			Port:       int32(published),
+			TargetPort: intstr.FromInt(p.Target),
+			Protocol:   v1.Protocol(manifestProtocol),
+		})
+	}
+
+	// Volumes
+	var volumes []v1.Volume
+	for i, v := range service.Volumes {
+		volume, mount, err := parseComposeVolume(v)
+		if err != nil {
+			return nil, err
+		}
+
+		switch volume.Type {
+		case "bind":
+			logrus.Warnf("bind mount %s of service %s is not supported, skipping", volume.Source, name)
+			continue
+		case "tmpfs":
+			mount.Name = fmt.Sprintf("%s-tmpfs-%d", name, i)
+			volumes = append(volumes, v1.Volume{
+				Name:         mount.Name,
+				VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{Medium: v1.StorageMediumMemory}},
+			})
+		case "volume":
+			if volume.Source == "" {
+				mount.Name = fmt.Sprintf("%s-volume-%d", name, i)
+				volumes = append(volumes, v1.Volume{
+					Name:         mount.Name,
+					VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+				})
+				break
+			}
+
+			if _, present := namedVolumes[volume.Source]; !present {
+				logrus.Warnf("volume %s of service %s is not declared in the top-level volumes", volume.Source, name)
+			}
+			claim := manifestName(volume.Source)
+			mount.Name = claim
+			volumes = append(volumes, v1.Volume{
+				Name:         claim,
+				VolumeSource: v1.VolumeSource{PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: claim}},
+			})
+			if !claimed[claim] {
+				claimed[claim] = true
+				objects = append(objects, &v1.PersistentVolumeClaim{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "PersistentVolumeClaim"},
+					ObjectMeta: metav1.ObjectMeta{Name: claim},
+					Spec: v1.PersistentVolumeClaimSpec{
+						AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteOnce},
+						Resources: v1.ResourceRequirements{
+							Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+						},
+					},
+				})
+			}
+		default:
+			logrus.Warnf("volume of type %s of service %s is not supported, skipping", volume.Type, name)
+			continue
+		}
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+	}
+
+	replicas := int32(1)
+	objects = append(objects, &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{container},
+					Volumes:    volumes,
+				},
+			},
+		},
+	})
+
+	if len(servicePorts) > 0 {
+		objects = append(objects, &v1.Service{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec: v1.ServiceSpec{
+				Selector: labels,
+				Ports:    servicePorts,
+			},
+		})
+	}
+
+	return objects, nil
+}
+
+// marshalObjects serializes Kubernetes objects into a multi-document yaml.
+// Objects are encoded as JSON, then converted to YAML.
+func marshalObjects(objects []runtime.Object) ([]byte, error) {
+	serializer := json.NewSerializer(json.DefaultMetaFactory, scheme.Scheme, scheme.Scheme, false)
+
+	var documents [][]byte
+	for _, object := range objects {
+		var buf bytes.Buffer
+		if err := serializer.Encode(object, &buf); err != nil {
+			return nil, errors.Wrapf(err, "marshalling %s", object.GetObjectKind().GroupVersionKind().Kind)
+		}
+		document, err := ghodssyaml.JSONToYAML(buf.Bytes())
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting %s to yaml", object.GetObjectKind().GroupVersionKind().Kind)
+		}
+		documents = append(documents, document)
+	}
+	return bytes.Join(documents, []byte("---\n")), nil
+}
+
+// composeMapping reads a map or a list of `KEY=VALUE` strings. Keys without
+// a value are mapped to nil.
+func composeMapping(v interface{}) (map[string]*string, error) {
+	values := map[string]*string{}
+	switch m := v.(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		for key, value := range m {
+			if value == nil {
+				values[fmt.Sprint(key)] = nil
+				continue
+			}
+			s := fmt.Sprint(value)
+			values[fmt.Sprint(key)] = &s
+		}
+	case []interface{}:
+		for _, item := range m {
+			kv := strings.SplitN(fmt.Sprint(item), "=", 2)
+			if len(kv) == 1 {
+				values[kv[0]] = nil
+				continue
+			}
+			values[kv[0]] = &kv[1]
+		}
+	default:
+		return nil, fmt.Errorf("expected a map or a list, got %v", v)
+	}
+	return values, nil
+}
+
+// composeStrings reads a string or a list of strings.
+func composeStrings(v interface{}) ([]string, error) {
+	switch s := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{s}, nil
+	case []interface{}:
+		var values []string
+		for _, item := range s {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("expected a string or a list, got %v", v)
+	}
+}
+
+// composeCommand reads a command, either as a list or as a string that
+// is split like a shell would.
+func composeCommand(v interface{}) ([]string, error) {
+	if command, ok := v.(string); ok {
+		return shell.NewLex('\\').ProcessWords(command, util.OSEnviron())
+	}
+	return composeStrings(v)
+}
+
+func sortedKeys(m map[string]*string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readEnvFile reads the `KEY=VALUE` lines of an env file.
+func readEnvFile(path string, data map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "opening env file")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 1 {
+			data[kv[0]] = ""
+			continue
+		}
+		data[kv[0]] = kv[1]
+	}
+	return errors.Wrapf(scanner.Err(), "reading env file %s", path)
+}
+
+// parseComposePort reads the short syntax, `[[ip:]published:]target[/protocol]`
+// where ports can be ranges, or the long syntax of a port.
+func parseComposePort(v interface{}) ([]composePort, error) {
+	switch p := v.(type) {
+	case int:
+		return []composePort{{Target: p}}, nil
+	case map[interface{}]interface{}:
+		target, err := strconv.Atoi(fmt.Sprint(p["target"]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid target port: %v", p["target"])
+		}
+		port := composePort{Target: target}
+		if published, present := p["published"]; present {
+			if port.Published, err = strconv.Atoi(fmt.Sprint(published)); err != nil {
+				return nil, fmt.Errorf("invalid published port: %v", published)
+			}
+		}
+		if protocol, present := p["protocol"]; present {
+			port.Protocol = fmt.Sprint(protocol)
+		}
+		return []composePort{port}, nil
+	}
+
+	spec := fmt.Sprint(v)
+	protocol := ""
+	if i := strings.Index(spec, "/"); i != -1 {
+		spec, protocol = spec[:i], spec[i+1:]
+	}
+
+	parts := strings.Split(spec, ":")
+	targets, err := parsePortRange(parts[len(parts)-1])
+	if err != nil {
+		return nil, err
+	}
+	published := make([]int, len(targets))
+	if len(parts) > 1 && parts[len(parts)-2] != "" {
+		if published, err = parsePortRange(parts[len(parts)-2]); err != nil {
+			return nil, err
+		}
+	}
+	if len(published) != len(targets) {
+		return nil, fmt.Errorf("invalid port %v: ranges don't match", v)
+	}
+
+	var ports []composePort
+	for i, target := range targets {
+		ports = append(ports, composePort{
+			Published: published[i],
+			Target:    target,
+			Protocol:  protocol,
+		})
+	}
+	return ports, nil
+}
+
+func parsePortRange(portRange string) ([]int, error) {
+	bounds := strings.SplitN(portRange, "-", 2)
+	start, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %s", portRange)
+	}
+	end := start
+	if len(bounds) == 2 {
+		if end, err = strconv.Atoi(bounds[1]); err != nil {
+			return nil, fmt.Errorf("invalid port %s", portRange)
+		}
+	}
+
+	var ports []int
+	for port := start; port <= end; port++ {
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// composeVolume is the long syntax of a volume.
+type composeVolume struct {
+	Type   string
+	Source string
+}
+
+// parseComposeVolume reads the short syntax, `[source:]target[:mode]`, or
+// the long syntax of a volume.
+func parseComposeVolume(v interface{}) (composeVolume, v1.VolumeMount, error) {
+	if m, ok := v.(map[interface{}]interface{}); ok {
+		volume := composeVolume{Type: "volume"}
+		if t, present := m["type"]; present {
+			volume.Type = fmt.Sprint(t)
+		}
+		if source, present := m["source"]; present {
+			volume.Source = fmt.Sprint(source)
+		}
+		target, present := m["target"]
+		if !present {
+			return volume, v1.VolumeMount{}, fmt.Errorf("missing target for volume %v", v)
+		}
+		readOnly, _ := m["read_only"].(bool)
+		return volume, v1.VolumeMount{MountPath: fmt.Sprint(target), ReadOnly: readOnly}, nil
+	}
+
+	parts := strings.Split(fmt.Sprint(v), ":")
+	volume := composeVolume{Type: "volume"}
+	mount := v1.VolumeMount{}
+	switch len(parts) {
+	case 1:
+		mount.MountPath = parts[0]
+	case 2, 3:
+		volume.Source = parts[0]
+		mount.MountPath = parts[1]
+		mount.ReadOnly = len(parts) == 3 && parts[2] == "ro"
+	default:
+		return volume, mount, fmt.Errorf("invalid volume %v", v)
+	}
+
+	if strings.HasPrefix(volume.Source, ".") || strings.HasPrefix(volume.Source, "/") || strings.HasPrefix(volume.Source, "~") {
+		volume.Type = "bind"
+	}
+	return volume, mount, nil
+}
@@ -0,0 +1,420 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseComposePort(t *testing.T) {
+	var tests = []struct {
+		description string
+		port        interface{}
+		expected    []composePort
+		shouldErr   bool
+	}{
+		{
+			description: "number",
+			port:        3000,
+			expected:    []composePort{{Target: 3000}},
+		},
+		{
+			description: "published",
+			port:        "8000:80",
+			expected:    []composePort{{Published: 8000, Target: 80}},
+		},
+		{
+			description: "ip and protocol",
+			port:        "127.0.0.1:5000:5000/udp",
+			expected:    []composePort{{Published: 5000, Target: 5000, Protocol: "udp"}},
+		},
+		{
+			description: "ranges",
+			port:        "9090-9091:8080-8081",
+			expected:    []composePort{{Published: 9090, Target: 8080}, {Published: 9091, Target: 8081}},
+		},
+		{
+			description: "target range",
+			port:        "3000-3001",
+			expected:    []composePort{{Target: 3000}, {Target: 3001}},
+		},
+		{
+			description: "long syntax",
+			port: map[interface{}]interface{}{
+				"target":    80,
+				"published": 8080,
+				"protocol":  "tcp",
+			},
+			expected: []composePort{{Published: 8080, Target: 80, Protocol: "tcp"}},
+		},
+		{
+			description: "ranges don't match",
+			port:        "9090-9092:8080-8081",
+			shouldErr:   true,
+		},
+		{
+			description: "invalid",
+			port:        "http",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ports, err := parseComposePort(test.port)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, ports)
+		})
+	}
+}
+
+func TestParseComposeVolume(t *testing.T) {
+	var tests = []struct {
+		description    string
+		volume         interface{}
+		expectedVolume composeVolume
+		expectedMount  v1.VolumeMount
+	}{
+		{
+			description:    "anonymous",
+			volume:         "/var/lib/data",
+			expectedVolume: composeVolume{Type: "volume"},
+			expectedMount:  v1.VolumeMount{MountPath: "/var/lib/data"},
+		},
+		{
+			description:    "named",
+			volume:         "data:/var/lib/data:ro",
+			expectedVolume: composeVolume{Type: "volume", Source: "data"},
+			expectedMount:  v1.VolumeMount{MountPath: "/var/lib/data", ReadOnly: true},
+		},
+		{
+			description:    "bind mount",
+			volume:         "./cache:/tmp/cache",
+			expectedVolume: composeVolume{Type: "bind", Source: "./cache"},
+			expectedMount:  v1.VolumeMount{MountPath: "/tmp/cache"},
+		},
+		{
+			description: "long syntax",
+			volume: map[interface{}]interface{}{
+				"type":   "tmpfs",
+				"target": "/run",
+			},
+			expectedVolume: composeVolume{Type: "tmpfs"},
+			expectedMount:  v1.VolumeMount{MountPath: "/run"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			volume, mount, err := parseComposeVolume(test.volume)
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expectedVolume, volume)
+			testutil.CheckDeepEqual(t, test.expectedMount, mount)
+		})
+	}
+}
+
+func TestConvertComposeFile(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("docker-compose.yml", `version: "3"
+services:
+  web:
+    build:
+      context: ./web
+      dockerfile: Dockerfile.dev
+      args:
+        VERSION: "1.0"
+    image: gcr.io/k8s-skaffold/web
+    ports:
+    - "8080:80"
+    env_file: web.env
+    volumes:
+    - data:/data
+  api:
+    build:
+      context: api
+      dockerfile: docker/Dockerfile
+  worker:
+    build: worker
+    volumes:
+    - data:/data
+  redis:
+    image: redis
+volumes:
+  data:
+`).Write("web.env", "# comment\nKEY=value\n")
+
+	manifests, pairs, err := convertComposeFile(tmpDir.Path("docker-compose.yml"))
+	testutil.CheckError(t, false, err)
+
+	version := "1.0"
+	testutil.CheckDeepEqual(t, []builderPair{{
+		Builder:   dockerBuilder{Workspace: tmpDir.Path("api"), Dockerfile: "docker/Dockerfile"},
+		ImageName: "api",
+	}, {
+		Builder: dockerBuilder{
+			Workspace:  tmpDir.Path("web"),
+			Dockerfile: "Dockerfile.dev",
+			BuildArgs:  map[string]*string{"VERSION": &version},
+		},
+		ImageName: "gcr.io/k8s-skaffold/web",
+	}, {
+		Builder:   dockerBuilder{Workspace: tmpDir.Path("worker"), Dockerfile: "Dockerfile"},
+		ImageName: "worker",
+	}}, pairs)
+
+	var paths []string
+	for _, manifest := range manifests {
+		paths = append(paths, manifest.Path)
+	}
+	testutil.CheckDeepEqual(t, []string{
+		filepath.Join("k8s", "api.yaml"),
+		filepath.Join("k8s", "redis.yaml"),
+		filepath.Join("k8s", "web.yaml"),
+		filepath.Join("k8s", "worker.yaml"),
+	}, paths)
+
+	// The generated manifests must be valid
+	expectedImages := [][]string{{"api"}, {"redis"}, {"gcr.io/k8s-skaffold/web"}, {"worker"}}
+	for i, manifest := range manifests {
+		tmpDir.Write(manifest.Path, string(manifest.Content))
+		images, err := parseKubernetesYaml(tmpDir.Path(manifest.Path))
+		testutil.CheckErrorAndDeepEqual(t, false, err, expectedImages[i], images)
+	}
+
+	testutil.CheckDeepEqual(t, `apiVersion: v1
+data:
+  KEY: value
+kind: ConfigMap
+metadata:
+  creationTimestamp: null
+  labels:
+    app: web
+  name: web-env
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  creationTimestamp: null
+  name: data
+spec:
+  accessModes:
+  - ReadWriteOnce
+  resources:
+    requests:
+      storage: 1Gi
+status: {}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  creationTimestamp: null
+  labels:
+    app: web
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  strategy: {}
+  template:
+    metadata:
+      creationTimestamp: null
+      labels:
+        app: web
+    spec:
+      containers:
+      - envFrom:
+        - configMapRef:
+            name: web-env
+        image: gcr.io/k8s-skaffold/web
+        name: web
+        ports:
+        - containerPort: 80
+        resources: {}
+        volumeMounts:
+        - mountPath: /data
+          name: data
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+status: {}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  creationTimestamp: null
+  labels:
+    app: web
+  name: web
+spec:
+  ports:
+  - name: tcp-8080
+    port: 8080
+    targetPort: 80
+  selector:
+    app: web
+status:
+  loadBalancer: {}
+`, string(manifests[2].Content))
+}
+
+func TestConvertComposeFilePorts(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("docker-compose.yml", `version: "3"
+services:
+  dns:
+    image: dns
+    ports:
+    - "80"
+    - "8080:80"
+    - "53:53/udp"
+    - "53:53"
+    expose:
+    - "80"
+`)
+
+	manifests, _, err := convertComposeFile(tmpDir.Path("docker-compose.yml"))
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  creationTimestamp: null
+  labels:
+    app: dns
+  name: dns
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: dns
+  strategy: {}
+  template:
+    metadata:
+      creationTimestamp: null
+      labels:
+        app: dns
+    spec:
+      containers:
+      - image: dns
+        name: dns
+        ports:
+        - containerPort: 80
+        - containerPort: 53
+          protocol: UDP
+        - containerPort: 53
+        resources: {}
+status: {}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  creationTimestamp: null
+  labels:
+    app: dns
+  name: dns
+spec:
+  ports:
+  - name: tcp-80
+    port: 80
+    targetPort: 80
+  - name: tcp-8080
+    port: 8080
+    targetPort: 80
+  - name: udp-53
+    port: 53
+    protocol: UDP
+    targetPort: 53
+  - name: tcp-53
+    port: 53
+    targetPort: 53
+  selector:
+    app: dns
+status:
+  loadBalancer: {}
+`, string(manifests[0].Content))
+}
+
+func TestConvertComposeFileWithSameNames(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("docker-compose.yml", `version: "3"
+services:
+  my-app:
+    image: app
+  my_app:
+    image: app
+`)
+
+	manifests, _, err := convertComposeFile(tmpDir.Path("docker-compose.yml"))
+
+	testutil.CheckError(t, false, err)
+	var paths []string
+	for _, manifest := range manifests {
+		paths = append(paths, manifest.Path)
+	}
+	testutil.CheckDeepEqual(t, []string{
+		filepath.Join("k8s", "my-app.yaml"),
+		filepath.Join("k8s", "my-app-2.yaml"),
+	}, paths)
+	testutil.CheckDeepEqual(t, true, strings.Contains(string(manifests[1].Content), "name: my-app-2\n"))
+}
+
+func TestConvertComposeFileErrors(t *testing.T) {
+	var tests = []struct {
+		description string
+		compose     string
+	}{
+		{
+			description: "version 1",
+			compose:     "web:\n  image: nginx",
+		},
+		{
+			description: "no image nor build",
+			compose:     "version: '2'\nservices:\n  web:\n    ports: ['80']",
+		},
+		{
+			description: "invalid port",
+			compose:     "version: '2'\nservices:\n  web:\n    image: nginx\n    ports: ['http']",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			tmpDir.Write("docker-compose.yml", test.compose)
+
+			_, _, err := convertComposeFile(tmpDir.Path("docker-compose.yml"))
+
+			testutil.CheckError(t, true, err)
+		})
+	}
+}
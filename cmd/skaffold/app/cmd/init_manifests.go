@@ -0,0 +1,230 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/pkg/errors"
+	"gopkg.in/AlecAivazis/survey.v1"
+)
+
+// manifestsDir is where generated manifests are written.
+const manifestsDir = "k8s"
+
+var manifestTemplate = template.Must(template.New("manifest").Parse(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{.Name}}
+  labels:
+    app: {{.Name}}
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: {{.Name}}
+  template:
+    metadata:
+      labels:
+        app: {{.Name}}
+    spec:
+      containers:
+      - name: {{.Name}}
+        image: {{.Image}}
+{{- if .Ports}}
+        ports:
+{{- range .Ports}}
+        - containerPort: {{.Port}}
+{{- if .Protocol}}
+          protocol: {{.Protocol}}
+{{- end}}
+{{- end}}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{.Name}}
+  labels:
+    app: {{.Name}}
+spec:
+  selector:
+    app: {{.Name}}
+  ports:
+{{- range .Ports}}
+  - name: {{.Name}}
+    port: {{.Port}}
+    targetPort: {{.Port}}
+{{- if .Protocol}}
+    protocol: {{.Protocol}}
+{{- end}}
+{{- end}}
+{{- end}}
+`))
+
+// manifestPort is a port of the generated manifests. The protocol is left
+// out when it's TCP, the default.
+type manifestPort struct {
+	Name     string
+	Port     int
+	Protocol string
+}
+
+// generatedManifest is a Kubernetes manifest generated by `skaffold init`.
+type generatedManifest struct {
+	Path    string
+	Content []byte
+}
+
+// generateManifests generates a Deployment, and a Service if ports are
+// exposed, for each image built from a Dockerfile. Images whose names end
+// the same, like `gcr.io/a/app` and `gcr.io/b/app`, get a numeric suffix.
+func generateManifests(pairs []builderPair) ([]generatedManifest, error) {
+	var manifests []generatedManifest
+	used := map[string]bool{}
+	for _, pair := range pairs {
+		b, ok := pair.Builder.(dockerBuilder)
+		if !ok {
+			continue
+		}
+
+		ports, err := docker.ExposedPorts(b.path())
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading exposed ports of %s", b.path())
+		}
+
+		name := uniqueManifestName(pair.ImageName, used)
+		content, err := generateManifest(name, pair.ImageName, ports)
+		if err != nil {
+			return nil, err
+		}
+
+		manifests = append(manifests, generatedManifest{
+			Path:    filepath.Join(manifestsDir, name+".yaml"),
+			Content: content,
+		})
+	}
+	return manifests, nil
+}
+
+func generateManifest(name, image string, exposed []docker.ExposedPort) ([]byte, error) {
+	var ports []manifestPort
+	for _, p := range exposed {
+		port := manifestPort{
+			Name: fmt.Sprintf("%s-%d", p.Protocol, p.Port),
+			Port: p.Port,
+		}
+		if p.Protocol != "tcp" {
+			port.Protocol = strings.ToUpper(p.Protocol)
+		}
+		ports = append(ports, port)
+	}
+
+	var buf bytes.Buffer
+	err := manifestTemplate.Execute(&buf, struct {
+		Name  string
+		Image string
+		Ports []manifestPort
+	}{
+		Name:  name,
+		Image: image,
+		Ports: ports,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "generating manifest")
+	}
+	return buf.Bytes(), nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// manifestName turns an image name into a valid Kubernetes name.
+// `gcr.io/project/my_app:v1` becomes `my-app`.
+func manifestName(image string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.IndexAny(name, ":@"); i != -1 {
+		name = name[:i]
+	}
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	return strings.Trim(name, "-")
+}
+
+// uniqueManifestName returns the manifest name of an image or a service,
+// with a numeric suffix if the name is already used.
+func uniqueManifestName(image string, used map[string]bool) string {
+	name := manifestName(image)
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s-%d", manifestName(image), i)
+	}
+	used[name] = true
+	return name
+}
+
+// promptUserForImages asks the user for the name of the image built
+// from each Dockerfile.
+func promptUserForImages(builders []initBuilder) ([]builderPair, error) {
+	var pairs []builderPair
+	for _, builder := range builders {
+		b, ok := builder.(dockerBuilder)
+		if !ok {
+			continue
+		}
+
+		dir, err := filepath.Abs(filepath.Dir(b.path()))
+		if err != nil {
+			return nil, err
+		}
+
+		var image string
+		prompt := &survey.Input{
+			Message: fmt.Sprintf("Choose the image name for %s", b.path()),
+			Default: manifestName(filepath.Base(dir)),
+		}
+		if err := survey.AskOne(prompt, &image, nil); err != nil {
+			return nil, errors.Wrap(err, "reading image name")
+		}
+
+		pairs = append(pairs, builderPair{
+			Builder:   b,
+			ImageName: image,
+		})
+	}
+	return pairs, nil
+}
+
+// writeManifests writes the generated manifests, without overwriting existing files.
+func writeManifests(manifests []generatedManifest) error {
+	for _, manifest := range manifests {
+		if _, err := os.Stat(manifest.Path); err == nil && !force {
+			return fmt.Errorf("%s already exists, use --force to overwrite it", manifest.Path)
+		}
+		if err := os.MkdirAll(filepath.Dir(manifest.Path), 0755); err != nil {
+			return errors.Wrap(err, "creating manifests directory")
+		}
+		if err := ioutil.WriteFile(manifest.Path, manifest.Content, 0644); err != nil {
+			return errors.Wrapf(err, "writing %s", manifest.Path)
+		}
+	}
+	return nil
+}
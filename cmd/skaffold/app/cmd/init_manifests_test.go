@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestManifestName(t *testing.T) {
+	var tests = []struct {
+		image    string
+		expected string
+	}{
+		{image: "web", expected: "web"},
+		{image: "gcr.io/project/my_app:v1", expected: "my-app"},
+		{image: "localhost:5000/Backend@sha256:abacabac", expected: "backend"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.image, func(t *testing.T) {
+			testutil.CheckDeepEqual(t, test.expected, manifestName(test.image))
+		})
+	}
+}
+
+func TestGenerateManifests(t *testing.T) {
+	var tests = []struct {
+		description string
+		dockerfile  string
+		expected    string
+	}{
+		{
+			description: "with exposed ports",
+			dockerfile:  "FROM nginx\nEXPOSE 80 80\nEXPOSE 53/udp",
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: gcr.io/k8s-skaffold/web
+        ports:
+        - containerPort: 80
+        - containerPort: 53
+          protocol: UDP
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  selector:
+    app: web
+  ports:
+  - name: tcp-80
+    port: 80
+    targetPort: 80
+  - name: udp-53
+    port: 53
+    targetPort: 53
+    protocol: UDP
+`,
+		},
+		{
+			description: "no exposed ports",
+			dockerfile:  "FROM busybox",
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: gcr.io/k8s-skaffold/web
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			tmpDir.Write("Dockerfile", test.dockerfile)
+
+			manifests, err := generateManifests([]builderPair{
+				{Builder: dockerBuilder{Dockerfile: tmpDir.Path("Dockerfile")}, ImageName: "gcr.io/k8s-skaffold/web"},
+				{Builder: jibBuilder{BuildFile: tmpDir.Path("pom.xml")}, ImageName: "gcr.io/k8s-skaffold/java"},
+			})
+
+			testutil.CheckError(t, false, err)
+			testutil.CheckDeepEqual(t, 1, len(manifests))
+			testutil.CheckDeepEqual(t, filepath.Join("k8s", "web.yaml"), manifests[0].Path)
+			testutil.CheckDeepEqual(t, test.expected, string(manifests[0].Content))
+
+			// The generated manifest must be valid
+			tmpDir.Write("web.yaml", string(manifests[0].Content))
+			images, err := parseKubernetesYaml(tmpDir.Path("web.yaml"))
+			testutil.CheckErrorAndDeepEqual(t, false, err, []string{"gcr.io/k8s-skaffold/web"}, images)
+		})
+	}
+}
+
+func TestGenerateManifestsWithSameNames(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	tmpDir.Write("a/Dockerfile", "FROM busybox").
+		Write("b/Dockerfile", "FROM busybox").
+		Write("c/Dockerfile", "FROM busybox")
+
+	manifests, err := generateManifests([]builderPair{
+		{Builder: dockerBuilder{Dockerfile: tmpDir.Path("a/Dockerfile")}, ImageName: "gcr.io/a/app"},
+		{Builder: dockerBuilder{Dockerfile: tmpDir.Path("b/Dockerfile")}, ImageName: "gcr.io/b/app"},
+		{Builder: dockerBuilder{Dockerfile: tmpDir.Path("c/Dockerfile")}, ImageName: "gcr.io/c/app"},
+	})
+
+	testutil.CheckError(t, false, err)
+	var paths []string
+	for _, manifest := range manifests {
+		paths = append(paths, manifest.Path)
+	}
+	testutil.CheckDeepEqual(t, []string{
+		filepath.Join("k8s", "app.yaml"),
+		filepath.Join("k8s", "app-2.yaml"),
+		filepath.Join("k8s", "app-3.yaml"),
+	}, paths)
+	testutil.CheckDeepEqual(t, true, strings.Contains(string(manifests[1].Content), "name: app-2\n"))
+}
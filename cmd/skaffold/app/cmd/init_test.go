@@ -18,6 +18,7 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"testing"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
@@ -31,11 +32,24 @@ deploy:
   kubectl: {}
 `, latest.Version)
 
-	buf, err := generateSkaffoldPipeline(nil, nil)
+	buf, err := generateSkaffoldPipeline(latest.DeployConfig{
+		DeployType: latest.DeployType{KubectlDeploy: &latest.KubectlDeploy{}},
+	}, nil)
 
 	testutil.CheckErrorAndDeepEqual(t, false, err, expectedYaml, string(buf))
 }
 
+func TestInitAnalyzeComposeFile(t *testing.T) {
+	defer func(analyzeFlag bool, composeFileFlag string) {
+		analyze, composeFile = analyzeFlag, composeFileFlag
+	}(analyze, composeFile)
+	analyze, composeFile = true, "docker-compose.yml"
+
+	err := doInit(ioutil.Discard)
+
+	testutil.CheckError(t, true, err)
+}
+
 func TestGenerateSkaffoldPipeline(t *testing.T) {
 	expectedYaml := fmt.Sprintf(`apiVersion: %s
 kind: Config
@@ -50,13 +64,28 @@ deploy:
     - k8s/deployment.yaml
 `, latest.Version)
 
-	k8sConfigs := []string{"k8s/deployment.yaml"}
-	dockerfilePairs := []dockerfilePair{{
-		Dockerfile: "dockerfile.test",
-		ImageName:  "docker/image",
+	deploy := deployConfig([]string{"k8s/deployment.yaml"}, nil, nil)
+	pairs := []builderPair{{
+		Builder:   dockerBuilder{Dockerfile: "dockerfile.test"},
+		ImageName: "docker/image",
 	}}
 
-	buf, err := generateSkaffoldPipeline(k8sConfigs, dockerfilePairs)
+	buf, err := generateSkaffoldPipeline(deploy, pairs)
 
 	testutil.CheckErrorAndDeepEqual(t, false, err, expectedYaml, string(buf))
 }
+
+func TestParseImagesFromYaml(t *testing.T) {
+	doc := map[interface{}]interface{}{
+		"image": map[interface{}]interface{}{
+			"repository": "gcr.io/k8s-skaffold/example",
+		},
+		"containers": []interface{}{
+			map[interface{}]interface{}{"image": "gcr.io/k8s-skaffold/other"},
+		},
+	}
+
+	images := parseImagesFromYaml(doc)
+
+	testutil.CheckDeepEqual(t, []string{"gcr.io/k8s-skaffold/other"}, images)
+}
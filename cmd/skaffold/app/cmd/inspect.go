@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+var inspectOutput string
+
+// NewCmdInspect describes the CLI command to print the effective pipeline.
+func NewCmdInspect(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "inspect",
+		Aliases: []string{"validate"},
+		Short:   "Prints and validates the effective pipeline, once profiles and default values are applied",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return inspect(out)
+		},
+	}
+	cmd.Flags().StringVarP(&opts.ConfigurationFile, "filename", "f", "skaffold.yaml", "Filename or URL to the pipeline file")
+	cmd.Flags().StringArrayVarP(&opts.Profiles, "profile", "p", nil, "Activate profiles by name. Prefix a profile name with '-' to deactivate it")
+	cmd.Flags().StringArrayVar(&opts.Modules, "module", nil, "Only merge the required pipeline files of these modules. Set multiple times for multiple modules")
+	cmd.Flags().StringVar(&opts.VarFile, "var-file", "", "YAML file of values available to the templates of the pipeline file")
+	cmd.Flags().StringVarP(&inspectOutput, "output", "o", "yaml", "Output format: yaml or json")
+	return cmd
+}
+
+func inspect(out io.Writer) error {
+	inspection, err := schema.InspectPipeline(out, opts.ConfigurationFile, opts)
+	if err != nil {
+		return errors.Wrap(err, "loading skaffold config")
+	}
+
+	buf, err := yamlv2.Marshal(inspection.Pipeline)
+	if err != nil {
+		return errors.Wrap(err, "marshalling pipeline")
+	}
+
+	switch inspectOutput {
+	case "yaml":
+		fmt.Fprint(out, annotateSections(string(buf), inspection.Origins))
+		for _, e := range inspection.Errors {
+			fmt.Fprintf(out, "# error: %s\n", e)
+		}
+	case "json":
+		pipeline, err := yaml.YAMLToJSON(buf)
+		if err != nil {
+			return errors.Wrap(err, "converting pipeline to json")
+		}
+		encoded, err := json.MarshalIndent(struct {
+			Pipeline json.RawMessage          `json:"pipeline"`
+			Profiles schema.Origins           `json:"profiles,omitempty"`
+			Errors   []schema.ValidationError `json:"errors,omitempty"`
+		}{pipeline, inspection.Origins, inspection.Errors}, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "marshalling inspection")
+		}
+		fmt.Fprintln(out, string(encoded))
+	default:
+		return fmt.Errorf("unknown output format %s, should be yaml or json", inspectOutput)
+	}
+
+	if len(inspection.Errors) > 0 {
+		return fmt.Errorf("invalid pipeline: %d error(s) found", len(inspection.Errors))
+	}
+	return nil
+}
+
+// annotateSections adds a comment above each top-level section set by profiles.
+func annotateSections(pipeline string, origins schema.Origins) string {
+	var annotated []string
+	for _, line := range strings.SplitAfter(pipeline, "\n") {
+		if !strings.HasPrefix(line, " ") {
+			section := strings.SplitN(line, ":", 2)[0]
+			if profiles, present := origins[section]; present {
+				annotated = append(annotated, fmt.Sprintf("# %s set by profiles: %s\n", section, strings.Join(profiles, ", ")))
+			}
+		}
+		annotated = append(annotated, line)
+	}
+	return strings.Join(annotated, "")
+}
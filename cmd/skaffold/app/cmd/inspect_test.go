@@ -0,0 +1,34 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestAnnotateSections(t *testing.T) {
+	pipeline := "apiVersion: skaffold/v1beta1\nkind: Config\nbuild:\n  local: {}\ndeploy:\n  helm: {}\n"
+
+	annotated := annotateSections(pipeline, schema.Origins{
+		"deploy": {"prod", "staging"},
+	})
+
+	testutil.CheckDeepEqual(t, "apiVersion: skaffold/v1beta1\nkind: Config\nbuild:\n  local: {}\n# deploy set by profiles: prod, staging\ndeploy:\n  helm: {}\n", annotated)
+}
@@ -38,6 +38,7 @@ func NewCmdRun(out io.Writer) *cobra.Command {
 	AddRunDeployFlags(cmd)
 
 	cmd.Flags().StringVarP(&opts.CustomTag, "tag", "t", "", "The optional custom tag to use for images which overrides the current Tagger configuration")
+	cmd.Flags().BoolVar(&opts.PortForward, "port-forward", false, "Port-forward exposed container ports within pods and the resources listed in the pipeline's portForward section")
 	return cmd
 }
 
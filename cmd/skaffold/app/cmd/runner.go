@@ -21,6 +21,7 @@ import (
 
 	configutil "github.com/GoogleContainerTools/skaffold/cmd/skaffold/app/cmd/config"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/runner"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
@@ -29,22 +30,10 @@ import (
 )
 
 // newRunner creates a SkaffoldRunner and returns the SkaffoldPipeline associated with it.
-func newRunner(out io.Writer, opts *config.SkaffoldOptions) (*runner.SkaffoldRunner, *latest.SkaffoldPipeline, error) {
-	parsed, err := schema.ParseConfig(opts.ConfigurationFile, true)
+func newRunner(out io.Writer, opts *config.SkaffoldOptions, manifestTransforms ...deploy.ManifestTransform) (*runner.SkaffoldRunner, *latest.SkaffoldPipeline, error) {
+	config, err := schema.LoadPipeline(out, opts.ConfigurationFile, opts)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "parsing skaffold config")
-	}
-
-	// automatically upgrade older config
-	parsed, err = schema.UpgradeToLatest(out, parsed)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "invalid config")
-	}
-
-	config := parsed.(*latest.SkaffoldPipeline)
-	err = schema.ApplyProfiles(config, opts.Profiles)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "applying profiles")
+		return nil, nil, errors.Wrap(err, "loading skaffold config")
 	}
 
 	defaultRepo, err := configutil.GetDefaultRepo(opts.DefaultRepo)
@@ -56,7 +45,7 @@ func newRunner(out io.Writer, opts *config.SkaffoldOptions) (*runner.SkaffoldRun
 		return nil, nil, errors.Wrap(err, "substituting default repos")
 	}
 
-	runner, err := runner.NewForConfig(opts, config)
+	runner, err := runner.NewForConfig(opts, config, manifestTransforms...)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "creating runner")
 	}
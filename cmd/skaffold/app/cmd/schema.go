@@ -0,0 +1,77 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdSchema describes the CLI command to work with the JSON Schemas of skaffold.yaml.
+func NewCmdSchema(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "A set of commands to work with the JSON Schemas of the pipeline file",
+	}
+	cmd.AddCommand(NewCmdSchemaGet(out))
+	cmd.AddCommand(NewCmdSchemaList(out))
+	return cmd
+}
+
+// NewCmdSchemaGet describes the CLI command to print the JSON Schema of a given version.
+func NewCmdSchemaGet(out io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get [version]",
+		Short: "Prints the JSON Schema of a version of the pipeline file. Defaults to " + latest.Version,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			apiVersion := latest.Version
+			if len(args) > 0 {
+				apiVersion = args[0]
+			}
+			return printSchema(out, apiVersion)
+		},
+	}
+}
+
+// NewCmdSchemaList describes the CLI command to list the supported versions.
+func NewCmdSchemaList(out io.Writer) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "Lists the supported versions of the pipeline file",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			for _, apiVersion := range schema.APIVersions() {
+				fmt.Fprintln(out, apiVersion)
+			}
+		},
+	}
+}
+
+func printSchema(out io.Writer, apiVersion string) error {
+	buf, err := schema.GetJSONSchema(apiVersion)
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(buf)
+	return err
+}
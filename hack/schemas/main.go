@@ -0,0 +1,45 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema"
+)
+
+// Generates the JSON Schemas of all the api versions into the schemas/ folder.
+// Run from the root of the repository: go run hack/schemas/main.go
+func main() {
+	for _, apiVersion := range schema.APIVersions() {
+		buf, err := schema.GetJSONSchema(apiVersion)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		output := filepath.Join("schemas", strings.TrimPrefix(apiVersion, "skaffold/")+".json")
+		if err := ioutil.WriteFile(output, buf, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
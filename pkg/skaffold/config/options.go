@@ -37,6 +37,36 @@ type SkaffoldOptions struct {
 	CustomLabels      []string
 	WatchPollInterval int
 	DefaultRepo       string
+	Logs              LogOptions
+
+	// Modules restricts the required pipeline files that are merged
+	// into the pipeline to the ones with these names.
+	Modules []string
+
+	// VarFile is a YAML file of values available to the templates of the pipeline.
+	VarFile string
+
+	// Command is the skaffold command being run, like `dev` or `run`.
+	Command string
+}
+
+// LogOptions configures how the logs of deployed containers are printed.
+type LogOptions struct {
+	// Include and exclude regular expressions matched against pod names,
+	// container names and log lines.
+	IncludePods       []string
+	ExcludePods       []string
+	IncludeContainers []string
+	ExcludeContainers []string
+	IncludeLines      []string
+	ExcludeLines      []string
+
+	// JSON pretty-prints JSON log lines, showing only JSONFields.
+	JSON       bool
+	JSONFields []string
+
+	// File is an optional file where the log lines are also written.
+	File string
 }
 
 // Labels returns a map of labels to be applied to all deployed
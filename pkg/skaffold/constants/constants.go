@@ -19,6 +19,7 @@ package constants
 import (
 	"fmt"
 	"runtime"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -65,6 +66,9 @@ const (
 
 var DefaultKubectlManifests = []string{"k8s/*.yaml"}
 
+// SyncBackInterval is the interval between two checks for files changed inside the containers.
+var SyncBackInterval = 2 * time.Second
+
 var LatestDownloadURL = fmt.Sprintf("https://storage.googleapis.com/skaffold/releases/latest/skaffold-%s-%s", runtime.GOOS, runtime.GOARCH)
 
 var Labels = struct {
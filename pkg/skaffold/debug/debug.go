@@ -0,0 +1,212 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	appsv1beta2 "k8s.io/api/apps/v1beta2"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	extv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// DebugConfigAnnotation is the pod template annotation that describes
+// how each container was configured for debugging.
+const DebugConfigAnnotation = "debug.skaffold.dev/config"
+
+// retrieveImageConfiguration is overridden for unit testing
+var retrieveImageConfiguration = retrieveImageConfigurationFromImage
+
+// ApplyDebuggingTransforms configures the containers running the built images for remote debugging.
+// The debug endpoints are printed per container.
+func ApplyDebuggingTransforms(out io.Writer, l kubectl.ManifestList, builds []build.Artifact) (kubectl.ManifestList, error) {
+	images := map[string]bool{}
+	for _, b := range builds {
+		images[b.Tag] = true
+	}
+
+	decoder := scheme.Codecs.UniversalDeserializer()
+	var updated kubectl.ManifestList
+	for _, manifest := range l {
+		obj, _, err := decoder.Decode(manifest, nil, nil)
+		if err != nil {
+			logrus.Debugf("Leaving manifest untouched, unable to decode: %s", err)
+			updated = append(updated, manifest)
+			continue
+		}
+
+		if !transformManifest(out, obj, images) {
+			updated = append(updated, manifest)
+			continue
+		}
+
+		buf, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, errors.Wrap(err, "marshalling manifest")
+		}
+		updated = append(updated, buf)
+	}
+
+	return updated, nil
+}
+
+// transformManifest configures the pod spec of a workload. It returns true if the
+// object was changed.
+func transformManifest(out io.Writer, obj runtime.Object, images map[string]bool) bool {
+	switch o := obj.(type) {
+	case *v1.Pod:
+		return transformPodSpec(out, "pod/"+o.Name, &o.ObjectMeta, &o.Spec, images)
+	case *v1.ReplicationController:
+		if o.Spec.Template != nil {
+			return transformPodSpec(out, "replicationcontroller/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+		}
+	case *appsv1.Deployment:
+		return transformPodSpec(out, "deployment/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1beta1.Deployment:
+		return transformPodSpec(out, "deployment/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1beta2.Deployment:
+		return transformPodSpec(out, "deployment/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *extv1beta1.Deployment:
+		return transformPodSpec(out, "deployment/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1.StatefulSet:
+		return transformPodSpec(out, "statefulset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1beta1.StatefulSet:
+		return transformPodSpec(out, "statefulset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1beta2.StatefulSet:
+		return transformPodSpec(out, "statefulset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1.DaemonSet:
+		return transformPodSpec(out, "daemonset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1beta2.DaemonSet:
+		return transformPodSpec(out, "daemonset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *extv1beta1.DaemonSet:
+		return transformPodSpec(out, "daemonset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1.ReplicaSet:
+		return transformPodSpec(out, "replicaset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *appsv1beta2.ReplicaSet:
+		return transformPodSpec(out, "replicaset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *extv1beta1.ReplicaSet:
+		return transformPodSpec(out, "replicaset/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	case *batchv1.Job:
+		return transformPodSpec(out, "job/"+o.Name, &o.Spec.Template.ObjectMeta, &o.Spec.Template.Spec, images)
+	}
+
+	return false
+}
+
+// transformPodSpec configures the containers of a pod spec that run one of the built images.
+func transformPodSpec(out io.Writer, workload string, metadata *metav1.ObjectMeta, podSpec *v1.PodSpec, images map[string]bool) bool {
+	ports := allocatedPorts(podSpec)
+	configurations := map[string]ContainerDebugConfiguration{}
+
+	for i := range podSpec.Containers {
+		container := &podSpec.Containers[i]
+		if !images[container.Image] {
+			continue
+		}
+
+		config, err := retrieveImageConfiguration(container.Image)
+		if err != nil {
+			logrus.Warnf("Unable to configure container %s for debugging: %s", container.Name, err)
+			continue
+		}
+
+		configuration := transformContainer(container, config, ports)
+		if configuration == nil {
+			logrus.Warnf("Unable to determine the runtime of container %s: it is not configured for debugging", container.Name)
+			continue
+		}
+
+		configurations[container.Name] = *configuration
+		printEndpoints(out, workload, container.Name, *configuration)
+	}
+
+	if len(configurations) == 0 {
+		return false
+	}
+
+	encoded, err := json.Marshal(configurations)
+	if err != nil {
+		logrus.Warnf("Unable to encode debug configuration: %s", err)
+	} else {
+		if metadata.Annotations == nil {
+			metadata.Annotations = map[string]string{}
+		}
+		metadata.Annotations[DebugConfigAnnotation] = string(encoded)
+	}
+
+	return true
+}
+
+func printEndpoints(out io.Writer, workload, container string, configuration ContainerDebugConfiguration) {
+	var endpoints []string
+	for name, port := range configuration.Ports {
+		endpoints = append(endpoints, fmt.Sprintf("%s=%d", name, port))
+	}
+	sort.Strings(endpoints)
+
+	color.Default.Fprintf(out, "Debugging %s container %s (%s): %s\n", workload, container, configuration.Runtime, strings.Join(endpoints, ", "))
+}
+
+// allocatedPorts lists the ports already used by the containers of a pod.
+func allocatedPorts(podSpec *v1.PodSpec) map[int32]bool {
+	ports := map[int32]bool{}
+	for _, container := range podSpec.Containers {
+		for _, port := range container.Ports {
+			ports[port.ContainerPort] = true
+		}
+	}
+	return ports
+}
+
+func retrieveImageConfigurationFromImage(image string) (imageConfiguration, error) {
+	config, err := docker.RetrieveImage(image)
+	if err != nil {
+		return imageConfiguration{}, errors.Wrapf(err, "retrieving image configuration for %s", image)
+	}
+
+	env := map[string]string{}
+	for _, kv := range config.Config.Env {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	return imageConfiguration{
+		env:        env,
+		entrypoint: config.Config.Entrypoint,
+		arguments:  config.Config.Cmd,
+		workingDir: config.Config.WorkingDir,
+	}, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const deploymentManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: gcr.io/k8s-skaffold/web:abc
+      - name: proxy
+        image: nginx`
+
+const serviceManifest = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+  - port: 80`
+
+func TestApplyDebuggingTransforms(t *testing.T) {
+	defer func(r func(string) (imageConfiguration, error)) { retrieveImageConfiguration = r }(retrieveImageConfiguration)
+	retrieveImageConfiguration = func(image string) (imageConfiguration, error) {
+		return imageConfiguration{
+			env:        map[string]string{"NODE_VERSION": "10"},
+			entrypoint: []string{"node", "server.js"},
+		}, nil
+	}
+
+	var out bytes.Buffer
+	manifests, err := ApplyDebuggingTransforms(&out, kubectl.ManifestList{[]byte(deploymentManifest), []byte(serviceManifest)}, []build.Artifact{{
+		ImageName: "gcr.io/k8s-skaffold/web",
+		Tag:       "gcr.io/k8s-skaffold/web:abc",
+	}})
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, 2, len(manifests))
+	testutil.CheckDeepEqual(t, serviceManifest, string(manifests[1]))
+
+	deployment := string(manifests[0])
+	for _, expected := range []string{
+		`debug.skaffold.dev/config: '{"web":{"runtime":"nodejs","ports":{"devtools":9229}}}'`,
+		"apiVersion: apps/v1",
+		"kind: Deployment",
+		"- --inspect=0.0.0.0:9229",
+		"containerPort: 9229",
+		"name: devtools",
+	} {
+		if !strings.Contains(deployment, expected) {
+			t.Errorf("expected transformed manifest to contain %q, got:\n%s", expected, deployment)
+		}
+	}
+	if strings.Count(deployment, "containerPort") != 1 {
+		t.Errorf("only the built image should be transformed, got:\n%s", deployment)
+	}
+
+	testutil.CheckDeepEqual(t, "Debugging deployment/web container web (nodejs): devtools=9229\n", out.String())
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"path/filepath"
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+// ContainerDebugConfiguration describes how a container was configured for debugging.
+type ContainerDebugConfiguration struct {
+	// Runtime is the detected language runtime, like `jvm` or `nodejs`.
+	Runtime string `json:"runtime,omitempty"`
+
+	// Ports maps the name of the debug ports to the container ports.
+	Ports map[string]int32 `json:"ports,omitempty"`
+}
+
+// imageConfiguration is the part of an image's configuration used to detect its runtime.
+type imageConfiguration struct {
+	env        map[string]string
+	entrypoint []string
+	arguments  []string
+	workingDir string
+}
+
+// containerTransformer configures a container for a given language runtime.
+type containerTransformer interface {
+	// IsApplicable says if the container runs this transformer's runtime.
+	IsApplicable(config imageConfiguration) bool
+
+	// Apply configures the container, and returns nil if it couldn't.
+	Apply(container *v1.Container, config imageConfiguration, portAlloc func(int32) int32) *ContainerDebugConfiguration
+}
+
+var containerTransforms = []containerTransformer{
+	jdwpTransformer{},
+	nodeTransformer{},
+	pythonTransformer{},
+	dlvTransformer{},
+}
+
+// transformContainer configures a container with the first applicable transformer
+// that succeeds.
+func transformContainer(container *v1.Container, config imageConfiguration, ports map[int32]bool) *ContainerDebugConfiguration {
+	// The container's env and command override the image's configuration.
+	config = containerConfiguration(container, config)
+
+	portAlloc := func(desired int32) int32 {
+		port := desired
+		for ports[port] {
+			port++
+		}
+		ports[port] = true
+		return port
+	}
+
+	for _, transform := range containerTransforms {
+		if !transform.IsApplicable(config) {
+			continue
+		}
+		if debug := transform.Apply(container, config, portAlloc); debug != nil {
+			return debug
+		}
+	}
+
+	return nil
+}
+
+func containerConfiguration(container *v1.Container, config imageConfiguration) imageConfiguration {
+	env := map[string]string{}
+	for k, v := range config.env {
+		env[k] = v
+	}
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+
+	result := imageConfiguration{
+		env:        env,
+		entrypoint: config.entrypoint,
+		arguments:  config.arguments,
+		workingDir: config.workingDir,
+	}
+	if len(container.Command) > 0 {
+		result.entrypoint = container.Command
+		result.arguments = nil
+	}
+	if len(container.Args) > 0 {
+		result.arguments = container.Args
+	}
+	if container.WorkingDir != "" {
+		result.workingDir = container.WorkingDir
+	}
+
+	return result
+}
+
+// commandLine returns the full command run by a container.
+func (c imageConfiguration) commandLine() []string {
+	var commandLine []string
+	commandLine = append(commandLine, c.entrypoint...)
+	commandLine = append(commandLine, c.arguments...)
+	return commandLine
+}
+
+// runs checks if the command line starts with one of the given executables.
+func (c imageConfiguration) runs(executables ...string) bool {
+	commandLine := c.commandLine()
+	if len(commandLine) == 0 {
+		return false
+	}
+
+	base := filepath.Base(commandLine[0])
+	for _, executable := range executables {
+		if base == executable || strings.HasPrefix(base, executable) && isVersionSuffix(strings.TrimPrefix(base, executable)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVersionSuffix checks for suffixes like `3` or `2.7` in `python3` or `python2.7`.
+func isVersionSuffix(suffix string) bool {
+	return strings.Trim(suffix, "0123456789.") == ""
+}
+
+// setCommandLine replaces the command run by a container.
+func setCommandLine(container *v1.Container, commandLine []string) {
+	container.Command = commandLine
+	container.Args = nil
+}
+
+// setEnv sets an environment variable on a container.
+func setEnv(container *v1.Container, name, value string) {
+	for i := range container.Env {
+		if container.Env[i].Name == name {
+			container.Env[i].Value = value
+			container.Env[i].ValueFrom = nil
+			return
+		}
+	}
+	container.Env = append(container.Env, v1.EnvVar{Name: name, Value: value})
+}
+
+// exposePort adds a named container port.
+func exposePort(container *v1.Container, name string, port int32) {
+	container.Ports = append(container.Ports, v1.ContainerPort{
+		Name:          name,
+		ContainerPort: port,
+		Protocol:      v1.ProtocolTCP,
+	})
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// dlvTransformer configures Go containers with the Delve debugger. The image
+// must contain the `dlv` binary.
+type dlvTransformer struct{}
+
+const defaultDlvPort = 56268
+
+func (dlvTransformer) IsApplicable(config imageConfiguration) bool {
+	for _, name := range []string{"GOLANG_VERSION", "GOROOT"} {
+		if _, found := config.env[name]; found {
+			return true
+		}
+	}
+	return config.runs("dlv")
+}
+
+// Apply runs the binary through a headless `dlv exec`.
+func (dlvTransformer) Apply(container *v1.Container, config imageConfiguration, portAlloc func(int32) int32) *ContainerDebugConfiguration {
+	commandLine := config.commandLine()
+	if len(commandLine) == 0 || config.runs("dlv") {
+		return nil
+	}
+
+	port := portAlloc(defaultDlvPort)
+	dlv := []string{"dlv", "exec", "--headless", "--continue", "--accept-multiclient", fmt.Sprintf("--listen=:%d", port), "--api-version=2", commandLine[0]}
+	if len(commandLine) > 1 {
+		dlv = append(append(dlv, "--"), commandLine[1:]...)
+	}
+	setCommandLine(container, dlv)
+	exposePort(container, "dlv", port)
+
+	return &ContainerDebugConfiguration{
+		Runtime: "go",
+		Ports:   map[string]int32{"dlv": port},
+	}
+}
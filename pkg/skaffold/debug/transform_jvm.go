@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+// jdwpTransformer configures JVM containers with the JDWP agent.
+type jdwpTransformer struct{}
+
+const defaultJdwpPort = 5005
+
+func (jdwpTransformer) IsApplicable(config imageConfiguration) bool {
+	for _, name := range []string{"JAVA_TOOL_OPTIONS", "JAVA_VERSION", "JAVA_HOME"} {
+		if _, found := config.env[name]; found {
+			return true
+		}
+	}
+	return config.runs("java")
+}
+
+// Apply adds the JDWP agent through JAVA_TOOL_OPTIONS, which is picked up by any JVM.
+func (jdwpTransformer) Apply(container *v1.Container, config imageConfiguration, portAlloc func(int32) int32) *ContainerDebugConfiguration {
+	toolOptions := config.env["JAVA_TOOL_OPTIONS"]
+	if strings.Contains(toolOptions, "-agentlib:jdwp") {
+		return nil
+	}
+
+	port := portAlloc(defaultJdwpPort)
+	agent := fmt.Sprintf("-agentlib:jdwp=transport=dt_socket,server=y,suspend=n,address=%d", port)
+	setEnv(container, "JAVA_TOOL_OPTIONS", strings.TrimSpace(toolOptions+" "+agent))
+	exposePort(container, "jdwp", port)
+
+	return &ContainerDebugConfiguration{
+		Runtime: "jvm",
+		Ports:   map[string]int32{"jdwp": port},
+	}
+}
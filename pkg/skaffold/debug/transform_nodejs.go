@@ -0,0 +1,58 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/api/core/v1"
+)
+
+// nodeTransformer configures NodeJS containers with the inspector.
+type nodeTransformer struct{}
+
+const defaultDevtoolsPort = 9229
+
+func (nodeTransformer) IsApplicable(config imageConfiguration) bool {
+	for _, name := range []string{"NODE_VERSION", "NODEJS_VERSION", "NODE_ENV"} {
+		if _, found := config.env[name]; found {
+			return true
+		}
+	}
+	return config.runs("node", "nodejs", "npm")
+}
+
+// Apply adds `--inspect` to the node command line. When node is started by
+// another command, like `npm start`, the flag is passed through NODE_OPTIONS.
+func (nodeTransformer) Apply(container *v1.Container, config imageConfiguration, portAlloc func(int32) int32) *ContainerDebugConfiguration {
+	port := portAlloc(defaultDevtoolsPort)
+	inspect := fmt.Sprintf("--inspect=0.0.0.0:%d", port)
+
+	if config.runs("node", "nodejs") {
+		commandLine := config.commandLine()
+		setCommandLine(container, append([]string{commandLine[0], inspect}, commandLine[1:]...))
+	} else {
+		setEnv(container, "NODE_OPTIONS", strings.TrimSpace(config.env["NODE_OPTIONS"]+" "+inspect))
+	}
+	exposePort(container, "devtools", port)
+
+	return &ContainerDebugConfiguration{
+		Runtime: "nodejs",
+		Ports:   map[string]int32{"devtools": port},
+	}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"fmt"
+
+	"k8s.io/api/core/v1"
+)
+
+// pythonTransformer configures Python containers with ptvsd. The image
+// must have the ptvsd module installed.
+type pythonTransformer struct{}
+
+const defaultPtvsdPort = 5678
+
+func (pythonTransformer) IsApplicable(config imageConfiguration) bool {
+	if _, found := config.env["PYTHON_VERSION"]; found {
+		return true
+	}
+	return config.runs("python")
+}
+
+// Apply runs the script through the ptvsd module. Only containers
+// that run python directly can be configured.
+func (pythonTransformer) Apply(container *v1.Container, config imageConfiguration, portAlloc func(int32) int32) *ContainerDebugConfiguration {
+	if !config.runs("python") {
+		return nil
+	}
+
+	port := portAlloc(defaultPtvsdPort)
+	commandLine := config.commandLine()
+	ptvsd := []string{commandLine[0], "-m", "ptvsd", "--host", "0.0.0.0", "--port", fmt.Sprint(port)}
+	setCommandLine(container, append(ptvsd, commandLine[1:]...))
+	exposePort(container, "dap", port)
+
+	return &ContainerDebugConfiguration{
+		Runtime: "python",
+		Ports:   map[string]int32{"dap": port},
+	}
+}
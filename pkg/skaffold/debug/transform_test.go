@@ -0,0 +1,133 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package debug
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/api/core/v1"
+)
+
+func TestTransformContainer(t *testing.T) {
+	var tests = []struct {
+		description   string
+		container     v1.Container
+		config        imageConfiguration
+		usedPorts     []int32
+		expected      v1.Container
+		expectedDebug *ContainerDebugConfiguration
+	}{
+		{
+			description: "unknown runtime",
+			container:   v1.Container{Name: "app"},
+			config:      imageConfiguration{entrypoint: []string{"/app"}},
+			expected:    v1.Container{Name: "app"},
+		},
+		{
+			description: "jvm from env",
+			container:   v1.Container{},
+			config:      imageConfiguration{env: map[string]string{"JAVA_VERSION": "8"}, entrypoint: []string{"java", "-jar", "app.jar"}},
+			expected: v1.Container{
+				Env:   []v1.EnvVar{{Name: "JAVA_TOOL_OPTIONS", Value: "-agentlib:jdwp=transport=dt_socket,server=y,suspend=n,address=5005"}},
+				Ports: []v1.ContainerPort{{Name: "jdwp", ContainerPort: 5005, Protocol: v1.ProtocolTCP}},
+			},
+			expectedDebug: &ContainerDebugConfiguration{Runtime: "jvm", Ports: map[string]int32{"jdwp": 5005}},
+		},
+		{
+			description: "jvm keeps existing tool options",
+			container:   v1.Container{Env: []v1.EnvVar{{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g"}}},
+			config:      imageConfiguration{entrypoint: []string{"java"}},
+			usedPorts:   []int32{5005},
+			expected: v1.Container{
+				Env:   []v1.EnvVar{{Name: "JAVA_TOOL_OPTIONS", Value: "-Xmx1g -agentlib:jdwp=transport=dt_socket,server=y,suspend=n,address=5006"}},
+				Ports: []v1.ContainerPort{{Name: "jdwp", ContainerPort: 5006, Protocol: v1.ProtocolTCP}},
+			},
+			expectedDebug: &ContainerDebugConfiguration{Runtime: "jvm", Ports: map[string]int32{"jdwp": 5006}},
+		},
+		{
+			description: "node command",
+			container:   v1.Container{Args: []string{"server.js"}},
+			config:      imageConfiguration{entrypoint: []string{"node"}, arguments: []string{"index.js"}},
+			expected: v1.Container{
+				Command: []string{"node", "--inspect=0.0.0.0:9229", "server.js"},
+				Ports:   []v1.ContainerPort{{Name: "devtools", ContainerPort: 9229, Protocol: v1.ProtocolTCP}},
+			},
+			expectedDebug: &ContainerDebugConfiguration{Runtime: "nodejs", Ports: map[string]int32{"devtools": 9229}},
+		},
+		{
+			description: "npm start",
+			container:   v1.Container{},
+			config:      imageConfiguration{env: map[string]string{"NODE_VERSION": "10"}, arguments: []string{"npm", "start"}},
+			expected: v1.Container{
+				Env:   []v1.EnvVar{{Name: "NODE_OPTIONS", Value: "--inspect=0.0.0.0:9229"}},
+				Ports: []v1.ContainerPort{{Name: "devtools", ContainerPort: 9229, Protocol: v1.ProtocolTCP}},
+			},
+			expectedDebug: &ContainerDebugConfiguration{Runtime: "nodejs", Ports: map[string]int32{"devtools": 9229}},
+		},
+		{
+			description: "python3",
+			container:   v1.Container{Command: []string{"python3", "app.py"}},
+			config:      imageConfiguration{},
+			expected: v1.Container{
+				Command: []string{"python3", "-m", "ptvsd", "--host", "0.0.0.0", "--port", "5678", "app.py"},
+				Ports:   []v1.ContainerPort{{Name: "dap", ContainerPort: 5678, Protocol: v1.ProtocolTCP}},
+			},
+			expectedDebug: &ContainerDebugConfiguration{Runtime: "python", Ports: map[string]int32{"dap": 5678}},
+		},
+		{
+			description: "python not run directly",
+			container:   v1.Container{},
+			config:      imageConfiguration{env: map[string]string{"PYTHON_VERSION": "3.7"}, entrypoint: []string{"gunicorn"}},
+			expected:    v1.Container{},
+		},
+		{
+			description: "python not run directly falls through to go",
+			container:   v1.Container{},
+			config:      imageConfiguration{env: map[string]string{"PYTHON_VERSION": "3.7", "GOLANG_VERSION": "1.11"}, entrypoint: []string{"/app"}},
+			expected: v1.Container{
+				Command: []string{"dlv", "exec", "--headless", "--continue", "--accept-multiclient", "--listen=:56268", "--api-version=2", "/app"},
+				Ports:   []v1.ContainerPort{{Name: "dlv", ContainerPort: 56268, Protocol: v1.ProtocolTCP}},
+			},
+			expectedDebug: &ContainerDebugConfiguration{Runtime: "go", Ports: map[string]int32{"dlv": 56268}},
+		},
+		{
+			description: "go",
+			container:   v1.Container{},
+			config:      imageConfiguration{env: map[string]string{"GOLANG_VERSION": "1.11"}, entrypoint: []string{"/app"}, arguments: []string{"--flag"}},
+			expected: v1.Container{
+				Command: []string{"dlv", "exec", "--headless", "--continue", "--accept-multiclient", "--listen=:56268", "--api-version=2", "/app", "--", "--flag"},
+				Ports:   []v1.ContainerPort{{Name: "dlv", ContainerPort: 56268, Protocol: v1.ProtocolTCP}},
+			},
+			expectedDebug: &ContainerDebugConfiguration{Runtime: "go", Ports: map[string]int32{"dlv": 56268}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ports := map[int32]bool{}
+			for _, port := range test.usedPorts {
+				ports[port] = true
+			}
+
+			debug := transformContainer(&test.container, test.config, ports)
+
+			testutil.CheckDeepEqual(t, test.expectedDebug, debug)
+			testutil.CheckDeepEqual(t, test.expected, test.container)
+		})
+	}
+}
@@ -34,18 +34,33 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// ManifestTransform transforms the manifests, after the images were replaced, before they are applied.
+type ManifestTransform func(l kubectl.ManifestList, builds []build.Artifact) (kubectl.ManifestList, error)
+
+func applyManifestTransforms(l kubectl.ManifestList, builds []build.Artifact, transforms []ManifestTransform) (kubectl.ManifestList, error) {
+	var err error
+	for _, transform := range transforms {
+		l, err = transform(l, builds)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to transform manifests")
+		}
+	}
+	return l, nil
+}
+
 // KubectlDeployer deploys workflows using kubectl CLI.
 type KubectlDeployer struct {
 	*latest.KubectlDeploy
 
-	workingDir  string
-	kubectl     kubectl.CLI
-	defaultRepo string
+	workingDir         string
+	kubectl            kubectl.CLI
+	defaultRepo        string
+	manifestTransforms []ManifestTransform
 }
 
 // NewKubectlDeployer returns a new KubectlDeployer for a DeployConfig filled
 // with the needed configuration for `kubectl apply`
-func NewKubectlDeployer(workingDir string, cfg *latest.KubectlDeploy, kubeContext string, namespace string, defaultRepo string) *KubectlDeployer {
+func NewKubectlDeployer(workingDir string, cfg *latest.KubectlDeploy, kubeContext string, namespace string, defaultRepo string, manifestTransforms ...ManifestTransform) *KubectlDeployer {
 	return &KubectlDeployer{
 		KubectlDeploy: cfg,
 		workingDir:    workingDir,
@@ -54,7 +69,8 @@ func NewKubectlDeployer(workingDir string, cfg *latest.KubectlDeploy, kubeContex
 			KubeContext: kubeContext,
 			Flags:       cfg.Flags,
 		},
-		defaultRepo: defaultRepo,
+		defaultRepo:        defaultRepo,
+		manifestTransforms: manifestTransforms,
 	}
 }
 
@@ -86,6 +102,11 @@ func (k *KubectlDeployer) Deploy(ctx context.Context, out io.Writer, builds []bu
 		return nil, errors.Wrap(err, "replacing images in manifests")
 	}
 
+	manifests, err = applyManifestTransforms(manifests, builds, k.manifestTransforms)
+	if err != nil {
+		return nil, err
+	}
+
 	updated, err := k.kubectl.Apply(ctx, out, manifests)
 	if err != nil {
 		return nil, errors.Wrap(err, "apply")
@@ -55,11 +55,12 @@ type configMapGenerator struct {
 type KustomizeDeployer struct {
 	*latest.KustomizeDeploy
 
-	kubectl     kubectl.CLI
-	defaultRepo string
+	kubectl            kubectl.CLI
+	defaultRepo        string
+	manifestTransforms []ManifestTransform
 }
 
-func NewKustomizeDeployer(cfg *latest.KustomizeDeploy, kubeContext string, namespace string, defaultRepo string) *KustomizeDeployer {
+func NewKustomizeDeployer(cfg *latest.KustomizeDeploy, kubeContext string, namespace string, defaultRepo string, manifestTransforms ...ManifestTransform) *KustomizeDeployer {
 	return &KustomizeDeployer{
 		KustomizeDeploy: cfg,
 		kubectl: kubectl.CLI{
@@ -67,7 +68,8 @@ func NewKustomizeDeployer(cfg *latest.KustomizeDeploy, kubeContext string, names
 			KubeContext: kubeContext,
 			Flags:       cfg.Flags,
 		},
-		defaultRepo: defaultRepo,
+		defaultRepo:        defaultRepo,
+		manifestTransforms: manifestTransforms,
 	}
 }
 
@@ -94,6 +96,11 @@ func (k *KustomizeDeployer) Deploy(ctx context.Context, out io.Writer, builds []
 		return nil, errors.Wrap(err, "replacing images in manifests")
 	}
 
+	manifests, err = applyManifestTransforms(manifests, builds, k.manifestTransforms)
+	if err != nil {
+		return nil, err
+	}
+
 	updated, err := k.kubectl.Apply(ctx, out, manifests)
 	if err != nil {
 		return nil, errors.Wrap(err, "apply")
@@ -0,0 +1,280 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes"
+	kubectx "github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/context"
+	"github.com/docker/distribution/reference"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Overridden for unit testing.
+var (
+	lookPath       = exec.LookPath
+	kubectlVersion = func() kubectl.ClientVersion { return (&kubectl.CLI{}).Version() }
+	currentContext = kubectx.CurrentContext
+	getClientset   = kubernetes.GetClientset
+	dockerVersion  = func(ctx context.Context) (string, error) {
+		client, err := docker.NewAPIClient()
+		if err != nil {
+			return "", err
+		}
+		version, err := client.ServerVersion(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s (API %s)", version.Version, version.APIVersion), nil
+	}
+	getAuthConfig = func(registry string) (bool, error) {
+		auth, err := docker.DefaultAuthHelper.GetAuthConfig(registry)
+		if err != nil {
+			return false, err
+		}
+		return auth.Username != "" || auth.Auth != "" || auth.IdentityToken != "" || auth.RegistryToken != "", nil
+	}
+)
+
+// clusterTimeout is how long to wait for the cluster to answer.
+const clusterTimeout = 10 * time.Second
+
+// DefaultChecks lists the checks run by `skaffold diagnose`.
+func DefaultChecks() []Check {
+	return []Check{
+		&kubectlCheck{},
+		&clusterCheck{},
+		&dockerCheck{},
+		&registryCheck{},
+		&binaryCheck{
+			binary:  "helm",
+			hint:    "install helm, see https://docs.helm.sh/using_helm/#installing-helm",
+			applies: func(env *Environment) bool { return env.Pipeline.Deploy.HelmDeploy != nil },
+		},
+		&binaryCheck{
+			binary:  "kustomize",
+			hint:    "install kustomize, see https://github.com/kubernetes-sigs/kustomize/blob/master/docs/INSTALL.md",
+			applies: func(env *Environment) bool { return env.Pipeline.Deploy.KustomizeDeploy != nil },
+		},
+		&binaryCheck{
+			binary:  "container-structure-test",
+			hint:    "install container-structure-test, see https://github.com/GoogleContainerTools/container-structure-test#installation",
+			applies: hasStructureTests,
+		},
+		&kanikoSecretCheck{},
+	}
+}
+
+// kubectlCheck checks that a recent enough kubectl is installed.
+type kubectlCheck struct{}
+
+func (c *kubectlCheck) Name() string { return "kubectl" }
+
+func (c *kubectlCheck) Applies(*Environment) bool { return true }
+
+func (c *kubectlCheck) Run(context.Context, *Environment) Result {
+	if _, err := lookPath("kubectl"); err != nil {
+		return fail("install kubectl, see https://kubernetes.io/docs/tasks/tools/install-kubectl/", "kubectl not found")
+	}
+
+	version := kubectlVersion()
+	minor, err := strconv.Atoi(version.Minor)
+	if err != nil {
+		return warn("check that `kubectl version --client` works", "unable to get the version of kubectl")
+	}
+	if minor < 12 {
+		return warn("upgrade kubectl", "kubectl %s is installed, version 1.12.0 or greater is recommended", version)
+	}
+
+	return pass("kubectl %s", version)
+}
+
+// clusterCheck checks that the cluster of the current context can be reached.
+type clusterCheck struct{}
+
+func (c *clusterCheck) Name() string { return "cluster" }
+
+func (c *clusterCheck) Applies(*Environment) bool { return true }
+
+func (c *clusterCheck) Run(ctx context.Context, _ *Environment) Result {
+	kubeContext, err := currentContext()
+	if err != nil || kubeContext == "" {
+		return fail("choose a context with `kubectl config use-context`", "no current kubernetes context")
+	}
+
+	client, err := getClientset()
+	if err != nil {
+		return fail("check your kubeconfig", "unable to create a client for context %s: %s", kubeContext, err)
+	}
+
+	type serverVersion struct {
+		version string
+		err     error
+	}
+	result := make(chan serverVersion, 1)
+	go func() {
+		version, err := client.Discovery().ServerVersion()
+		if err != nil {
+			result <- serverVersion{err: err}
+			return
+		}
+		result <- serverVersion{version: version.GitVersion}
+	}()
+
+	hint := fmt.Sprintf("check that the cluster of context %s is running and that `kubectl cluster-info` succeeds", kubeContext)
+	select {
+	case v := <-result:
+		if v.err != nil {
+			return fail(hint, "cluster of context %s is unreachable: %s", kubeContext, v.err)
+		}
+		return pass("context %s, kubernetes %s", kubeContext, v.version)
+	case <-time.After(clusterTimeout):
+		return fail(hint, "cluster of context %s didn't answer in %v", kubeContext, clusterTimeout)
+	case <-ctx.Done():
+		return fail(hint, "cluster of context %s didn't answer: %s", kubeContext, ctx.Err())
+	}
+}
+
+// dockerCheck checks that the Docker daemon can be reached by local builds.
+type dockerCheck struct{}
+
+func (c *dockerCheck) Name() string { return "docker" }
+
+func (c *dockerCheck) Applies(env *Environment) bool {
+	return env.Pipeline.Build.LocalBuild != nil && len(env.Pipeline.Build.Artifacts) > 0
+}
+
+func (c *dockerCheck) Run(ctx context.Context, _ *Environment) Result {
+	version, err := dockerVersion(ctx)
+	if err != nil {
+		return fail("check that the Docker daemon is running, that DOCKER_HOST is correct and that the current user can access it", "unable to reach the Docker daemon: %s", err)
+	}
+
+	return pass("Docker %s", version)
+}
+
+// registryCheck checks that there are credentials for the registry of the default repo.
+type registryCheck struct{}
+
+func (c *registryCheck) Name() string { return "registry" }
+
+func (c *registryCheck) Applies(env *Environment) bool { return env.DefaultRepo != "" }
+
+func (c *registryCheck) Run(_ context.Context, env *Environment) Result {
+	named, err := reference.ParseNormalizedNamed(env.DefaultRepo)
+	if err != nil {
+		return fail("fix the default repo with --default-repo or `skaffold config set default-repo`", "invalid default repo %s: %s", env.DefaultRepo, err)
+	}
+	registry := reference.Domain(named)
+
+	found, err := getAuthConfig(registry)
+	if err != nil {
+		return fail(loginHint(registry), "unable to get the credentials for %s: %s", registry, err)
+	}
+	if !found {
+		return warn(loginHint(registry), "no credentials found for %s", registry)
+	}
+
+	return pass("credentials found for %s", registry)
+}
+
+func loginHint(registry string) string {
+	if registry == "gcr.io" || strings.HasSuffix(registry, ".gcr.io") {
+		return "run `gcloud auth configure-docker`"
+	}
+	return fmt.Sprintf("run `docker login %s`", registry)
+}
+
+// binaryCheck checks that a binary used by the pipeline is installed.
+type binaryCheck struct {
+	binary  string
+	hint    string
+	applies func(env *Environment) bool
+}
+
+func (c *binaryCheck) Name() string { return c.binary }
+
+func (c *binaryCheck) Applies(env *Environment) bool { return c.applies(env) }
+
+func (c *binaryCheck) Run(context.Context, *Environment) Result {
+	path, err := lookPath(c.binary)
+	if err != nil {
+		return fail(c.hint, "%s not found", c.binary)
+	}
+
+	return pass("%s found at %s", c.binary, path)
+}
+
+func hasStructureTests(env *Environment) bool {
+	for _, test := range env.Pipeline.Test {
+		if len(test.StructureTests) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// kanikoSecretCheck checks the pull secret used by Kaniko builds. Either
+// the secret exists in the cluster or it's created from a local file.
+type kanikoSecretCheck struct{}
+
+func (c *kanikoSecretCheck) Name() string { return "kaniko secret" }
+
+func (c *kanikoSecretCheck) Applies(env *Environment) bool {
+	return env.Pipeline.Build.KanikoBuild != nil
+}
+
+func (c *kanikoSecretCheck) Run(_ context.Context, env *Environment) Result {
+	kaniko := env.Pipeline.Build.KanikoBuild
+
+	client, err := getClientset()
+	if err != nil {
+		return fail("check your kubeconfig", "unable to create a kubernetes client: %s", err)
+	}
+	_, err = client.CoreV1().Secrets(kaniko.Namespace).Get(kaniko.PullSecretName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fail("check that the cluster is reachable", "unable to look for secret %s in namespace %s: %s", kaniko.PullSecretName, kaniko.Namespace, err)
+	}
+	exists := err == nil
+
+	if kaniko.PullSecret != "" {
+		if _, err := os.Stat(kaniko.PullSecret); err != nil {
+			return fail("set pullSecret to the path of a service account key", "pull secret file %s can't be read: %s", kaniko.PullSecret, err)
+		}
+		if exists {
+			return fail(fmt.Sprintf("delete it with `kubectl delete secret %s -n %s`", kaniko.PullSecretName, kaniko.Namespace),
+				"secret %s already exists in namespace %s, it can't be created from %s", kaniko.PullSecretName, kaniko.Namespace, kaniko.PullSecret)
+		}
+		return pass("secret %s will be created from %s", kaniko.PullSecretName, kaniko.PullSecret)
+	}
+
+	if !exists {
+		return fail(fmt.Sprintf("create it with `kubectl create secret generic %s --from-file=kaniko-secret=<service account key>.json -n %s`", kaniko.PullSecretName, kaniko.Namespace),
+			"secret %s not found in namespace %s", kaniko.PullSecretName, kaniko.Namespace)
+	}
+	return pass("secret %s found in namespace %s", kaniko.PullSecretName, kaniko.Namespace)
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnose
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubectlCheck(t *testing.T) {
+	var tests = []struct {
+		description string
+		installed   bool
+		minor       string
+		expected    Status
+	}{
+		{description: "recent kubectl", installed: true, minor: "13", expected: Pass},
+		{description: "old kubectl", installed: true, minor: "10", expected: Warn},
+		{description: "unknown version", installed: true, minor: "unknown", expected: Warn},
+		{description: "not installed", expected: Fail},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer restore()
+			lookPath = fakeLookPath(test.installed)
+			kubectlVersion = func() kubectl.ClientVersion { return kubectl.ClientVersion{Major: "1", Minor: test.minor} }
+
+			result := (&kubectlCheck{}).Run(context.Background(), nil)
+
+			testutil.CheckDeepEqual(t, test.expected, result.Status)
+		})
+	}
+}
+
+func TestClusterCheck(t *testing.T) {
+	defer restore()
+	currentContext = func() (string, error) { return "", nil }
+
+	result := (&clusterCheck{}).Run(context.Background(), nil)
+	testutil.CheckDeepEqual(t, Fail, result.Status)
+
+	currentContext = func() (string, error) { return "minikube", nil }
+	getClientset = func() (kubernetes.Interface, error) { return fake.NewSimpleClientset(), nil }
+
+	result = (&clusterCheck{}).Run(context.Background(), nil)
+	testutil.CheckDeepEqual(t, Pass, result.Status)
+}
+
+func TestDockerCheck(t *testing.T) {
+	defer restore()
+	env := &Environment{Pipeline: &latest.SkaffoldPipeline{}}
+	testutil.CheckDeepEqual(t, false, (&dockerCheck{}).Applies(env))
+
+	env.Pipeline.Build.LocalBuild = &latest.LocalBuild{}
+	env.Pipeline.Build.Artifacts = []*latest.Artifact{{ImageName: "image"}}
+	testutil.CheckDeepEqual(t, true, (&dockerCheck{}).Applies(env))
+
+	dockerVersion = func(context.Context) (string, error) { return "18.09.0 (API 1.39)", nil }
+	result := (&dockerCheck{}).Run(context.Background(), env)
+	testutil.CheckDeepEqual(t, Result{Status: Pass, Message: "Docker 18.09.0 (API 1.39)"}, result)
+
+	dockerVersion = func(context.Context) (string, error) { return "", errors.New("connection refused") }
+	result = (&dockerCheck{}).Run(context.Background(), env)
+	testutil.CheckDeepEqual(t, Fail, result.Status)
+}
+
+func TestRegistryCheck(t *testing.T) {
+	var tests = []struct {
+		description  string
+		defaultRepo  string
+		found        bool
+		err          error
+		expected     Status
+		expectedHint string
+	}{
+		{description: "credentials found", defaultRepo: "gcr.io/project", found: true, expected: Pass},
+		{description: "no credentials for gcr", defaultRepo: "eu.gcr.io/project", expected: Warn, expectedHint: "run `gcloud auth configure-docker`"},
+		{description: "no credentials", defaultRepo: "registry.example.com:5000/team", expected: Warn, expectedHint: "run `docker login registry.example.com:5000`"},
+		{description: "helper error", defaultRepo: "docker.io/user", err: errors.New("helper failed"), expected: Fail, expectedHint: "run `docker login docker.io`"},
+		{description: "invalid repo", defaultRepo: "UPPER/case", expected: Fail, expectedHint: "fix the default repo with --default-repo or `skaffold config set default-repo`"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer restore()
+			getAuthConfig = func(string) (bool, error) { return test.found, test.err }
+
+			result := (&registryCheck{}).Run(context.Background(), &Environment{DefaultRepo: test.defaultRepo})
+
+			testutil.CheckDeepEqual(t, test.expected, result.Status)
+			testutil.CheckDeepEqual(t, test.expectedHint, result.Hint)
+		})
+	}
+}
+
+func TestBinaryChecks(t *testing.T) {
+	defer restore()
+	lookPath = fakeLookPath(false)
+
+	env := &Environment{Pipeline: &latest.SkaffoldPipeline{
+		Deploy: latest.DeployConfig{DeployType: latest.DeployType{HelmDeploy: &latest.HelmDeploy{}}},
+		Test:   []*latest.TestCase{{ImageName: "image", StructureTests: []string{"test.yaml"}}},
+	}}
+	var failed []string
+	for _, check := range DefaultChecks() {
+		if _, ok := check.(*binaryCheck); ok && check.Applies(env) {
+			result := check.Run(context.Background(), env)
+			testutil.CheckDeepEqual(t, Fail, result.Status)
+			failed = append(failed, check.Name())
+		}
+	}
+
+	testutil.CheckDeepEqual(t, []string{"helm", "container-structure-test"}, failed)
+}
+
+func TestKanikoSecretCheck(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmpDir.Write("key.json", "{}")
+
+	var tests = []struct {
+		description string
+		pullSecret  string
+		existing    bool
+		expected    Status
+	}{
+		{description: "existing secret", existing: true, expected: Pass},
+		{description: "missing secret", expected: Fail},
+		{description: "secret created from file", pullSecret: tmpDir.Path("key.json"), expected: Pass},
+		{description: "missing file", pullSecret: tmpDir.Path("missing.json"), expected: Fail},
+		{description: "secret already exists", pullSecret: tmpDir.Path("key.json"), existing: true, expected: Fail},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			defer restore()
+			client := fake.NewSimpleClientset()
+			if test.existing {
+				client = fake.NewSimpleClientset(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "kaniko-secret", Namespace: "default"}})
+			}
+			getClientset = func() (kubernetes.Interface, error) { return client, nil }
+
+			env := &Environment{Pipeline: &latest.SkaffoldPipeline{}}
+			env.Pipeline.Build.KanikoBuild = &latest.KanikoBuild{
+				PullSecret:     test.pullSecret,
+				PullSecretName: "kaniko-secret",
+				Namespace:      "default",
+			}
+
+			result := (&kanikoSecretCheck{}).Run(context.Background(), env)
+
+			testutil.CheckDeepEqual(t, test.expected, result.Status)
+		})
+	}
+}
+
+func fakeLookPath(installed bool) func(string) (string, error) {
+	return func(file string) (string, error) {
+		if !installed {
+			return "", errors.New("not found")
+		}
+		return "/usr/bin/" + file, nil
+	}
+}
+
+func restore() {
+	lookPath = defaults.lookPath
+	kubectlVersion = defaults.kubectlVersion
+	currentContext = defaults.currentContext
+	getClientset = defaults.getClientset
+	dockerVersion = defaults.dockerVersion
+	getAuthConfig = defaults.getAuthConfig
+}
+
+var defaults = struct {
+	lookPath       func(string) (string, error)
+	kubectlVersion func() kubectl.ClientVersion
+	currentContext func() (string, error)
+	getClientset   func() (kubernetes.Interface, error)
+	dockerVersion  func(context.Context) (string, error)
+	getAuthConfig  func(string) (bool, error)
+}{lookPath, kubectlVersion, currentContext, getClientset, dockerVersion, getAuthConfig}
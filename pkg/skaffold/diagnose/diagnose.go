@@ -0,0 +1,99 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnose
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// Pass means that the environment is ready.
+	Pass Status = "pass"
+	// Warn means that skaffold might not work as expected.
+	Warn Status = "warn"
+	// Fail means that skaffold will fail.
+	Fail Status = "fail"
+)
+
+// Result is the result of a check. A Hint tells how to fix warnings and failures.
+type Result struct {
+	Check   string `json:"check"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// Environment is what the checks diagnose.
+type Environment struct {
+	Pipeline    *latest.SkaffoldPipeline
+	DefaultRepo string
+}
+
+// Check diagnoses one aspect of the environment.
+type Check interface {
+	// Name identifies the check in the results.
+	Name() string
+
+	// Applies is false if the pipeline doesn't need what the check diagnoses.
+	Applies(env *Environment) bool
+
+	// Run runs the check.
+	Run(ctx context.Context, env *Environment) Result
+}
+
+// Run runs the checks that apply to the environment, in order.
+func Run(ctx context.Context, env *Environment, checks []Check) []Result {
+	var results []Result
+	for _, check := range checks {
+		if !check.Applies(env) {
+			continue
+		}
+
+		result := check.Run(ctx, env)
+		result.Check = check.Name()
+		results = append(results, result)
+	}
+	return results
+}
+
+// Failed returns true if one of the checks has failed.
+func Failed(results []Result) bool {
+	for _, result := range results {
+		if result.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+func pass(format string, a ...interface{}) Result {
+	return Result{Status: Pass, Message: fmt.Sprintf(format, a...)}
+}
+
+func warn(hint string, format string, a ...interface{}) Result {
+	return Result{Status: Warn, Message: fmt.Sprintf(format, a...), Hint: hint}
+}
+
+func fail(hint string, format string, a ...interface{}) Result {
+	return Result{Status: Fail, Message: fmt.Sprintf(format, a...), Hint: hint}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diagnose
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+type fakeCheck struct {
+	name    string
+	applies bool
+	status  Status
+}
+
+func (c *fakeCheck) Name() string { return c.name }
+
+func (c *fakeCheck) Applies(*Environment) bool { return c.applies }
+
+func (c *fakeCheck) Run(context.Context, *Environment) Result {
+	return Result{Status: c.status, Message: "message"}
+}
+
+func TestRun(t *testing.T) {
+	checks := []Check{
+		&fakeCheck{name: "first", applies: true, status: Pass},
+		&fakeCheck{name: "skipped", applies: false, status: Fail},
+		&fakeCheck{name: "second", applies: true, status: Warn},
+	}
+
+	results := Run(context.Background(), &Environment{Pipeline: &latest.SkaffoldPipeline{}}, checks)
+
+	testutil.CheckDeepEqual(t, []Result{
+		{Check: "first", Status: Pass, Message: "message"},
+		{Check: "second", Status: Warn, Message: "message"},
+	}, results)
+	testutil.CheckDeepEqual(t, false, Failed(results))
+	testutil.CheckDeepEqual(t, true, Failed(append(results, Result{Status: Fail})))
+}
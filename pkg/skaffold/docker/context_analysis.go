@@ -0,0 +1,274 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/docker/docker/pkg/fileutils"
+	"github.com/pkg/errors"
+)
+
+// FileSize is the size of a file, or of a directory, of the build context.
+type FileSize struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+// ContextAnalysis explains what is sent to the builder of a Docker artifact.
+//
+// Skaffold only sends the files referenced by the Dockerfile but
+// `docker build`, used with `useDockerCLI` or `useBuildkit`, sends the whole
+// workspace, minus what's excluded by the .dockerignore.
+type ContextAnalysis struct {
+	// Size and Files describe the context created by skaffold.
+	Size  int64 `json:"size"`
+	Files int   `json:"files"`
+
+	// FullSize and FullFiles describe the context sent by `docker build`.
+	FullSize  int64 `json:"fullSize"`
+	FullFiles int   `json:"fullFiles"`
+
+	// LargestFiles and LargestDirectories of the context sent by `docker build`.
+	LargestFiles       []FileSize `json:"largestFiles"`
+	LargestDirectories []FileSize `json:"largestDirectories"`
+
+	// Unreferenced lists the largest files of the context
+	// that are never referenced by `COPY` or `ADD`.
+	Unreferenced      []FileSize `json:"unreferenced"`
+	UnreferencedSize  int64      `json:"unreferencedSize"`
+	UnreferencedFiles int        `json:"unreferencedFiles"`
+
+	// Suggestions are .dockerignore entries that exclude the unreferenced
+	// files, largest first.
+	Suggestions []FileSize `json:"suggestions"`
+}
+
+// AnalyzeContext analyzes the build context of a Docker artifact. Lists are
+// limited to the `top` largest entries.
+func AnalyzeContext(ctx context.Context, workspace string, a *latest.DockerArtifact, top int) (*ContextAnalysis, error) {
+	analysis := &ContextAnalysis{}
+
+	// Files referenced by the Dockerfile, as sent by skaffold.
+	referenced, err := tarContents(ctx, workspace, a)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating docker context")
+	}
+	for _, size := range referenced {
+		analysis.Size += size
+		analysis.Files++
+	}
+
+	// The Dockerfile is always needed, even if it's in a sub-directory.
+	dockerfile, err := NormalizeDockerfilePath(workspace, a.DockerfilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "normalizing dockerfile path")
+	}
+	absWorkspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return nil, err
+	}
+	if rel, err := filepath.Rel(absWorkspace, dockerfile); err == nil {
+		referenced[filepath.ToSlash(rel)] = 0
+	}
+
+	// Files sent by `docker build`.
+	full, err := fullContext(workspace)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing workspace")
+	}
+
+	files := []FileSize{}
+	unreferenced := []FileSize{}
+	directories := map[string]int64{}
+	for path, size := range full {
+		files = append(files, FileSize{Path: path, Size: size})
+		for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+			directories[dir] += size
+		}
+
+		if _, found := referenced[filepath.ToSlash(path)]; !found && path != ".dockerignore" {
+			unreferenced = append(unreferenced, FileSize{Path: path, Size: size})
+			analysis.UnreferencedSize += size
+		}
+	}
+
+	analysis.FullFiles = len(files)
+	for _, file := range files {
+		analysis.FullSize += file.Size
+	}
+	analysis.UnreferencedFiles = len(unreferenced)
+	analysis.LargestFiles = largest(files, top)
+	analysis.LargestDirectories = largest(toFileSizes(directories), top)
+	analysis.Unreferenced = largest(unreferenced, top)
+	analysis.Suggestions = largest(suggestIgnores(referenced, unreferenced), top)
+
+	return analysis, nil
+}
+
+// tarContents lists the files of the context created by skaffold, with their sizes.
+func tarContents(ctx context.Context, workspace string, a *latest.DockerArtifact) (map[string]int64, error) {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(CreateDockerTarContext(ctx, w, workspace, a))
+	}()
+	defer r.Close()
+
+	files := map[string]int64{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files[header.Name] = header.Size
+		if _, err := io.Copy(ioutil.Discard, tr); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// fullContext lists the files of the workspace that are not excluded by the .dockerignore.
+func fullContext(workspace string) (map[string]int64, error) {
+	excludes, err := readDockerignore(workspace)
+	if err != nil {
+		return nil, err
+	}
+	pExclude, err := fileutils.NewPatternMatcher(excludes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid exclude patterns")
+	}
+
+	files := map[string]int64{}
+	err = filepath.Walk(workspace, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(workspace, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+
+		ignored, err := pExclude.Matches(relPath)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			// Files of an ignored directory can be re-included with `!`.
+			if ignored && !pExclude.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !ignored {
+			files[relPath] = info.Size()
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// suggestIgnores groups the unreferenced files by their outermost directory
+// that doesn't contain any referenced file.
+func suggestIgnores(referenced map[string]int64, unreferenced []FileSize) []FileSize {
+	usedDirs := map[string]bool{}
+	for path := range referenced {
+		for dir := filepath.Dir(filepath.FromSlash(path)); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			usedDirs[dir] = true
+		}
+	}
+
+	sizes := map[string]int64{}
+	for _, file := range unreferenced {
+		entry := file.Path
+		parts := strings.Split(file.Path, string(filepath.Separator))
+		for i := 1; i < len(parts); i++ {
+			dir := filepath.Join(parts[:i]...)
+			if !usedDirs[dir] {
+				entry = dir
+				break
+			}
+		}
+		sizes[filepath.ToSlash(entry)] += file.Size
+	}
+
+	return toFileSizes(sizes)
+}
+
+// AppendDockerignore adds entries to the .dockerignore of a workspace.
+func AppendDockerignore(workspace string, entries []string) error {
+	path := filepath.Join(workspace, ".dockerignore")
+
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "reading .dockerignore")
+	}
+
+	var content strings.Builder
+	content.Write(existing)
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		content.WriteString("\n")
+	}
+	content.WriteString("# Files not referenced by the Dockerfile, added by skaffold diagnose\n")
+	for _, entry := range entries {
+		fmt.Fprintln(&content, entry)
+	}
+
+	return ioutil.WriteFile(path, []byte(content.String()), 0644)
+}
+
+func toFileSizes(sizes map[string]int64) []FileSize {
+	list := []FileSize{}
+	for path, size := range sizes {
+		list = append(list, FileSize{Path: path, Size: size})
+	}
+	return list
+}
+
+// largest sorts by size, then by path, and keeps the top entries.
+func largest(list []FileSize, top int) []FileSize {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Size != list[j].Size {
+			return list[i].Size > list[j].Size
+		}
+		return list[i].Path < list[j].Path
+	})
+
+	if len(list) > top {
+		list = list[:top]
+	}
+	return list
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestAnalyzeContext(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	imageFetcher := fakeImageFetcher{}
+	RetrieveImage = imageFetcher.fetch
+	defer func() { RetrieveImage = retrieveImage }()
+
+	tmpDir.Write("Dockerfile", "FROM alpine\nCOPY src/main.go go.mod /app/").
+		Write(".dockerignore", "*.log").
+		Write("go.mod", "module app").
+		Write("src/main.go", "package main").
+		Write("src/main_test.go", "package main // tests").
+		Write("node_modules/a/index.js", strings.Repeat("a", 100)).
+		Write("node_modules/b/index.js", strings.Repeat("b", 50)).
+		Write("dump.sql", strings.Repeat("x", 200)).
+		Write("debug.log", strings.Repeat("l", 1000))
+
+	analysis, err := AnalyzeContext(context.Background(), tmpDir.Root(), &latest.DockerArtifact{DockerfilePath: "Dockerfile"}, 3)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, &ContextAnalysis{
+		Size:      int64(len("FROM alpine\nCOPY src/main.go go.mod /app/") + len("module app") + len("package main")),
+		Files:     3,
+		FullSize:  int64(41 + 5 + 10 + 12 + 21 + 100 + 50 + 200),
+		FullFiles: 8,
+		LargestFiles: []FileSize{
+			{Path: "dump.sql", Size: 200},
+			{Path: filepath.Join("node_modules", "a", "index.js"), Size: 100},
+			{Path: filepath.Join("node_modules", "b", "index.js"), Size: 50},
+		},
+		LargestDirectories: []FileSize{
+			{Path: "node_modules", Size: 150},
+			{Path: filepath.Join("node_modules", "a"), Size: 100},
+			{Path: filepath.Join("node_modules", "b"), Size: 50},
+		},
+		Unreferenced: []FileSize{
+			{Path: "dump.sql", Size: 200},
+			{Path: filepath.Join("node_modules", "a", "index.js"), Size: 100},
+			{Path: filepath.Join("node_modules", "b", "index.js"), Size: 50},
+		},
+		UnreferencedSize:  200 + 100 + 50 + 21,
+		UnreferencedFiles: 4,
+		Suggestions: []FileSize{
+			{Path: "dump.sql", Size: 200},
+			{Path: "node_modules", Size: 150},
+			{Path: "src/main_test.go", Size: 21},
+		},
+	}, analysis)
+}
+
+func TestAppendDockerignore(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	err := AppendDockerignore(tmpDir.Root(), []string{"node_modules"})
+	testutil.CheckError(t, false, err)
+
+	tmpDir.Write(".dockerignore", "*.log")
+	err = AppendDockerignore(tmpDir.Root(), []string{"node_modules", "dump.sql"})
+	testutil.CheckError(t, false, err)
+
+	content, err := ioutil.ReadFile(tmpDir.Path(".dockerignore"))
+	testutil.CheckErrorAndDeepEqual(t, false, err, `*.log
+# Files not referenced by the Dockerfile, added by skaffold diagnose
+node_modules
+dump.sql
+`, string(content))
+}
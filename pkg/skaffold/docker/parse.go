@@ -21,8 +21,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -68,27 +70,168 @@ func ValidateDockerfile(path string) bool {
 	return true
 }
 
-func expandBuildArgs(nodes []*parser.Node, buildArgs map[string]*string) {
+// maxExposedPortRange is the widest range of exposed ports that is listed.
+// Wider ranges, like `30000-40000`, are ignored.
+const maxExposedPortRange = 100
+
+// ExposedPort is a port exposed by a Dockerfile, with its protocol: tcp, udp or sctp.
+type ExposedPort struct {
+	Port     int
+	Protocol string
+}
+
+// ExposedPorts lists the ports exposed by the last stage of a Dockerfile,
+// without duplicates. Ports exposed by the base image are ignored.
+func ExposedPorts(dockerfilePath string) ([]ExposedPort, error) {
+	f, err := os.Open(dockerfilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening dockerfile: %s", dockerfilePath)
+	}
+	defer f.Close()
+
+	res, err := parser.Parse(f)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing dockerfile")
+	}
+
+	if err := expandBuildArgs(res.AST.Children, nil); err != nil {
+		return nil, errors.Wrap(err, "expanding build args")
+	}
+
+	var ports []ExposedPort
+	seen := map[ExposedPort]bool{}
+	envs := map[string]string{}
+	slex := shell.NewLex('\\')
+	for _, node := range res.AST.Children {
+		switch node.Value {
+		case command.From:
+			ports = nil
+			seen = map[ExposedPort]bool{}
+			envs = map[string]string{}
+		case command.Env:
+			for kv := node.Next; kv != nil && kv.Next != nil; kv = kv.Next.Next {
+				envs[kv.Value] = kv.Next.Value
+			}
+		case command.Expose:
+			for value := node.Next; value != nil; value = value.Next {
+				spec, err := processShellWord(slex, value.Value, envs)
+				if err != nil {
+					return nil, errors.Wrap(err, "processing word")
+				}
+				exposed, err := parsePorts(spec)
+				if err != nil {
+					return nil, err
+				}
+				for _, port := range exposed {
+					if !seen[port] {
+						seen[port] = true
+						ports = append(ports, port)
+					}
+				}
+			}
+		}
+	}
+
+	return ports, nil
+}
+
+// parsePorts parses `port`, `port/protocol` or `start-end/protocol`.
+func parsePorts(spec string) ([]ExposedPort, error) {
+	portRange, protocol := spec, "tcp"
+	if i := strings.Index(spec, "/"); i != -1 {
+		portRange, protocol = spec[:i], strings.ToLower(spec[i+1:])
+	}
+	switch protocol {
+	case "tcp", "udp", "sctp":
+	default:
+		return nil, fmt.Errorf("invalid protocol of exposed port %s", spec)
+	}
+
+	bounds := strings.SplitN(portRange, "-", 2)
+	start, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid exposed port %s", spec)
+	}
+	end := start
+	if len(bounds) == 2 {
+		if end, err = strconv.Atoi(bounds[1]); err != nil {
+			return nil, errors.Wrapf(err, "invalid exposed port %s", spec)
+		}
+	}
+	if start < 1 || end > 65535 || start > end {
+		return nil, fmt.Errorf("invalid exposed port %s", spec)
+	}
+	if end-start >= maxExposedPortRange {
+		logrus.Warnf("ignoring the range of exposed ports %s: it has more than %d ports", spec, maxExposedPortRange)
+		return nil, nil
+	}
+
+	var ports []ExposedPort
+	for port := start; port <= end; port++ {
+		ports = append(ports, ExposedPort{Port: port, Protocol: protocol})
+	}
+	return ports, nil
+}
+
+// expandBuildArgs replaces the build args with their values.
+// ARGs declared before the first FROM are only used in FROM instructions
+// and as defaults of the ARGs declared, without a value, inside a stage.
+func expandBuildArgs(nodes []*parser.Node, buildArgs map[string]*string) error {
+	slex := shell.NewLex('\\')
+	metaArgs := map[string]string{}
+	stageArgs := map[string]string{}
+	inStage := false
+
 	for i, node := range nodes {
+		if node.Value == command.From {
+			image, err := processShellWord(slex, node.Next.Value, metaArgs)
+			if err != nil {
+				return errors.Wrap(err, "processing base image")
+			}
+			node.Next.Value = image
+			stageArgs = map[string]string{}
+			inStage = true
+			continue
+		}
 		if node.Value != command.Arg {
 			continue
 		}
 
 		// build arg's key
-		keyValue := strings.Split(node.Next.Value, "=")
+		keyValue := strings.SplitN(node.Next.Value, "=", 2)
 		key := keyValue[0]
 
 		// build arg's value
 		var value string
-		if buildArgs[key] != nil {
+		switch {
+		case buildArgs[key] != nil:
 			value = *buildArgs[key]
-		} else if len(keyValue) > 1 {
-			value = keyValue[1]
+		case len(keyValue) > 1:
+			scope := metaArgs
+			if inStage {
+				scope = stageArgs
+			}
+			defaultValue, err := processShellWord(slex, keyValue[1], scope)
+			if err != nil {
+				return errors.Wrapf(err, "processing default value of %s", key)
+			}
+			value = defaultValue
+		case inStage:
+			value = metaArgs[key]
 		}
 
+		if !inStage {
+			metaArgs[key] = value
+			continue
+		}
+		stageArgs[key] = value
+
 		for _, node := range nodes[i+1:] {
-			// Stop replacements if an arg is redefined with the same key
-			if node.Value == command.Arg && strings.Split(node.Next.Value, "=")[0] == key {
+			// Stop replacements at the end of the stage or if an arg is redefined with the same key
+			if node.Value == command.From {
+				break
+			}
+			if node.Value == command.Arg && strings.SplitN(node.Next.Value, "=", 2)[0] == key {
 				break
 			}
 
@@ -98,18 +241,8 @@ func expandBuildArgs(nodes []*parser.Node, buildArgs map[string]*string) {
 			}
 		}
 	}
-}
-
-func fromInstructions(nodes []*parser.Node) []from {
-	var list []from
-
-	for _, node := range nodes {
-		if node.Value == command.From {
-			list = append(list, fromInstruction(node))
-		}
-	}
 
-	return list
+	return nil
 }
 
 func fromInstruction(node *parser.Node) from {
@@ -124,66 +257,187 @@ func fromInstruction(node *parser.Node) from {
 	}
 }
 
-func onbuildInstructions(nodes []*parser.Node) ([]*parser.Node, error) {
-	var instructions []string
+// stage is a FROM instruction and the instructions that follow it.
+type stage struct {
+	from
+	nodes []*parser.Node
+}
 
-	stages := map[string]bool{}
-	for _, from := range fromInstructions(nodes) {
-		stages[from.as] = true
+// splitStages splits the instructions of a Dockerfile into stages.
+// Instructions before the first FROM are ignored.
+func splitStages(nodes []*parser.Node) []stage {
+	var stages []stage
 
-		if from.image == "scratch" {
-			continue
+	for _, node := range nodes {
+		if node.Value == command.From {
+			stages = append(stages, stage{from: fromInstruction(node)})
+		} else if len(stages) > 0 {
+			last := &stages[len(stages)-1]
+			last.nodes = append(last.nodes, node)
 		}
+	}
 
-		if _, found := stages[from.image]; found {
-			continue
-		}
+	return stages
+}
 
-		logrus.Debugf("Checking base image %s for ONBUILD triggers.", from.image)
-		img, err := RetrieveImage(from.image)
-		if err != nil {
-			logrus.Warnf("Error processing base image for ONBUILD triggers: %s. Dependencies may be incomplete.", err)
-			continue
-		}
+// imageConfig is the part of a stage's configuration that affects how
+// the COPY and ADD instructions are interpreted.
+type imageConfig struct {
+	envs    map[string]string
+	workdir string
+}
+
+func (c imageConfig) copy() imageConfig {
+	envs := map[string]string{}
+	for k, v := range c.envs {
+		envs[k] = v
+	}
+	return imageConfig{envs: envs, workdir: c.workdir}
+}
 
-		if len(img.Config.OnBuild) > 0 {
-			logrus.Debugf("Found ONBUILD triggers %v in image %s", img.Config.OnBuild, from.image)
-			instructions = append(instructions, img.Config.OnBuild...)
+// baseImage returns the configuration that a stage inherits from its base image
+// and the ONBUILD triggers of this image.
+func baseImage(image string) (imageConfig, []*parser.Node, error) {
+	config := imageConfig{envs: map[string]string{}, workdir: "/"}
+	if image == "scratch" {
+		return config, nil, nil
+	}
+
+	img, err := RetrieveImage(image)
+	if err != nil {
+		logrus.Warnf("Error retrieving base image %s: %s. Dependencies and sync destinations may be incomplete.", image, err)
+		return config, nil, nil
+	}
+
+	for _, env := range img.Config.Env {
+		if kv := strings.SplitN(env, "=", 2); len(kv) == 2 {
+			config.envs[kv[0]] = kv[1]
 		}
 	}
+	if img.Config.WorkingDir != "" {
+		config.workdir = img.Config.WorkingDir
+	}
 
-	obRes, err := parser.Parse(strings.NewReader(strings.Join(instructions, "\n")))
+	if len(img.Config.OnBuild) == 0 {
+		return config, nil, nil
+	}
+
+	logrus.Debugf("Found ONBUILD triggers %v in image %s", img.Config.OnBuild, image)
+	obRes, err := parser.Parse(strings.NewReader(strings.Join(img.Config.OnBuild, "\n")))
 	if err != nil {
-		return nil, errors.Wrap(err, "parsing ONBUILD instructions")
+		return config, nil, errors.Wrap(err, "parsing ONBUILD instructions")
 	}
 
-	return obRes.AST.Children, nil
+	return config, obRes.AST.Children, nil
 }
 
-func copiedFiles(nodes []*parser.Node) ([][]string, error) {
-	var copied [][]string
+// copyInstruction is a COPY or ADD instruction with its words resolved.
+type copyInstruction struct {
+	stage     int
+	srcs      []string
+	urls      []string
+	dest      string
+	destIsDir bool
+}
 
-	envs := map[string]string{}
-	for _, node := range nodes {
-		switch node.Value {
-		case command.Add, command.Copy:
-			files, err := processCopy(node, envs)
-			if err != nil {
-				return nil, err
+// dockerfile lists the instructions of a Dockerfile that read from
+// outside of the images: copies of files from the workspace and remote urls.
+type dockerfile struct {
+	copies []copyInstruction
+	// parents is, for each stage, the index of the stage it is based on or -1.
+	parents []int
+}
+
+func walkStages(nodes []*parser.Node) (*dockerfile, error) {
+	df := &dockerfile{}
+
+	slex := shell.NewLex('\\')
+	stageIndexes := map[string]int{}
+	var stageConfigs []imageConfig
+
+	for i, stage := range splitStages(nodes) {
+		parent := -1
+		instructions := stage.nodes
+
+		var config imageConfig
+		if index, found := stageIndexes[stage.image]; found {
+			// A stage based on a previous stage inherits its configuration.
+			parent = index
+			config = stageConfigs[index].copy()
+		} else {
+			var onbuild []*parser.Node
+			var err error
+			if config, onbuild, err = baseImage(stage.image); err != nil {
+				return nil, errors.Wrap(err, "listing ONBUILD instructions")
 			}
+			instructions = append(onbuild, instructions...)
+		}
 
-			if len(files) > 0 {
-				copied = append(copied, files)
+		for _, node := range instructions {
+			switch node.Value {
+			case command.Env:
+				for kv := node.Next; kv != nil && kv.Next != nil; kv = kv.Next.Next {
+					config.envs[kv.Value] = kv.Next.Value
+				}
+
+			case command.Workdir:
+				dir, err := processShellWord(slex, node.Next.Value, config.envs)
+				if err != nil {
+					return nil, errors.Wrap(err, "processing word")
+				}
+				config.workdir = resolvePath(config.workdir, dir)
+
+			case command.Add, command.Copy:
+				copied, err := processCopy(node, config)
+				if err != nil {
+					return nil, err
+				}
+				if copied != nil {
+					copied.stage = i
+					df.copies = append(df.copies, *copied)
+				}
 			}
-		case command.Env:
-			envs[node.Next.Value] = node.Next.Next.Value
+		}
+
+		stageConfigs = append(stageConfigs, config)
+		df.parents = append(df.parents, parent)
+		if stage.as != "" {
+			stageIndexes[stage.as] = i
 		}
 	}
 
-	return copied, nil
+	return df, nil
 }
 
-func readDockerfile(workspace, absDockerfilePath string, buildArgs map[string]*string) ([]string, error) {
+// copiedFiles lists the sources, from the workspace, of each COPY and ADD instruction.
+func (df *dockerfile) copiedFiles() [][]string {
+	var copied [][]string
+	for _, c := range df.copies {
+		if len(c.srcs) > 0 {
+			copied = append(copied, c.srcs)
+		}
+	}
+	return copied
+}
+
+// finalCopies lists the COPY and ADD instructions of the final stage
+// and of the stages it is based on.
+func (df *dockerfile) finalCopies() []copyInstruction {
+	inFinalImage := map[int]bool{}
+	for stage := len(df.parents) - 1; stage >= 0; stage = df.parents[stage] {
+		inFinalImage[stage] = true
+	}
+
+	var copies []copyInstruction
+	for _, c := range df.copies {
+		if inFinalImage[c.stage] {
+			copies = append(copies, c)
+		}
+	}
+	return copies
+}
+
+func readDockerfile(absDockerfilePath string, buildArgs map[string]*string) (*dockerfile, error) {
 	f, err := os.Open(absDockerfilePath)
 	if err != nil {
 		return nil, errors.Wrapf(err, "opening dockerfile: %s", absDockerfilePath)
@@ -195,19 +449,16 @@ func readDockerfile(workspace, absDockerfilePath string, buildArgs map[string]*s
 		return nil, errors.Wrap(err, "parsing dockerfile")
 	}
 
-	expandBuildArgs(res.AST.Children, buildArgs)
-
-	instructions, err := onbuildInstructions(res.AST.Children)
-	if err != nil {
-		return nil, errors.Wrap(err, "listing ONBUILD instructions")
+	if err := expandBuildArgs(res.AST.Children, buildArgs); err != nil {
+		return nil, errors.Wrap(err, "expanding build args")
 	}
 
-	copied, err := copiedFiles(append(instructions, res.AST.Children...))
+	df, err := walkStages(res.AST.Children)
 	if err != nil {
 		return nil, errors.Wrap(err, "listing copied files")
 	}
 
-	return expandPaths(workspace, copied)
+	return df, nil
 }
 
 func expandPaths(workspace string, copied [][]string) ([]string, error) {
@@ -264,25 +515,20 @@ func GetDependencies(ctx context.Context, workspace string, a *latest.DockerArti
 		return nil, errors.Wrap(err, "normalizing dockerfile path")
 	}
 
-	deps, err := readDockerfile(workspace, absDockerfilePath, a.BuildArgs)
+	df, err := readDockerfile(absDockerfilePath, a.BuildArgs)
 	if err != nil {
 		return nil, err
 	}
 
-	// Read patterns to ignore
-	var excludes []string
-	dockerignorePath := filepath.Join(workspace, ".dockerignore")
-	if _, err := os.Stat(dockerignorePath); !os.IsNotExist(err) {
-		r, err := os.Open(dockerignorePath)
-		if err != nil {
-			return nil, err
-		}
-		defer r.Close()
+	deps, err := expandPaths(workspace, df.copiedFiles())
+	if err != nil {
+		return nil, err
+	}
 
-		excludes, err = dockerignore.ReadAll(r)
-		if err != nil {
-			return nil, err
-		}
+	// Read patterns to ignore
+	excludes, err := readDockerignore(workspace)
+	if err != nil {
+		return nil, err
 	}
 
 	pExclude, err := fileutils.NewPatternMatcher(excludes)
@@ -321,7 +567,8 @@ func GetDependencies(ctx context.Context, workspace string, a *latest.DockerArti
 					}
 
 					if info.IsDir() {
-						if ignored {
+						// Files of an ignored directory can be re-included with `!`.
+						if ignored && !pExclude.Exclusions() {
 							return filepath.SkipDir
 						}
 					} else if !ignored {
@@ -364,6 +611,163 @@ func GetDependencies(ctx context.Context, workspace string, a *latest.DockerArti
 	return dependencies, nil
 }
 
+// RemoteDependencies lists the remote urls downloaded by the ADD instructions
+// of the given docker artifact.
+func RemoteDependencies(workspace string, a *latest.DockerArtifact) ([]string, error) {
+	absDockerfilePath, err := NormalizeDockerfilePath(workspace, a.DockerfilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "normalizing dockerfile path")
+	}
+
+	df, err := readDockerfile(absDockerfilePath, a.BuildArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var urls []string
+	for _, c := range df.copies {
+		for _, url := range c.urls {
+			if !util.StrSliceContains(urls, url) {
+				urls = append(urls, url)
+			}
+		}
+	}
+	sort.Strings(urls)
+
+	return urls, nil
+}
+
+// readDockerignore reads the patterns of the workspace's .dockerignore, if any.
+func readDockerignore(workspace string) ([]string, error) {
+	dockerignorePath := filepath.Join(workspace, ".dockerignore")
+	if _, err := os.Stat(dockerignorePath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	r, err := os.Open(dockerignorePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return dockerignore.ReadAll(r)
+}
+
+// copyRule describes where a COPY or ADD instruction puts a source
+// of the build context in the image.
+type copyRule struct {
+	src       string
+	dest      string
+	destIsDir bool
+}
+
+// destination computes where a file, relative to the build context, is copied by the rule.
+func (r copyRule) destination(file string) (string, bool) {
+	switch {
+	case r.src == ".":
+		return path.Join(r.dest, file), true
+
+	case file == r.src:
+		if r.destIsDir {
+			return path.Join(r.dest, path.Base(file)), true
+		}
+		return r.dest, true
+
+	case strings.HasPrefix(file, r.src+"/"):
+		return path.Join(r.dest, strings.TrimPrefix(file, r.src+"/")), true
+	}
+
+	if !util.HasMeta(r.src) {
+		return "", false
+	}
+
+	// A wildcard source can match the file itself or one of its parent folders.
+	for dir := file; dir != "."; dir = path.Dir(dir) {
+		if matches, _ := path.Match(r.src, dir); !matches {
+			continue
+		}
+
+		if dir == file {
+			return path.Join(r.dest, path.Base(file)), true
+		}
+		return path.Join(r.dest, strings.TrimPrefix(file, dir+"/")), true
+	}
+
+	return "", false
+}
+
+// SyncDestinations infers, from the COPY and ADD instructions of the final image
+// of a Dockerfile, where the given files end up in the image.
+// Files are relative to the workspace. Files that are not copied, or that
+// are excluded from the build context by .dockerignore, are omitted.
+func SyncDestinations(workspace string, a *latest.DockerArtifact, files []string) (map[string][]string, error) {
+	absDockerfilePath, err := NormalizeDockerfilePath(workspace, a.DockerfilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "normalizing dockerfile path")
+	}
+
+	df, err := readDockerfile(absDockerfilePath, a.BuildArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	excludes, err := readDockerignore(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	pExclude, err := fileutils.NewPatternMatcher(excludes)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid exclude patterns")
+	}
+
+	rules := copyRules(df.finalCopies())
+
+	destinations := map[string][]string{}
+	for _, file := range files {
+		ignored, err := pExclude.Matches(filepath.Clean(file))
+		if err != nil {
+			return nil, err
+		}
+		if ignored {
+			continue
+		}
+
+		for _, rule := range rules {
+			dest, found := rule.destination(filepath.ToSlash(filepath.Clean(file)))
+			if found && !util.StrSliceContains(destinations[file], dest) {
+				destinations[file] = append(destinations[file], dest)
+			}
+		}
+	}
+
+	return destinations, nil
+}
+
+func copyRules(copies []copyInstruction) []copyRule {
+	var rules []copyRule
+
+	for _, c := range copies {
+		for _, src := range c.srcs {
+			rules = append(rules, copyRule{
+				src:       path.Clean(strings.TrimPrefix(filepath.ToSlash(src), "/")),
+				dest:      c.dest,
+				destIsDir: c.destIsDir || util.HasMeta(src),
+			})
+		}
+	}
+
+	return rules
+}
+
+// resolvePath resolves a path in the image relative to the working directory.
+func resolvePath(workdir, p string) string {
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(workdir, p)
+}
+
 var imageCache sync.Map
 
 func retrieveImage(image string) (*v1.ConfigFile, error) {
@@ -413,36 +817,53 @@ func retrieveRemoteConfig(identifier string) (*v1.ConfigFile, error) {
 	return img.ConfigFile()
 }
 
-func processCopy(value *parser.Node, envs map[string]string) ([]string, error) {
-	var copied []string
+// processCopy resolves the sources and the destination of a COPY or ADD instruction.
+// Remote sources of ADD instructions are returned separately.
+func processCopy(node *parser.Node, config imageConfig) (*copyInstruction, error) {
+	// If the --from flag is provided, we are dealing with a multi-stage dockerfile
+	// Adding a dependency from a different stage does not imply a source dependency
+	if hasMultiStageFlag(node.Flags) {
+		return nil, nil
+	}
 
+	var words []string
 	slex := shell.NewLex('\\')
-	for {
-		// Skip last node, since it is the destination, and stop if we arrive at a comment
-		if value.Next.Next == nil || strings.HasPrefix(value.Next.Next.Value, "#") {
-			break
-		}
-		src, err := processShellWord(slex, value.Next.Value, envs)
+	// Stop if we arrive at a comment
+	for word := node.Next; word != nil && !strings.HasPrefix(word.Value, "#"); word = word.Next {
+		value, err := processShellWord(slex, word.Value, config.envs)
 		if err != nil {
 			return nil, errors.Wrap(err, "processing word")
 		}
-		// If the --from flag is provided, we are dealing with a multi-stage dockerfile
-		// Adding a dependency from a different stage does not imply a source dependency
-		if hasMultiStageFlag(value.Flags) {
-			return nil, nil
-		}
-		if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
-			copied = append(copied, src)
-		} else {
-			logrus.Debugf("Skipping watch on remote dependency %s", src)
-		}
+		words = append(words, value)
+	}
+	if len(words) < 2 {
+		return nil, nil
+	}
 
-		value = value.Next
+	srcs, dest := words[:len(words)-1], words[len(words)-1]
+	copied := &copyInstruction{
+		dest:      resolvePath(config.workdir, dest),
+		destIsDir: len(srcs) > 1 || dest == "." || strings.HasSuffix(dest, "/"),
+	}
+
+	for _, src := range srcs {
+		switch {
+		case !isURL(src):
+			copied.srcs = append(copied.srcs, src)
+		case node.Value == command.Add:
+			copied.urls = append(copied.urls, src)
+		default:
+			logrus.Debugf("Skipping remote source %s of COPY", src)
+		}
 	}
 
 	return copied, nil
 }
 
+func isURL(src string) bool {
+	return strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")
+}
+
 func processShellWord(lex *shell.Lex, word string, envs map[string]string) (string, error) {
 	envSlice := []string{}
 	for envKey, envVal := range envs {
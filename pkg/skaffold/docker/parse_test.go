@@ -174,6 +174,93 @@ FROM nginx
 COPY . /
 `
 
+const copyWorkdirFromBaseImage = `
+FROM node:10
+COPY server.js .
+`
+
+const copyFromStage = `
+FROM golang:1.9.2 as builder
+COPY worker.go .
+FROM busybox
+COPY --from=builder /go/worker /worker
+COPY --from=nginx /etc/nginx/nginx.conf /etc/nginx/
+`
+
+const envFromParentStage = `
+FROM busybox as base
+ENV file server.go
+FROM base
+COPY $file .
+`
+
+const envFromOtherStage = `
+FROM busybox as base
+ENV file server.go
+FROM busybox
+COPY ${file:-worker.go} .
+`
+
+const multipleEnvs = `
+FROM busybox
+ENV first=server.go second=worker.go
+COPY $first $second .
+`
+
+const argInFrom = `
+ARG BASE=ubuntu:14.04
+FROM $BASE
+COPY server.go .
+`
+
+const metaArgRedeclared = `
+ARG FILE=server.go
+FROM ubuntu:14.04
+ARG FILE
+COPY $FILE .
+`
+
+const metaArgNotRedeclared = `
+ARG FILE=server.go
+FROM ubuntu:14.04
+COPY ${FILE:-worker.go} .
+`
+
+const argScopedToStage = `
+FROM ubuntu:14.04 as base
+ARG FILE=server.go
+FROM ubuntu:14.04
+COPY ${FILE:-worker.go} .
+`
+
+const argQuotedDefault = `
+FROM ubuntu:14.04
+ARG FILE="server.go"
+COPY $FILE .
+`
+
+const remoteAdds = `
+ARG VERSION=1.0
+FROM ubuntu:14.04 as builder
+ARG VERSION
+ADD https://example.com/tool-${VERSION}.tar.gz /
+ADD server.go http://example.com/config /app/
+FROM busybox
+ARG VERSION
+ADD https://example.com/tool-$VERSION.tar.gz /
+COPY https://example.com/ignored /
+`
+
+const copyIntoParentStage = `
+FROM busybox as base
+WORKDIR /app
+COPY server.go .
+FROM golang:1.9.2 as builder
+COPY worker.go .
+FROM base
+COPY worker.go .
+`
+
 type fakeImageFetcher struct {
 	fetched []string
 }
@@ -184,6 +271,12 @@ func (f *fakeImageFetcher) fetch(image string) (*v1.ConfigFile, error) {
 	switch image {
 	case "ubuntu:14.04", "busybox", "nginx", "golang:1.9.2":
 		return &v1.ConfigFile{}, nil
+	case "node:10":
+		return &v1.ConfigFile{
+			Config: v1.Config{
+				WorkingDir: "/usr/src/app",
+			},
+		}, nil
 	case "golang:onbuild":
 		return &v1.ConfigFile{
 			Config: v1.Config{
@@ -430,6 +523,100 @@ func TestGetDependencies(t *testing.T) {
 			expected:    []string{"Dockerfile"},
 			fetched:     []string{"ubuntu:14.04"},
 		},
+		{
+			description: "copy from stage or image",
+			dockerfile:  copyFromStage,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "worker.go"},
+			fetched:     []string{"golang:1.9.2", "busybox"},
+		},
+		{
+			description: "env inherited from parent stage",
+			dockerfile:  envFromParentStage,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "server.go"},
+			fetched:     []string{"busybox"},
+		},
+		{
+			description: "env not inherited from other stage",
+			dockerfile:  envFromOtherStage,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "worker.go"},
+			fetched:     []string{"busybox", "busybox"},
+		},
+		{
+			description: "multiple envs in one instruction",
+			dockerfile:  multipleEnvs,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "server.go", "worker.go"},
+			fetched:     []string{"busybox"},
+		},
+		{
+			description: "arg default in from",
+			dockerfile:  argInFrom,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "server.go"},
+			fetched:     []string{"ubuntu:14.04"},
+		},
+		{
+			description: "build arg in from",
+			dockerfile:  argInFrom,
+			workspace:   ".",
+			buildArgs:   map[string]*string{"BASE": util.StringPtr("nginx")},
+			expected:    []string{"Dockerfile", "server.go"},
+			fetched:     []string{"nginx"},
+		},
+		{
+			description: "arg declared before from and redeclared in stage",
+			dockerfile:  metaArgRedeclared,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "server.go"},
+			fetched:     []string{"ubuntu:14.04"},
+		},
+		{
+			description: "arg declared before from is not visible in stage",
+			dockerfile:  metaArgNotRedeclared,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "worker.go"},
+			fetched:     []string{"ubuntu:14.04"},
+		},
+		{
+			description: "arg is scoped to its stage",
+			dockerfile:  argScopedToStage,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "worker.go"},
+			fetched:     []string{"ubuntu:14.04", "ubuntu:14.04"},
+		},
+		{
+			description: "arg with quoted default",
+			dockerfile:  argQuotedDefault,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "server.go"},
+			fetched:     []string{"ubuntu:14.04"},
+		},
+		{
+			description: "no dependencies on remote adds",
+			dockerfile:  remoteAdds,
+			workspace:   ".",
+			expected:    []string{"Dockerfile", "server.go"},
+			fetched:     []string{"ubuntu:14.04", "busybox"},
+		},
+		{
+			description: "dockerignore with exclusion",
+			dockerfile:  copyAll,
+			workspace:   ".",
+			ignore:      "docker\n!docker/nginx.conf",
+			expected:    []string{".dot", "Dockerfile", "bar", filepath.Join("docker", "nginx.conf"), "file", "server.go", "test.conf", "worker.go"},
+			fetched:     []string{"nginx"},
+		},
+		{
+			description: "dockerignore with exclusion of a single file",
+			dockerfile:  copyAll,
+			workspace:   ".",
+			ignore:      "*\n!server.go",
+			expected:    []string{"Dockerfile", "server.go"},
+			fetched:     []string{"nginx"},
+		},
 		{
 			description: "from base stage, ignoring case",
 			dockerfile:  fromStageIgnoreCase,
@@ -471,3 +658,242 @@ func TestGetDependencies(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoteDependencies(t *testing.T) {
+	var tests = []struct {
+		description string
+		dockerfile  string
+		buildArgs   map[string]*string
+		expected    []string
+	}{
+		{
+			description: "no remote dependencies",
+			dockerfile:  copyServerGo,
+		},
+		{
+			description: "remote add",
+			dockerfile:  remoteFileAdd,
+			expected:    []string{"https://example.com/test"},
+		},
+		{
+			description: "remote adds in all stages",
+			dockerfile:  remoteAdds,
+			expected:    []string{"http://example.com/config", "https://example.com/tool-1.0.tar.gz"},
+		},
+		{
+			description: "remote adds with build arg",
+			dockerfile:  remoteAdds,
+			buildArgs:   map[string]*string{"VERSION": util.StringPtr("2.0")},
+			expected:    []string{"http://example.com/config", "https://example.com/tool-2.0.tar.gz"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			imageFetcher := fakeImageFetcher{}
+			RetrieveImage = imageFetcher.fetch
+			defer func() { RetrieveImage = retrieveImage }()
+
+			tmpDir.Write("Dockerfile", test.dockerfile)
+
+			urls, err := RemoteDependencies(tmpDir.Root(), &latest.DockerArtifact{
+				BuildArgs:      test.buildArgs,
+				DockerfilePath: "Dockerfile",
+			})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, urls)
+		})
+	}
+}
+
+func TestSyncDestinations(t *testing.T) {
+	var tests = []struct {
+		description string
+		dockerfile  string
+		buildArgs   map[string]*string
+		ignore      string
+		files       []string
+		expected    map[string][]string
+	}{
+		{
+			description: "copy to workdir",
+			dockerfile:  copyServerGo,
+			files:       []string{"server.go", "worker.go"},
+			expected:    map[string][]string{"server.go": {"/server.go"}},
+		},
+		{
+			description: "add to file",
+			dockerfile:  addNginx,
+			files:       []string{"nginx.conf"},
+			expected:    map[string][]string{"nginx.conf": {"/etc/nginx"}},
+		},
+		{
+			description: "copy twice",
+			dockerfile:  multiCopy,
+			files:       []string{"test.conf"},
+			expected:    map[string][]string{"test.conf": {"/etc/test1", "/etc/test2"}},
+		},
+		{
+			description: "wildcards",
+			dockerfile:  wildcards,
+			files:       []string{"server.go", filepath.Join("docker", "bar")},
+			expected:    map[string][]string{"server.go": {"/tmp/server.go"}},
+		},
+		{
+			description: "copy directory",
+			dockerfile:  copyDirectory,
+			files:       []string{filepath.Join("docker", "nginx.conf"), "file"},
+			expected: map[string][]string{
+				filepath.Join("docker", "nginx.conf"): {"/etc/docker/nginx.conf"},
+				"file":                                {"/etc/file"},
+			},
+		},
+		{
+			description: "env and workdir",
+			dockerfile:  envTest,
+			files:       []string{"bar"},
+			expected:    map[string][]string{"bar": {"/quux"}},
+		},
+		{
+			description: "only final stage",
+			dockerfile:  multiStageDockerfile,
+			files:       []string{"worker.go"},
+			expected:    map[string][]string{},
+		},
+		{
+			description: "build args",
+			dockerfile:  copyServerGoBuildArg,
+			buildArgs:   map[string]*string{"FOO": util.StringPtr("server.go")},
+			files:       []string{"server.go"},
+			expected:    map[string][]string{"server.go": {"/server.go"}},
+		},
+		{
+			description: "workdir from base image",
+			dockerfile:  copyWorkdirFromBaseImage,
+			files:       []string{"server.js"},
+			expected:    map[string][]string{"server.js": {"/usr/src/app/server.js"}},
+		},
+		{
+			description: "remote files",
+			dockerfile:  remoteFileAdd,
+			files:       []string{"test"},
+			expected:    map[string][]string{},
+		},
+		{
+			description: "final stage based on a previous stage",
+			dockerfile:  copyIntoParentStage,
+			files:       []string{"server.go", "worker.go"},
+			expected:    map[string][]string{"server.go": {"/app/server.go"}, "worker.go": {"/app/worker.go"}},
+		},
+		{
+			description: "dockerignored files",
+			dockerfile:  copyDirectory,
+			ignore:      "docker",
+			files:       []string{filepath.Join("docker", "nginx.conf"), "file"},
+			expected:    map[string][]string{"file": {"/etc/file"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			imageFetcher := fakeImageFetcher{}
+			RetrieveImage = imageFetcher.fetch
+			defer func() { RetrieveImage = retrieveImage }()
+
+			tmpDir.Write("Dockerfile", test.dockerfile)
+			if test.ignore != "" {
+				tmpDir.Write(".dockerignore", test.ignore)
+			}
+
+			destinations, err := SyncDestinations(tmpDir.Root(), &latest.DockerArtifact{
+				BuildArgs:      test.buildArgs,
+				DockerfilePath: "Dockerfile",
+			}, test.files)
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, destinations)
+		})
+	}
+}
+
+func TestExposedPorts(t *testing.T) {
+	var tests = []struct {
+		description string
+		dockerfile  string
+		expected    []ExposedPort
+		shouldErr   bool
+	}{
+		{
+			description: "no exposed ports",
+			dockerfile:  copyServerGo,
+		},
+		{
+			description: "several ports",
+			dockerfile:  "FROM nginx\nEXPOSE 80 443/tcp\nEXPOSE 53/udp",
+			expected:    []ExposedPort{{80, "tcp"}, {443, "tcp"}, {53, "udp"}},
+		},
+		{
+			description: "duplicates",
+			dockerfile:  "FROM nginx\nEXPOSE 80 80/udp\nEXPOSE 80/TCP",
+			expected:    []ExposedPort{{80, "tcp"}, {80, "udp"}},
+		},
+		{
+			description: "port range",
+			dockerfile:  "FROM nginx\nEXPOSE 8000-8002",
+			expected:    []ExposedPort{{8000, "tcp"}, {8001, "tcp"}, {8002, "tcp"}},
+		},
+		{
+			description: "wide port range",
+			dockerfile:  "FROM nginx\nEXPOSE 80 30000-40000",
+			expected:    []ExposedPort{{80, "tcp"}},
+		},
+		{
+			description: "env variable",
+			dockerfile:  "FROM nginx\nENV PORT 8080\nEXPOSE $PORT",
+			expected:    []ExposedPort{{8080, "tcp"}},
+		},
+		{
+			description: "build arg",
+			dockerfile:  "FROM nginx\nARG PORT=9000\nEXPOSE ${PORT}",
+			expected:    []ExposedPort{{9000, "tcp"}},
+		},
+		{
+			description: "only last stage",
+			dockerfile:  "FROM golang as builder\nEXPOSE 6060\nFROM alpine\nEXPOSE 8080",
+			expected:    []ExposedPort{{8080, "tcp"}},
+		},
+		{
+			description: "invalid port",
+			dockerfile:  "FROM nginx\nEXPOSE http",
+			shouldErr:   true,
+		},
+		{
+			description: "invalid range",
+			dockerfile:  "FROM nginx\nEXPOSE 8002-8000",
+			shouldErr:   true,
+		},
+		{
+			description: "invalid protocol",
+			dockerfile:  "FROM nginx\nEXPOSE 80/http",
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			tmpDir.Write("Dockerfile", test.dockerfile)
+
+			ports, err := ExposedPorts(tmpDir.Path("Dockerfile"))
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, ports)
+		})
+	}
+}
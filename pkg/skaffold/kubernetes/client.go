@@ -47,6 +47,14 @@ func getClientConfig() (*restclient.Config, error) {
 	return clientConfig, nil
 }
 
+// currentNamespace returns the namespace of the current kubectl context.
+func currentNamespace() (string, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+	namespace, _, err := kubeConfig.Namespace()
+	return namespace, err
+}
+
 func GetDynamicClient() (dynamic.Interface, error) {
 	config, err := getClientConfig()
 	if err != nil {
@@ -0,0 +1,130 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// ContainerWatcher calls a function for every new container of the selected pods,
+// as soon as it's running. Restarted containers are seen as new containers.
+type ContainerWatcher struct {
+	podSelector       PodSelector
+	onNewContainer    func(context.Context, *v1.Pod, v1.Container)
+	trackedContainers trackedContainers
+
+	// handled are closed, by container id, once onNewContainer has returned.
+	handled     map[string]chan struct{}
+	handledLock sync.Mutex
+}
+
+// NewContainerWatcher creates a new ContainerWatcher.
+func NewContainerWatcher(podSelector PodSelector, onNewContainer func(context.Context, *v1.Pod, v1.Container)) *ContainerWatcher {
+	return &ContainerWatcher{
+		podSelector:    podSelector,
+		onNewContainer: onNewContainer,
+		trackedContainers: trackedContainers{
+			ids: map[string]bool{},
+		},
+		handled: map[string]chan struct{}{},
+	}
+}
+
+// Wait blocks until the function called for a new container has returned,
+// or the context is cancelled. The container might not be seen yet.
+func (w *ContainerWatcher) Wait(ctx context.Context, containerID string) {
+	select {
+	case <-w.handledChan(containerID):
+	case <-ctx.Done():
+	}
+}
+
+func (w *ContainerWatcher) handledChan(containerID string) chan struct{} {
+	w.handledLock.Lock()
+	defer w.handledLock.Unlock()
+
+	c, present := w.handled[containerID]
+	if !present {
+		c = make(chan struct{})
+		w.handled[containerID] = c
+	}
+	return c
+}
+
+// Start listens to pods until the context is cancelled.
+func (w *ContainerWatcher) Start(ctx context.Context) error {
+	watcher, err := PodWatcher()
+	if err != nil {
+		return errors.Wrap(err, "initializing pod watcher")
+	}
+
+	go func() {
+		defer watcher.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+
+				if evt.Type != watch.Added && evt.Type != watch.Modified {
+					continue
+				}
+
+				pod, ok := evt.Object.(*v1.Pod)
+				if !ok || !w.podSelector.Select(pod) {
+					continue
+				}
+
+				w.checkContainers(ctx, pod)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// checkContainers calls onNewContainer for the running containers of a pod
+// that were not seen yet.
+func (w *ContainerWatcher) checkContainers(ctx context.Context, pod *v1.Pod) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.ContainerID == "" || status.State.Running == nil {
+			continue
+		}
+
+		if alreadyTracked := w.trackedContainers.add(status.ContainerID); alreadyTracked {
+			continue
+		}
+
+		for _, c := range pod.Spec.Containers {
+			if c.Name == status.Name {
+				go func(c v1.Container, containerID string) {
+					w.onNewContainer(ctx, pod, c)
+					close(w.handledChan(containerID))
+				}(c, status.ContainerID)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func podWithContainers(name string, statuses ...v1.ContainerStatus) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1.PodStatus{ContainerStatuses: statuses},
+	}
+	for _, status := range statuses {
+		pod.Spec.Containers = append(pod.Spec.Containers, v1.Container{Name: status.Name})
+	}
+	return pod
+}
+
+func running(name, id string) v1.ContainerStatus {
+	return v1.ContainerStatus{
+		Name:        name,
+		ContainerID: id,
+		State:       v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+	}
+}
+
+func waiting(name string) v1.ContainerStatus {
+	return v1.ContainerStatus{
+		Name:  name,
+		State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{}},
+	}
+}
+
+func TestContainerWatcher(t *testing.T) {
+	var tests = []struct {
+		description string
+		pods        []*v1.Pod
+		expected    []string
+	}{
+		{
+			description: "running containers",
+			pods:        []*v1.Pod{podWithContainers("pod", running("app", "id1"), running("sidecar", "id2"))},
+			expected:    []string{"pod/app", "pod/sidecar"},
+		},
+		{
+			description: "waiting container",
+			pods:        []*v1.Pod{podWithContainers("pod", waiting("app"))},
+		},
+		{
+			description: "container started later",
+			pods: []*v1.Pod{
+				podWithContainers("pod", waiting("app")),
+				podWithContainers("pod", running("app", "id1")),
+			},
+			expected: []string{"pod/app"},
+		},
+		{
+			description: "same container seen twice",
+			pods: []*v1.Pod{
+				podWithContainers("pod", running("app", "id1")),
+				podWithContainers("pod", running("app", "id1")),
+			},
+			expected: []string{"pod/app"},
+		},
+		{
+			description: "restarted container",
+			pods: []*v1.Pod{
+				podWithContainers("pod", running("app", "id1")),
+				podWithContainers("pod", running("app", "id2")),
+			},
+			expected: []string{"pod/app", "pod/app"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var (
+				lock sync.Mutex
+				wg   sync.WaitGroup
+				seen []string
+			)
+			wg.Add(len(test.expected))
+
+			w := NewContainerWatcher(NewImageList(), func(_ context.Context, pod *v1.Pod, c v1.Container) {
+				lock.Lock()
+				seen = append(seen, pod.Name+"/"+c.Name)
+				lock.Unlock()
+				wg.Done()
+			})
+			for _, pod := range test.pods {
+				w.checkContainers(context.Background(), pod)
+			}
+			wg.Wait()
+
+			sort.Strings(seen)
+			testutil.CheckDeepEqual(t, test.expected, seen)
+		})
+	}
+}
+
+func TestContainerWatcherWait(t *testing.T) {
+	replay := make(chan struct{})
+	w := NewContainerWatcher(NewImageList(), func(context.Context, *v1.Pod, v1.Container) {
+		<-replay
+	})
+
+	waited := make(chan struct{})
+	go func() {
+		w.Wait(context.Background(), "id1")
+		close(waited)
+	}()
+
+	w.checkContainers(context.Background(), podWithContainers("pod", running("app", "id1")))
+	select {
+	case <-waited:
+		t.Fatal("container should be waited for until it's handled")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(replay)
+	<-waited
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w.Wait(ctx, "unknown")
+}
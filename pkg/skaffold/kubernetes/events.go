@@ -0,0 +1,171 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// previousLogLines is the number of lines printed from a container's
+// previous instance, after it restarted.
+var previousLogLines int64 = 20
+
+// watchEvents prints the warning events about the pods whose logs are streamed.
+func (a *LogAggregator) watchEvents(ctx context.Context) {
+	watcher, err := EventWatcher()
+	if err != nil {
+		logrus.Warnf("Unable to watch events: %s", err)
+		return
+	}
+
+	go func() {
+		defer watcher.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case evt, ok := <-watcher.ResultChan():
+				if !ok {
+					return
+				}
+
+				if evt.Type != watch.Added && evt.Type != watch.Modified {
+					continue
+				}
+
+				event, ok := evt.Object.(*v1.Event)
+				if !ok {
+					continue
+				}
+
+				a.printEvent(event)
+			}
+		}
+	}()
+}
+
+// trackPod records a selected pod, so that its events are printed.
+func (a *LogAggregator) trackPod(pod *v1.Pod) {
+	a.pods.Store(pod.Namespace+"/"+pod.Name, pod)
+}
+
+// untrackPod forgets a deleted pod.
+func (a *LogAggregator) untrackPod(pod *v1.Pod) {
+	a.pods.Delete(pod.Namespace + "/" + pod.Name)
+}
+
+// printEvent prints a warning event about a tracked pod. Events that are
+// repeated are printed each time their count increases.
+func (a *LogAggregator) printEvent(event *v1.Event) {
+	if event.Type != v1.EventTypeWarning || event.InvolvedObject.Kind != "Pod" {
+		return
+	}
+	if !event.LastTimestamp.IsZero() && event.LastTimestamp.Time.Before(a.startTime) {
+		return
+	}
+
+	v, found := a.pods.Load(event.InvolvedObject.Namespace + "/" + event.InvolvedObject.Name)
+	if !found {
+		return
+	}
+	pod := v.(*v1.Pod)
+
+	count := event.Count
+	if count == 0 {
+		count = 1
+	}
+	if previous, found := a.eventCounts.Load(event.UID); found && previous.(int32) >= count {
+		return
+	}
+	a.eventCounts.Store(event.UID, count)
+
+	a.printStatus(pod, fmt.Sprintf("[%s]", pod.Name), fmt.Sprintf("%s %s: %s", event.Type, event.Reason, strings.TrimSpace(event.Message)))
+}
+
+// reportRestarts prints why the containers of a pod were restarted,
+// followed by the last lines logged by their previous instance.
+func (a *LogAggregator) reportRestarts(ctx context.Context, pod *v1.Pod) {
+	for _, container := range a.restartedContainers(pod) {
+		header := prefix(pod, container)
+		if terminated := container.LastTerminationState.Terminated; terminated != nil {
+			a.printStatus(pod, header, fmt.Sprintf("Container restarted (%d restarts), previous instance terminated: %s (exit code %d)", container.RestartCount, terminated.Reason, terminated.ExitCode))
+		} else {
+			a.printStatus(pod, header, fmt.Sprintf("Container restarted (%d restarts)", container.RestartCount))
+		}
+
+		a.printPreviousLogs(ctx, pod, container.Name, header)
+	}
+}
+
+// restartedContainers lists the containers that restarted since the pod was last seen.
+func (a *LogAggregator) restartedContainers(pod *v1.Pod) []v1.ContainerStatus {
+	a.restartsLock.Lock()
+	defer a.restartsLock.Unlock()
+
+	var restarted []v1.ContainerStatus
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, container := range statuses {
+		key := pod.Namespace + "/" + pod.Name + "/" + container.Name
+		previous, found := a.restarts[key]
+		a.restarts[key] = container.RestartCount
+
+		if !found && pod.CreationTimestamp.Time.Before(a.startTime) {
+			// Only report restarts that happened during this session.
+			continue
+		}
+		if container.RestartCount > previous {
+			restarted = append(restarted, container)
+		}
+	}
+
+	return restarted
+}
+
+func (a *LogAggregator) printPreviousLogs(ctx context.Context, pod *v1.Pod, container string, header string) {
+	rc, err := logStream(ctx, pod.Namespace, pod.Name, &v1.PodLogOptions{
+		Container: container,
+		Previous:  true,
+		TailLines: &previousLogLines,
+	})
+	if err != nil {
+		logrus.Debugf("Unable to get previous logs of pod: %s container: %s: %s", pod.Name, container, err)
+		return
+	}
+	defer rc.Close()
+
+	if err := a.streamRequest(ctx, a.colorPicker.Pick(pod), header, rc, &logPosition{}); err != nil {
+		logrus.Debugf("Unable to print previous logs of pod: %s container: %s: %s", pod.Name, container, err)
+	}
+}
+
+// printStatus prints a line about a pod with the pod's color prefix.
+func (a *LogAggregator) printStatus(pod *v1.Pod, header string, message string) {
+	if a.IsMuted() {
+		return
+	}
+
+	a.colorPicker.Pick(pod).Fprintf(a.output, "%s ", header)
+	color.LightYellow.Fprintln(a.output, message)
+}
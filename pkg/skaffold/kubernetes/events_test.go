@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func warning(uid, pod, reason, message string, count int32, timestamp time.Time) *v1.Event {
+	return &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{UID: types.UID(uid)},
+		InvolvedObject: v1.ObjectReference{Kind: "Pod", Namespace: "ns", Name: pod},
+		Type:           v1.EventTypeWarning,
+		Reason:         reason,
+		Message:        message,
+		Count:          count,
+		LastTimestamp:  metav1.NewTime(timestamp),
+	}
+}
+
+func TestPrintEvent(t *testing.T) {
+	var out bytes.Buffer
+	a, _ := NewLogAggregator(&out, NewImageList(), NewColorPicker(nil), config.LogOptions{})
+	a.startTime = time.Now()
+	a.trackPod(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"}})
+
+	later := a.startTime.Add(time.Second)
+	a.printEvent(warning("1", "web", "FailedScheduling", "0/1 nodes are available: 1 Insufficient cpu.", 1, later))
+	a.printEvent(warning("2", "web", "BackOff", "Back-off restarting failed container", 1, later))
+	// Same event, repeated
+	a.printEvent(warning("2", "web", "BackOff", "Back-off restarting failed container", 1, later))
+	a.printEvent(warning("2", "web", "BackOff", "Back-off restarting failed container", 2, later))
+	// Untracked pod
+	a.printEvent(warning("3", "db", "Failed", "ErrImagePull", 1, later))
+	// Old event
+	a.printEvent(warning("4", "web", "Failed", "ErrImagePull", 1, a.startTime.Add(-time.Minute)))
+
+	testutil.CheckDeepEqual(t, "[web] Warning FailedScheduling: 0/1 nodes are available: 1 Insufficient cpu.\n"+
+		"[web] Warning BackOff: Back-off restarting failed container\n"+
+		"[web] Warning BackOff: Back-off restarting failed container\n", out.String())
+}
+
+func TestReportRestarts(t *testing.T) {
+	defer func(s func(context.Context, string, string, *v1.PodLogOptions) (io.ReadCloser, error)) { logStream = s }(logStream)
+	var requests []*v1.PodLogOptions
+	logStream = func(ctx context.Context, namespace, pod string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+		requests = append(requests, opts)
+		return ioutil.NopCloser(strings.NewReader("allocating\nout of memory\n")), nil
+	}
+
+	var out bytes.Buffer
+	a, _ := NewLogAggregator(&out, NewImageList(), NewColorPicker(nil), config.LogOptions{})
+	a.startTime = time.Now()
+
+	pod := func(restarts int32) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns", CreationTimestamp: metav1.NewTime(a.startTime.Add(time.Second))},
+			Status: v1.PodStatus{
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name:         "app",
+					RestartCount: restarts,
+					LastTerminationState: v1.ContainerState{
+						Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137},
+					},
+				}},
+			},
+		}
+	}
+
+	a.reportRestarts(context.Background(), pod(0))
+	a.reportRestarts(context.Background(), pod(1))
+	a.reportRestarts(context.Background(), pod(1))
+
+	testutil.CheckDeepEqual(t, "[web app] Container restarted (1 restarts), previous instance terminated: OOMKilled (exit code 137)\n"+
+		"[web app] allocating\n"+
+		"[web app] out of memory\n", out.String())
+	testutil.CheckDeepEqual(t, 1, len(requests))
+	testutil.CheckDeepEqual(t, true, requests[0].Previous)
+	testutil.CheckDeepEqual(t, "app", requests[0].Container)
+}
@@ -18,18 +18,23 @@ package kubernetes
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"os/exec"
+	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 )
 
@@ -47,10 +52,36 @@ type LogAggregator struct {
 	startTime         time.Time
 	cancel            context.CancelFunc
 	trackedContainers trackedContainers
+	waitForContainer  func(context.Context, string)
+
+	filter     *logFilter
+	json       bool
+	jsonFields []string
+	logFile    string
+	file       io.WriteCloser
+	fileLock   sync.Mutex
+
+	// pods are the selected pods, by namespace/name, whose events are printed.
+	pods sync.Map
+	// restarts are the restart counts of containers, by namespace/pod/container.
+	restarts     map[string]int32
+	restartsLock sync.Mutex
+	// eventCounts are the counts of the events already printed, by uid.
+	eventCounts sync.Map
 }
 
 // NewLogAggregator creates a new LogAggregator for a given output.
-func NewLogAggregator(out io.Writer, podSelector PodSelector, colorPicker ColorPicker) *LogAggregator {
+func NewLogAggregator(out io.Writer, podSelector PodSelector, colorPicker ColorPicker, opts config.LogOptions) (*LogAggregator, error) {
+	filter, err := newLogFilter(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonFields := opts.JSONFields
+	if len(jsonFields) == 0 {
+		jsonFields = DefaultJSONLogFields
+	}
+
 	return &LogAggregator{
 		output:      out,
 		podSelector: podSelector,
@@ -58,7 +89,12 @@ func NewLogAggregator(out io.Writer, podSelector PodSelector, colorPicker ColorP
 		trackedContainers: trackedContainers{
 			ids: map[string]bool{},
 		},
-	}
+		filter:     filter,
+		json:       opts.JSON,
+		jsonFields: jsonFields,
+		logFile:    opts.File,
+		restarts:   map[string]int32{},
+	}, nil
 }
 
 // Start starts a logger that listens to pods and tail their logs
@@ -68,13 +104,24 @@ func (a *LogAggregator) Start(ctx context.Context) error {
 	a.cancel = cancel
 	a.startTime = time.Now()
 
+	if a.logFile != "" {
+		f, err := os.OpenFile(a.logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return errors.Wrap(err, "opening log file")
+		}
+		a.file = f
+	}
+
 	watcher, err := PodWatcher()
 	if err != nil {
 		return errors.Wrap(err, "initializing pod watcher")
 	}
 
+	a.watchEvents(cancelCtx)
+
 	go func() {
 		defer watcher.Stop()
+		defer a.closeFile()
 
 		for {
 			select {
@@ -85,16 +132,23 @@ func (a *LogAggregator) Start(ctx context.Context) error {
 					return
 				}
 
-				if evt.Type != watch.Added && evt.Type != watch.Modified {
+				pod, ok := evt.Object.(*v1.Pod)
+				if !ok {
 					continue
 				}
 
-				pod, ok := evt.Object.(*v1.Pod)
-				if !ok {
+				if evt.Type == watch.Deleted {
+					a.untrackPod(pod)
+					continue
+				}
+
+				if evt.Type != watch.Added && evt.Type != watch.Modified {
 					continue
 				}
 
 				if a.podSelector.Select(pod) {
+					a.trackPod(pod)
+					go a.reportRestarts(cancelCtx, pod)
 					go a.streamLogs(cancelCtx, pod)
 				}
 			}
@@ -104,6 +158,13 @@ func (a *LogAggregator) Start(ctx context.Context) error {
 	return nil
 }
 
+// WaitForContainers registers a function that is called, with the container id,
+// before the logs of a running container are streamed. For example, to wait
+// until the synced files are copied into a new container.
+func (a *LogAggregator) WaitForContainers(fn func(ctx context.Context, containerID string)) {
+	a.waitForContainer = fn
+}
+
 // Stop stops the logger.
 func (a *LogAggregator) Stop() {
 	a.cancel()
@@ -120,9 +181,16 @@ func sinceSeconds(d time.Duration) int64 {
 }
 
 func (a *LogAggregator) streamLogs(ctx context.Context, pod *v1.Pod) {
-	for _, container := range pod.Status.ContainerStatuses {
+	// Init containers are included so that the logs of a pod's startup are not lost.
+	statuses := append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+
+	for i, container := range statuses {
 		containerID := container.ContainerID
-		if containerID == "" || !container.Ready {
+		if containerID == "" || container.State.Waiting != nil {
+			continue
+		}
+
+		if !a.filter.selectContainer(pod.Name, container.Name) {
 			continue
 		}
 
@@ -133,25 +201,133 @@ func (a *LogAggregator) streamLogs(ctx context.Context, pod *v1.Pod) {
 
 		logrus.Infof("Stream logs from pod: %s container: %s", pod.Name, container.Name)
 
-		// In theory, it's more precise to use --since-time='' but there can be a time
-		// difference between the user's machine and the server.
-		// So we use --since=Xs and round up to the nearest second to not lose any log.
-		sinceSeconds := fmt.Sprintf("--since=%ds", sinceSeconds(time.Since(a.startTime)))
-
-		tr, tw := io.Pipe()
-		cmd := exec.CommandContext(ctx, "kubectl", "logs", sinceSeconds, "-f", pod.Name, "-c", container.Name, "--namespace", pod.Namespace)
-		cmd.Stdout = tw
-		go cmd.Run()
-
 		color := a.colorPicker.Pick(pod)
 		prefix := prefix(pod, container)
-		go func() {
-			if err := a.streamRequest(ctx, color, prefix, tr); err != nil {
-				logrus.Errorf("streaming request %s", err)
+		wait := a.waitForContainer != nil && container.State.Running != nil && i >= len(pod.Status.InitContainerStatuses)
+		go func(containerName, containerID string) {
+			if wait {
+				a.waitForContainer(ctx, containerID)
 			}
-			a.trackedContainers.remove(containerID)
-		}()
+
+			// Containers are tracked until their logs are fully streamed. Ids change when containers restart.
+			if err := a.tailContainer(ctx, pod, containerName, containerID, color, prefix); err != nil {
+				logrus.Warnf("Unable to stream logs from pod: %s container: %s: %s", pod.Name, containerName, err)
+				a.trackedContainers.remove(containerID)
+			}
+		}(container.Name, containerID)
+	}
+}
+
+// tailContainer streams the logs of a container until it terminates or the context is cancelled.
+// When the stream fails, or ends while the container is still running, it reconnects without
+// printing the same lines twice.
+func (a *LogAggregator) tailContainer(ctx context.Context, pod *v1.Pod, container, containerID string, headerColor color.Color, header string) error {
+	var position logPosition
+
+	for retries := 0; ; retries++ {
+		opts := &v1.PodLogOptions{
+			Container:  container,
+			Follow:     true,
+			Timestamps: true,
+		}
+		if position.timestamp.IsZero() {
+			// In theory, it's more precise to use SinceTime but there can be a time
+			// difference between the user's machine and the server.
+			// So we use SinceSeconds and round up to the nearest second to not lose any log.
+			since := sinceSeconds(time.Since(a.startTime))
+			opts.SinceSeconds = &since
+		} else {
+			// Reconnect from the timestamp of the last line, as given by the server.
+			opts.SinceTime = &metav1.Time{Time: position.timestamp}
+		}
+
+		rc, err := logStream(ctx, pod.Namespace, pod.Name, opts)
+		if err == nil {
+			before := position
+			position.reconnect()
+			err = a.streamRequest(ctx, headerColor, header, rc, &position)
+			rc.Close()
+			if err == nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				err = streamEnded(pod, containerID)
+				if err == nil {
+					logrus.Infof("%s exited", header)
+					return nil
+				}
+			}
+			if position.timestamp != before.timestamp || position.count != before.count {
+				retries = 0
+			}
+		}
+
+		if retries >= maxLogStreamRetries {
+			return err
+		}
+		logrus.Debugf("Reconnecting logs from pod: %s container: %s: %s", pod.Name, container, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logStreamRetryDelay):
+		}
+	}
+}
+
+// streamEnded is called when the log stream of a container has ended. It returns nil if the container
+// has terminated, or an error if the stream was closed while the container is running.
+func streamEnded(pod *v1.Pod, containerID string) error {
+	terminated, err := containerTerminated(pod.Namespace, pod.Name, containerID)
+	if err != nil {
+		return errors.Wrap(err, "checking container state")
+	}
+	if !terminated {
+		return errors.New("log stream closed while the container is running")
 	}
+	return nil
+}
+
+// isContainerTerminated checks whether a container has terminated. A container that was
+// restarted, or whose pod was deleted, is terminated.
+func isContainerTerminated(namespace, podName, containerID string) (bool, error) {
+	client, err := Client()
+	if err != nil {
+		return false, errors.Wrap(err, "getting k8s client")
+	}
+
+	pod, err := client.CoreV1().Pods(namespace).Get(podName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting pod %s", podName)
+	}
+
+	for _, status := range append(append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if status.ContainerID == containerID {
+			return status.State.Terminated != nil, nil
+		}
+	}
+	return true, nil
+}
+
+var (
+	// logStream and containerTerminated are overridden for unit testing
+	logStream           = streamPodLogs
+	containerTerminated = isContainerTerminated
+
+	logStreamRetryDelay = time.Second
+	maxLogStreamRetries = 5
+)
+
+func streamPodLogs(ctx context.Context, namespace, pod string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+	client, err := Client()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting k8s client")
+	}
+
+	return client.CoreV1().Pods(namespace).GetLogs(pod, opts).Context(ctx).Stream()
 }
 
 func prefix(pod *v1.Pod, container v1.ContainerStatus) string {
@@ -161,7 +337,7 @@ func prefix(pod *v1.Pod, container v1.ContainerStatus) string {
 	return fmt.Sprintf("[%s]", container.Name)
 }
 
-func (a *LogAggregator) streamRequest(ctx context.Context, headerColor color.Color, header string, rc io.Reader) error {
+func (a *LogAggregator) streamRequest(ctx context.Context, headerColor color.Color, header string, rc io.Reader, position *logPosition) error {
 	r := bufio.NewReader(rc)
 	for {
 		select {
@@ -174,12 +350,27 @@ func (a *LogAggregator) streamRequest(ctx context.Context, headerColor color.Col
 		// Read up to newline
 		line, err := r.ReadBytes('\n')
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
 			return errors.Wrap(err, "reading bytes from log stream")
 		}
 
+		timestamp, line := splitTimestamp(line)
+		if !timestamp.IsZero() && !position.isNew(timestamp) {
+			continue
+		}
+
+		text := string(line)
+		if !a.filter.selectLine(strings.TrimSuffix(text, "\n")) {
+			continue
+		}
+
+		// The log file receives the lines even while the output is muted.
+		if err := a.writeToFile(timestamp, header, text); err != nil {
+			return errors.Wrap(err, "writing pod log to file")
+		}
+
 		if a.IsMuted() {
 			continue
 		}
@@ -187,12 +378,95 @@ func (a *LogAggregator) streamRequest(ctx context.Context, headerColor color.Col
 		if _, err := headerColor.Fprintf(a.output, "%s ", header); err != nil {
 			return errors.Wrap(err, "writing pod prefix header to out")
 		}
-		if _, err := fmt.Fprint(a.output, string(line)); err != nil {
+		if a.json {
+			formatted, err := formatJSONLine(a.output, strings.TrimSpace(text), a.jsonFields)
+			if err != nil {
+				return errors.Wrap(err, "writing pod log to out")
+			}
+			if formatted {
+				continue
+			}
+		}
+		if _, err := fmt.Fprint(a.output, text); err != nil {
 			return errors.Wrap(err, "writing pod log to out")
 		}
 	}
-	logrus.Infof("%s exited", header)
-	return nil
+}
+
+// writeToFile writes a prefixed, timestamped line to the log file, if any.
+func (a *LogAggregator) writeToFile(timestamp time.Time, header, line string) error {
+	a.fileLock.Lock()
+	defer a.fileLock.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	_, err := fmt.Fprintf(a.file, "%s %s %s\n", timestamp.Format(time.RFC3339Nano), header, strings.TrimSuffix(line, "\n"))
+	return err
+}
+
+func (a *LogAggregator) closeFile() {
+	a.fileLock.Lock()
+	defer a.fileLock.Unlock()
+
+	if a.file != nil {
+		a.file.Close()
+		a.file = nil
+	}
+}
+
+// splitTimestamp separates the timestamp added by the server from a log line.
+func splitTimestamp(line []byte) (time.Time, []byte) {
+	i := bytes.IndexByte(line, ' ')
+	if i < 0 {
+		return time.Time{}, line
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, string(line[:i]))
+	if err != nil {
+		return time.Time{}, line
+	}
+
+	return timestamp, line[i+1:]
+}
+
+// logPosition tracks the last log line printed for a container, so that
+// reconnecting to the log stream doesn't print the same lines twice.
+type logPosition struct {
+	timestamp time.Time
+	// count is the number of lines printed with the last timestamp.
+	count int
+	// seen is the number of lines with the last timestamp read from the current stream.
+	seen int
+}
+
+// reconnect is called when a new stream is started.
+func (p *logPosition) reconnect() {
+	p.seen = 0
+}
+
+// isNew says if a line with the given timestamp was not printed yet, and records it.
+func (p *logPosition) isNew(timestamp time.Time) bool {
+	switch {
+	case timestamp.Before(p.timestamp):
+		return false
+	case timestamp.Equal(p.timestamp):
+		p.seen++
+		if p.seen <= p.count {
+			return false
+		}
+		p.count++
+		return true
+	default:
+		p.timestamp = timestamp
+		p.count = 1
+		p.seen = 1
+		return true
+	}
 }
 
 // Mute mutes the logs.
@@ -0,0 +1,175 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/pkg/errors"
+)
+
+// logFilter selects the containers and the log lines that are printed.
+type logFilter struct {
+	includePods       []*regexp.Regexp
+	excludePods       []*regexp.Regexp
+	includeContainers []*regexp.Regexp
+	excludeContainers []*regexp.Regexp
+	includeLines      []*regexp.Regexp
+	excludeLines      []*regexp.Regexp
+}
+
+func newLogFilter(opts config.LogOptions) (*logFilter, error) {
+	f := &logFilter{}
+
+	for _, r := range []struct {
+		name        string
+		expressions []string
+		compiled    *[]*regexp.Regexp
+	}{
+		{"pod", opts.IncludePods, &f.includePods},
+		{"pod", opts.ExcludePods, &f.excludePods},
+		{"container", opts.IncludeContainers, &f.includeContainers},
+		{"container", opts.ExcludeContainers, &f.excludeContainers},
+		{"line", opts.IncludeLines, &f.includeLines},
+		{"line", opts.ExcludeLines, &f.excludeLines},
+	} {
+		for _, expression := range r.expressions {
+			re, err := regexp.Compile(expression)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid %s log filter %s", r.name, expression)
+			}
+			*r.compiled = append(*r.compiled, re)
+		}
+	}
+
+	return f, nil
+}
+
+// selectContainer says if the logs of a container should be printed.
+func (f *logFilter) selectContainer(pod, container string) bool {
+	return selected(pod, f.includePods, f.excludePods) && selected(container, f.includeContainers, f.excludeContainers)
+}
+
+// selectLine says if a log line should be printed.
+func (f *logFilter) selectLine(line string) bool {
+	return selected(line, f.includeLines, f.excludeLines)
+}
+
+// selected returns true if the value matches at least one of the includes, if any,
+// and none of the excludes.
+func selected(value string, includes, excludes []*regexp.Regexp) bool {
+	for _, exclude := range excludes {
+		if exclude.MatchString(value) {
+			return false
+		}
+	}
+
+	if len(includes) == 0 {
+		return true
+	}
+	for _, include := range includes {
+		if include.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultJSONLogFields are the fields of JSON log lines printed by default.
+var DefaultJSONLogFields = []string{"level", "message"}
+
+// fieldAliases are alternative names commonly used by logging libraries.
+var fieldAliases = map[string][]string{
+	"level":   {"level", "severity", "lvl"},
+	"message": {"message", "msg"},
+}
+
+// formatJSONLine writes the chosen fields of a JSON log line, coloring the level.
+// It returns false if the line is not a JSON object.
+func formatJSONLine(out io.Writer, line string, fields []string) (bool, error) {
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		return false, nil
+	}
+
+	separator := ""
+	for _, field := range fields {
+		value, found := lookupField(entry, field)
+		if !found {
+			continue
+		}
+
+		if _, err := fmt.Fprint(out, separator); err != nil {
+			return true, err
+		}
+		separator = " "
+
+		var err error
+		switch field {
+		case "level":
+			level := strings.ToUpper(value)
+			_, err = levelColor(level).Fprint(out, level)
+		case "message":
+			_, err = fmt.Fprint(out, value)
+		default:
+			_, err = fmt.Fprintf(out, "%s=%s", field, value)
+		}
+		if err != nil {
+			return true, err
+		}
+	}
+
+	_, err := fmt.Fprintln(out)
+	return true, err
+}
+
+func lookupField(entry map[string]interface{}, field string) (string, bool) {
+	names, found := fieldAliases[field]
+	if !found {
+		names = []string{field}
+	}
+
+	for _, name := range names {
+		if value, found := entry[name]; found {
+			if s, ok := value.(string); ok {
+				return s, true
+			}
+			encoded, _ := json.Marshal(value)
+			return string(encoded), true
+		}
+	}
+	return "", false
+}
+
+func levelColor(level string) color.Color {
+	switch level {
+	case "ERROR", "FATAL", "PANIC", "CRITICAL":
+		return color.Red
+	case "WARN", "WARNING":
+		return color.Yellow
+	case "INFO":
+		return color.Green
+	default:
+		return color.None
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestLogFilter(t *testing.T) {
+	filter, err := newLogFilter(config.LogOptions{
+		IncludePods:       []string{"^web-", "^api-"},
+		ExcludePods:       []string{"-canary-"},
+		ExcludeContainers: []string{"^istio-proxy$"},
+		IncludeLines:      []string{"(?i)error", "warn"},
+		ExcludeLines:      []string{"healthz"},
+	})
+	testutil.CheckError(t, false, err)
+
+	var tests = []struct {
+		pod, container string
+		expected       bool
+	}{
+		{"web-1234", "web", true},
+		{"api-1234", "api", true},
+		{"db-1234", "db", false},
+		{"web-canary-1234", "web", false},
+		{"web-1234", "istio-proxy", false},
+	}
+	for _, test := range tests {
+		testutil.CheckDeepEqual(t, test.expected, filter.selectContainer(test.pod, test.container))
+	}
+
+	testutil.CheckDeepEqual(t, true, filter.selectLine("ERROR: boom"))
+	testutil.CheckDeepEqual(t, true, filter.selectLine("warn: disk is full"))
+	testutil.CheckDeepEqual(t, false, filter.selectLine("info: started"))
+	testutil.CheckDeepEqual(t, false, filter.selectLine("error on /healthz"))
+}
+
+func TestInvalidLogFilter(t *testing.T) {
+	_, err := newLogFilter(config.LogOptions{ExcludeLines: []string{"("}})
+
+	testutil.CheckError(t, true, err)
+}
+
+func TestFormatJSONLine(t *testing.T) {
+	var tests = []struct {
+		description string
+		line        string
+		fields      []string
+		expected    string
+		isJSON      bool
+	}{
+		{
+			description: "default fields",
+			line:        `{"level":"info","message":"started","port":8080}`,
+			fields:      DefaultJSONLogFields,
+			expected:    "INFO started\n",
+			isJSON:      true,
+		},
+		{
+			description: "aliases",
+			line:        `{"severity":"warning","msg":"slow request"}`,
+			fields:      DefaultJSONLogFields,
+			expected:    "WARNING slow request\n",
+			isJSON:      true,
+		},
+		{
+			description: "additional fields",
+			line:        `{"level":"error","message":"failed","port":8080,"user":"bob"}`,
+			fields:      []string{"level", "message", "port", "user", "missing"},
+			expected:    "ERROR failed port=8080 user=bob\n",
+			isJSON:      true,
+		},
+		{
+			description: "not json",
+			line:        "plain text",
+			fields:      DefaultJSONLogFields,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var out bytes.Buffer
+
+			isJSON, err := formatJSONLine(&out, test.line, test.fields)
+
+			testutil.CheckError(t, false, err)
+			testutil.CheckDeepEqual(t, test.isJSON, isJSON)
+			testutil.CheckDeepEqual(t, test.expected, out.String())
+		})
+	}
+}
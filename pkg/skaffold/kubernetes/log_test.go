@@ -17,8 +17,22 @@ limitations under the License.
 package kubernetes
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestSinceSeconds(t *testing.T) {
@@ -49,3 +63,168 @@ func TestSinceSeconds(t *testing.T) {
 		})
 	}
 }
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestTailContainerReconnects(t *testing.T) {
+	defer func(s func(context.Context, string, string, *v1.PodLogOptions) (io.ReadCloser, error), c func(string, string, string) (bool, error), d time.Duration) {
+		logStream = s
+		containerTerminated = c
+		logStreamRetryDelay = d
+	}(logStream, containerTerminated, logStreamRetryDelay)
+	logStreamRetryDelay = 0
+	containerTerminated = func(string, string, string) (bool, error) { return true, nil }
+
+	var requests []*v1.PodLogOptions
+	logStream = func(ctx context.Context, namespace, pod string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+		requests = append(requests, opts)
+		switch len(requests) {
+		case 1:
+			return ioutil.NopCloser(io.MultiReader(strings.NewReader(
+				"2018-10-01T10:00:00.1Z first\n"+
+					"2018-10-01T10:00:01.5Z second\n"+
+					"2018-10-01T10:00:01.5Z third\n"), failingReader{})), nil
+		case 2:
+			return nil, errors.New("unable to connect")
+		default:
+			return ioutil.NopCloser(strings.NewReader(
+				"2018-10-01T10:00:01.5Z second\n" +
+					"2018-10-01T10:00:01.5Z third\n" +
+					"2018-10-01T10:00:01.5Z fourth\n" +
+					"2018-10-01T10:00:02Z fifth\n")), nil
+		}
+	}
+
+	var out bytes.Buffer
+	a, _ := NewLogAggregator(&out, NewImageList(), NewColorPicker(nil), config.LogOptions{})
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+
+	err := a.tailContainer(context.Background(), pod, "app", "docker://app", color.None, "[app]")
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, "[app] first\n[app] second\n[app] third\n[app] fourth\n[app] fifth\n", out.String())
+	testutil.CheckDeepEqual(t, 3, len(requests))
+	testutil.CheckDeepEqual(t, true, requests[0].SinceSeconds != nil && requests[0].Timestamps && requests[0].Follow)
+	testutil.CheckDeepEqual(t, "2018-10-01T10:00:01.5Z", requests[2].SinceTime.Format(time.RFC3339Nano))
+}
+
+func TestTailContainerReconnectsWhenStreamEnds(t *testing.T) {
+	defer func(s func(context.Context, string, string, *v1.PodLogOptions) (io.ReadCloser, error), c func(string, string, string) (bool, error), d time.Duration) {
+		logStream = s
+		containerTerminated = c
+		logStreamRetryDelay = d
+	}(logStream, containerTerminated, logStreamRetryDelay)
+	logStreamRetryDelay = 0
+
+	var requests []*v1.PodLogOptions
+	logStream = func(ctx context.Context, namespace, pod string, opts *v1.PodLogOptions) (io.ReadCloser, error) {
+		requests = append(requests, opts)
+		if len(requests) == 1 {
+			return ioutil.NopCloser(strings.NewReader("2018-10-01T10:00:00.1Z first\n")), nil
+		}
+		return ioutil.NopCloser(strings.NewReader("2018-10-01T10:00:00.1Z first\n2018-10-01T10:00:01Z second\n")), nil
+	}
+	var checks []string
+	containerTerminated = func(namespace, pod, containerID string) (bool, error) {
+		checks = append(checks, namespace+"/"+pod+"/"+containerID)
+		// The first stream is closed while the container is running
+		return len(checks) > 1, nil
+	}
+
+	var out bytes.Buffer
+	a, _ := NewLogAggregator(&out, NewImageList(), NewColorPicker(nil), config.LogOptions{})
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}}
+
+	err := a.tailContainer(context.Background(), pod, "app", "docker://app", color.None, "[app]")
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, "[app] first\n[app] second\n", out.String())
+	testutil.CheckDeepEqual(t, []string{"ns/pod/docker://app", "ns/pod/docker://app"}, checks)
+	testutil.CheckDeepEqual(t, 2, len(requests))
+	testutil.CheckDeepEqual(t, "2018-10-01T10:00:00.1Z", requests[1].SinceTime.Format(time.RFC3339Nano))
+}
+
+func TestContainerTerminated(t *testing.T) {
+	defer func(c func() (kubernetes.Interface, error)) { Client = c }(Client)
+	Client = func() (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"},
+			Status: v1.PodStatus{
+				InitContainerStatuses: []v1.ContainerStatus{
+					{ContainerID: "docker://init", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}}},
+				},
+				ContainerStatuses: []v1.ContainerStatus{
+					{ContainerID: "docker://app", State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}},
+				},
+			},
+		}), nil
+	}
+
+	var tests = []struct {
+		description string
+		pod         string
+		containerID string
+		expected    bool
+	}{
+		{description: "running container", pod: "pod", containerID: "docker://app"},
+		{description: "terminated init container", pod: "pod", containerID: "docker://init", expected: true},
+		{description: "restarted container", pod: "pod", containerID: "docker://old", expected: true},
+		{description: "deleted pod", pod: "other", containerID: "docker://app", expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			terminated, err := isContainerTerminated("ns", test.pod, test.containerID)
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, terminated)
+		})
+	}
+}
+
+func TestTailContainerGivesUp(t *testing.T) {
+	defer func(s func(context.Context, string, string, *v1.PodLogOptions) (io.ReadCloser, error), d time.Duration) {
+		logStream = s
+		logStreamRetryDelay = d
+	}(logStream, logStreamRetryDelay)
+	logStreamRetryDelay = 0
+
+	attempts := 0
+	logStream = func(context.Context, string, string, *v1.PodLogOptions) (io.ReadCloser, error) {
+		attempts++
+		return nil, errors.New("unable to connect")
+	}
+
+	a, _ := NewLogAggregator(ioutil.Discard, NewImageList(), NewColorPicker(nil), config.LogOptions{})
+	err := a.tailContainer(context.Background(), &v1.Pod{}, "app", "docker://app", color.None, "[app]")
+
+	testutil.CheckError(t, true, err)
+	testutil.CheckDeepEqual(t, maxLogStreamRetries+1, attempts)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestStreamRequestToLogFile(t *testing.T) {
+	var out, file bytes.Buffer
+	a, err := NewLogAggregator(&out, NewImageList(), NewColorPicker(nil), config.LogOptions{
+		ExcludeLines: []string{"debug"},
+		JSON:         true,
+	})
+	testutil.CheckError(t, false, err)
+	a.file = nopWriteCloser{&file}
+
+	logs := "2018-10-01T10:00:00Z {\"level\":\"info\",\"message\":\"started\"}\n" +
+		"2018-10-01T10:00:01Z debug line\n" +
+		"2018-10-01T10:00:02Z plain line\n"
+	err = a.streamRequest(context.Background(), color.None, "[app]", strings.NewReader(logs), &logPosition{})
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, "[app] INFO started\n[app] plain line\n", out.String())
+	testutil.CheckDeepEqual(t, "2018-10-01T10:00:00Z [app] {\"level\":\"info\",\"message\":\"started\"}\n2018-10-01T10:00:02Z [app] plain line\n", file.String())
+}
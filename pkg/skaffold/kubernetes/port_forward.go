@@ -17,23 +17,32 @@ limitations under the License.
 package kubernetes
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
-	"os/exec"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"strconv"
+	"strings"
 	"sync"
-	"syscall"
+	"time"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
-	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
 )
 
 // PortForwarder is responsible for selecting pods satisfying a certain condition and port-forwarding the exposed
@@ -43,11 +52,16 @@ type PortForwarder struct {
 
 	output      io.Writer
 	podSelector PodSelector
+	resources   []*latest.PortForwardResource
 
 	// forwardedPods is a map of portForwardEntry.key() (string) -> portForwardEntry
 	forwardedPods *sync.Map
 
-	// forwardedPorts is a map of port (int32) -> container name (string)
+	// forwardedResources is a map of portForwardEntry.key() (string) -> portForwardEntry
+	// for user-defined resources
+	forwardedResources *sync.Map
+
+	// forwardedPorts is a map of local port (int32) -> container or resource name (string)
 	forwardedPorts *sync.Map
 }
 
@@ -58,66 +72,291 @@ type portForwardEntry struct {
 	containerName   string
 	port            int32
 
-	cmd *exec.Cmd
+	// resource is set, instead of podName, for user-defined resources, like `service/web`.
+	resource  string
+	localPort int32
+
+	// lock guards stopChan, which is closed to stop a running forward.
+	lock     sync.Mutex
+	stopChan chan struct{}
 }
 
+var (
+	// retryForwardDelay is the delay before a user-defined port forward is restarted.
+	retryForwardDelay = time.Second
+
+	// reconnectBackoff is the initial delay before a failed port forward is reconnected,
+	// or before a user-defined resource is forwarded again when none of its pods is running.
+	// It doubles after each failure, up to maxReconnectBackoff.
+	reconnectBackoff    = time.Second
+	maxReconnectBackoff = 30 * time.Second
+
+	// isPortFree is overridden for unit testing
+	isPortFree = isLocalPortFree
+)
+
 // Forwarder is an interface that can modify and manage port-forward processes
 type Forwarder interface {
 	Forward(*portForwardEntry) error
 	Stop(*portForwardEntry) error
 }
 
-type kubectlForwarder struct{}
+// errPodGone is returned when the pod to forward doesn't exist or isn't running anymore.
+var errPodGone = errors.New("pod is gone")
+
+type clientForwarder struct{}
+
+// Forward port-forwards a pod with the Kubernetes API, until the forward is stopped.
+// When the connection drops, it reconnects with an exponential backoff, unless the pod is gone.
+func (f *clientForwarder) Forward(pfe *portForwardEntry) error {
+	stop, started := pfe.start()
+	if !started {
+		return nil
+	}
+	defer pfe.done(stop)
+
+	backoff := reconnectBackoff
+	for {
+		err := f.forward(pfe, stop)
+		if isClosed(stop) {
+			return nil
+		}
+		if errors.Cause(err) == errPodGone {
+			return errors.Wrapf(err, "port forwarding %s", pfe)
+		}
+		if err == nil {
+			err = fmt.Errorf("lost connection for %s", pfe)
+		}
+
+		logrus.Warnf("%s, reconnecting in %s", err, backoff)
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(backoff):
+		}
 
-// Forward port-forwards a pod using kubectl port-forward
-// It returns an error only if the process fails or was terminated by a signal other than SIGTERM
-func (*kubectlForwarder) Forward(pfe *portForwardEntry) error {
+		backoff *= 2
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+	}
+}
+
+// forward port-forwards a running pod once. It returns when the forward is stopped
+// or when the connection to the pod is lost.
+func (*clientForwarder) forward(pfe *portForwardEntry, stop chan struct{}) error {
 	logrus.Debugf("Port forwarding %s", pfe)
-	portNumber := fmt.Sprintf("%d", pfe.port)
-	cmd := exec.Command("kubectl", "port-forward", pfe.podName, portNumber, portNumber, "--namespace", pfe.namespace)
-	pfe.cmd = cmd
 
-	buf := &bytes.Buffer{}
-	cmd.Stdout = buf
-	cmd.Stderr = buf
+	client, err := Client()
+	if err != nil {
+		return errors.Wrap(err, "getting k8s client")
+	}
+	config, err := getClientConfig()
+	if err != nil {
+		return errors.Wrap(err, "getting client config")
+	}
 
-	if err := cmd.Run(); err != nil && !util.IsTerminatedError(err) {
-		return errors.Wrapf(err, "port forwarding pod: %s/%s, port: %s, err: %s", pfe.namespace, pfe.podName, portNumber, buf.String())
+	namespace := pfe.namespace
+	if namespace == "" {
+		if namespace, err = currentNamespace(); err != nil {
+			return errors.Wrap(err, "getting current namespace")
+		}
 	}
-	return nil
+
+	pod, err := runningPod(client, namespace, pfe)
+	if err != nil {
+		return err
+	}
+	port, err := containerPort(client, namespace, pfe, pod)
+	if err != nil {
+		return err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return errors.Wrap(err, "creating round tripper")
+	}
+	url := client.CoreV1().RESTClient().Post().Resource("pods").Namespace(namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	ports := []string{fmt.Sprintf("%d:%d", pfe.localPort, port)}
+	forwarder, err := portforward.New(dialer, ports, stop, make(chan struct{}), ioutil.Discard, ioutil.Discard)
+	if err != nil {
+		return errors.Wrapf(err, "port forwarding %s", pfe)
+	}
+
+	return forwarder.ForwardPorts()
 }
 
-// Stop terminates an existing kubectl port-forward command using SIGTERM
-func (*kubectlForwarder) Stop(p *portForwardEntry) error {
-	logrus.Debugf("Terminating port-forward %s", p)
-	if p.cmd == nil {
-		return fmt.Errorf("no port-forward command found for %s", p)
+// Stop stops a port forward. If the forward hasn't started yet, it won't start.
+func (*clientForwarder) Stop(pfe *portForwardEntry) error {
+	logrus.Debugf("Terminating port-forward %s", pfe)
+
+	pfe.lock.Lock()
+	defer pfe.lock.Unlock()
+
+	if pfe.stopChan == nil {
+		pfe.stopChan = make(chan struct{})
 	}
-	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
-		return errors.Wrap(err, "terminating port-forward process")
+	if !isClosed(pfe.stopChan) {
+		close(pfe.stopChan)
 	}
 	return nil
 }
 
-// NewPortForwarder returns a struct that tracks and port-forwards pods as they are created and modified
-func NewPortForwarder(out io.Writer, podSelector PodSelector) *PortForwarder {
-	return &PortForwarder{
-		Forwarder:      &kubectlForwarder{},
-		output:         out,
-		podSelector:    podSelector,
-		forwardedPods:  &sync.Map{},
-		forwardedPorts: &sync.Map{},
+// start returns the channel that stops a new forward. It returns false
+// if the forward was stopped before it started, in which case it can be
+// started again.
+func (p *portForwardEntry) start() (chan struct{}, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.stopChan != nil && isClosed(p.stopChan) {
+		p.stopChan = nil
+		return nil, false
 	}
+	p.stopChan = make(chan struct{})
+	return p.stopChan, true
 }
 
-func (p *PortForwarder) cleanupPorts() {
-	p.forwardedPods.Range(func(k, v interface{}) bool {
-		entry := v.(*portForwardEntry)
-		if err := p.Stop(entry); err != nil {
-			logrus.Warnf("cleaning up port forwards: %s", err)
-		}
+// done is called when a forward has returned, so that the entry can be forwarded again.
+func (p *portForwardEntry) done(stop chan struct{}) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.stopChan == stop {
+		p.stopChan = nil
+	}
+}
+
+func isClosed(c chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
 		return false
+	}
+}
+
+// runningPod returns the running pod to forward. For user-defined resources,
+// like `service/web`, it's one of the pods selected by the resource.
+func runningPod(client kubernetes.Interface, namespace string, pfe *portForwardEntry) (*v1.Pod, error) {
+	name := pfe.podName
+	if pfe.resource != "" {
+		kind, resourceName, err := splitResource(pfe.resource)
+		if err != nil {
+			return nil, err
+		}
+		if kind != "pod" {
+			return selectedPod(client, namespace, kind, resourceName)
+		}
+		name = resourceName
+	}
+
+	pod, err := client.CoreV1().Pods(namespace).Get(name, meta_v1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, errPodGone
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting pod %s", name)
+	}
+	if !isRunning(pod) {
+		return nil, errPodGone
+	}
+	return pod, nil
+}
+
+// selectedPod returns a running pod selected by a service or a deployment.
+func selectedPod(client kubernetes.Interface, namespace, kind, name string) (*v1.Pod, error) {
+	selector, err := resourceSelector(client, namespace, kind, name)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := client.CoreV1().Pods(namespace).List(meta_v1.ListOptions{
+		LabelSelector: selector.String(),
 	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "listing pods of %s/%s", kind, name)
+	}
+	for i := range pods.Items {
+		if isRunning(&pods.Items[i]) {
+			return &pods.Items[i], nil
+		}
+	}
+	return nil, errPodGone
+}
+
+// containerPort returns the port of the pod to forward. For services, like
+// `kubectl port-forward service/...`, the port is a port of the service and
+// the pod's port is its target port, either a number or the name of a container port.
+func containerPort(client kubernetes.Interface, namespace string, pfe *portForwardEntry, pod *v1.Pod) (int32, error) {
+	if pfe.resource == "" {
+		return pfe.port, nil
+	}
+	kind, name, err := splitResource(pfe.resource)
+	if err != nil {
+		return 0, err
+	}
+	if kind != "service" {
+		return pfe.port, nil
+	}
+
+	svc, err := client.CoreV1().Services(namespace).Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		return 0, errors.Wrapf(err, "getting service %s", name)
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Port != pfe.port {
+			continue
+		}
+
+		if port.TargetPort.Type == intstr.Int {
+			if port.TargetPort.IntVal == 0 {
+				return port.Port, nil
+			}
+			return port.TargetPort.IntVal, nil
+		}
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.Name == port.TargetPort.StrVal {
+					return p.ContainerPort, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("pod %s has no port named %s", pod.Name, port.TargetPort.StrVal)
+	}
+	return 0, fmt.Errorf("service %s has no port %d", name, pfe.port)
+}
+
+func isRunning(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodRunning && pod.DeletionTimestamp == nil
+}
+
+// NewPortForwarder returns a struct that tracks and port-forwards pods as they are created and modified,
+// along with user-defined resources.
+func NewPortForwarder(out io.Writer, podSelector PodSelector, resources []*latest.PortForwardResource) *PortForwarder {
+	return &PortForwarder{
+		Forwarder:          &clientForwarder{},
+		output:             out,
+		podSelector:        podSelector,
+		resources:          resources,
+		forwardedPods:      &sync.Map{},
+		forwardedResources: &sync.Map{},
+		forwardedPorts:     &sync.Map{},
+	}
+}
+
+func (p *PortForwarder) cleanupPorts() {
+	for _, forwarded := range []*sync.Map{p.forwardedPods, p.forwardedResources} {
+		forwarded.Range(func(k, v interface{}) bool {
+			entry := v.(*portForwardEntry)
+			if err := p.Stop(entry); err != nil {
+				logrus.Warnf("cleaning up port forwards: %s", err)
+			}
+			return true
+		})
+	}
 }
 
 // Start begins a pod watcher that port forwards any pods involving containers with exposed ports.
@@ -128,6 +367,22 @@ func (p *PortForwarder) Start(ctx context.Context) error {
 		return errors.Wrap(err, "initializing pod watcher")
 	}
 
+	for _, r := range p.resources {
+		entry := &portForwardEntry{
+			resource:  fmt.Sprintf("%s/%s", r.Type, r.Name),
+			namespace: r.Namespace,
+			port:      r.Port,
+		}
+		localPort := r.LocalPort
+		if localPort == 0 {
+			localPort = r.Port
+		}
+		entry.localPort = p.availablePort(localPort, entry.resource)
+		p.forwardedResources.Store(entry.key(), entry)
+
+		go p.forwardResource(ctx, entry)
+	}
+
 	go func() {
 		defer watcher.Stop()
 
@@ -141,14 +396,21 @@ func (p *PortForwarder) Start(ctx context.Context) error {
 					return
 				}
 
-				// Pods will never be "added" in a state that they are ready for port-forwarding
-				// so only watch "modified" events
-				if evt.Type != watch.Modified {
+				pod, ok := evt.Object.(*v1.Pod)
+				if !ok {
 					continue
 				}
 
-				pod, ok := evt.Object.(*v1.Pod)
-				if !ok {
+				// User-defined resources are resolved to a pod when the forward is started.
+				// When a pod goes away, forward them again so that they use a new pod.
+				if evt.Type == watch.Deleted && p.podSelector.Select(pod) {
+					p.restartResources(pod)
+					continue
+				}
+
+				// Pods will never be "added" in a state that they are ready for port-forwarding
+				// so only watch "modified" events
+				if evt.Type != watch.Modified {
 					continue
 				}
 				if p.podSelector.Select(pod) && pod.Status.Phase == v1.PodRunning && pod.DeletionTimestamp == nil {
@@ -165,6 +427,121 @@ func (p *PortForwarder) Start(ctx context.Context) error {
 	return nil
 }
 
+// forwardResource port-forwards a user-defined resource until the context is cancelled.
+// The forward is restarted each time it terminates. When none of the resource's pods
+// is running, it's retried with an exponential backoff.
+func (p *PortForwarder) forwardResource(ctx context.Context, entry *portForwardEntry) {
+	backoff := reconnectBackoff
+	for {
+		color.Default.Fprintln(p.output, fmt.Sprintf("Port Forwarding %s %d -> %d", entry.resource, entry.port, entry.localPort))
+
+		delay := retryForwardDelay
+		switch err := p.Forward(entry); {
+		case err == nil:
+			backoff = reconnectBackoff
+		case errors.Cause(err) == errPodGone:
+			logrus.Debugf("%s, retrying in %s", err, backoff)
+			delay = backoff
+			backoff *= 2
+			if backoff > maxReconnectBackoff {
+				backoff = maxReconnectBackoff
+			}
+		default:
+			logrus.Warnf("port forwarding %s failed: %s", entry, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// restartResources stops the forwards of user-defined resources that select a deleted pod,
+// so that they are started again on another pod.
+func (p *PortForwarder) restartResources(pod *v1.Pod) {
+	p.forwardedResources.Range(func(k, v interface{}) bool {
+		entry := v.(*portForwardEntry)
+		if entry.namespace != "" && entry.namespace != pod.Namespace {
+			return true
+		}
+
+		selected, err := resourceSelectsPod(entry.resource, pod)
+		if err != nil {
+			logrus.Debugf("restarting port forward: %s", err)
+			return true
+		}
+		if selected {
+			if err := p.Stop(entry); err != nil {
+				logrus.Debugf("restarting port forward: %s", err)
+			}
+		}
+		return true
+	})
+}
+
+// resourceSelectsPod checks whether a user-defined resource, like `service/web`,
+// selects a given pod.
+func resourceSelectsPod(resource string, pod *v1.Pod) (bool, error) {
+	kind, name, err := splitResource(resource)
+	if err != nil {
+		return false, err
+	}
+	if kind == "pod" {
+		return name == pod.Name, nil
+	}
+
+	client, err := Client()
+	if err != nil {
+		return false, errors.Wrap(err, "getting k8s client")
+	}
+
+	selector, err := resourceSelector(client, pod.Namespace, kind, name)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(pod.Labels)), nil
+}
+
+// resourceSelector returns the pod selector of a service or a deployment.
+func resourceSelector(client kubernetes.Interface, namespace, kind, name string) (labels.Selector, error) {
+	switch kind {
+	case "service":
+		svc, err := client.CoreV1().Services(namespace).Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting service %s", name)
+		}
+		if len(svc.Spec.Selector) == 0 {
+			return nil, fmt.Errorf("service %s has no selector", name)
+		}
+		return labels.SelectorFromSet(svc.Spec.Selector), nil
+
+	case "deployment":
+		deployment, err := client.AppsV1().Deployments(namespace).Get(name, meta_v1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting deployment %s", name)
+		}
+		selector, err := meta_v1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing selector of deployment %s", name)
+		}
+		return selector, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported resource type %s", kind)
+	}
+}
+
+// splitResource splits a user-defined resource, like `service/web`, into its kind and name.
+func splitResource(resource string) (string, string, error) {
+	parts := strings.SplitN(resource, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid resource %s", resource)
+	}
+	return parts[0], parts[1], nil
+}
+
 func (p *PortForwarder) portForwardPod(pod *v1.Pod) error {
 	resourceVersion, err := strconv.Atoi(pod.ResourceVersion)
 	if err != nil {
@@ -175,14 +552,6 @@ func (p *PortForwarder) portForwardPod(pod *v1.Pod) error {
 
 	for _, c := range pod.Spec.Containers {
 		for _, port := range c.Ports {
-			// If the port is already port-forwarded by another container,
-			// continue without port-forwarding
-			currentApp, ok := p.forwardedPorts.Load(port.ContainerPort)
-			if ok && currentApp != c.Name {
-				color.LightYellow.Fprintf(p.output, "Port %d for %s is already in use by container %s\n", port.ContainerPort, c.Name, currentApp)
-				continue
-			}
-
 			entry := &portForwardEntry{
 				resourceVersion: resourceVersion,
 				podName:         pod.Name,
@@ -200,14 +569,22 @@ func (p *PortForwarder) portForwardPod(pod *v1.Pod) error {
 						return errors.Wrap(err, "terminating port-forward process")
 					}
 				}
+				entry.localPort = prevEntry.localPort
+			} else {
+				// If the port is already port-forwarded by another container,
+				// or used by another process, pick the next free one.
+				entry.localPort = p.availablePort(entry.port, entry.containerName)
 			}
 
-			color.Default.Fprintln(p.output, fmt.Sprintf("Port Forwarding %s %d -> %d", entry.podName, entry.port, entry.port))
+			color.Default.Fprintln(p.output, fmt.Sprintf("Port Forwarding %s %d -> %d", entry.podName, entry.port, entry.localPort))
 			p.forwardedPods.Store(entry.key(), entry)
-			p.forwardedPorts.Store(entry.port, entry.containerName)
 
 			g.Go(func() error {
-				return p.Forward(entry)
+				if err := p.Forward(entry); errors.Cause(err) != errPodGone {
+					return err
+				}
+				logrus.Debugf("Stopped port forwarding %s, the pod is gone", entry)
+				return nil
 			})
 		}
 	}
@@ -219,12 +596,46 @@ func (p *PortForwarder) portForwardPod(pod *v1.Pod) error {
 	return nil
 }
 
+// availablePort returns the first local port, starting from the desired one, that is
+// neither forwarded already nor used by another process. The port is reserved for name.
+func (p *PortForwarder) availablePort(desired int32, name string) int32 {
+	for port := desired; port <= maxPort; port++ {
+		if _, used := p.forwardedPorts.Load(port); used || !isPortFree(port) {
+			continue
+		}
+		if _, used := p.forwardedPorts.LoadOrStore(port, name); !used {
+			return port
+		}
+	}
+
+	logrus.Warnf("No free local port found for %s, using %d", name, desired)
+	return desired
+}
+
+const maxPort = 65535
+
+// isLocalPortFree checks whether a local port can be listened on.
+func isLocalPortFree(port int32) bool {
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return false
+	}
+	l.Close()
+	return true
+}
+
 // Key is an identifier for the lock on a port during the skaffold dev cycle.
 func (p *portForwardEntry) key() string {
+	if p.resource != "" {
+		return fmt.Sprintf("%s-%s-%d", p.namespace, p.resource, p.port)
+	}
 	return fmt.Sprintf("%s-%d", p.containerName, p.port)
 }
 
 // String is a utility function that returns the port forward entry as a user-readable string
 func (p *portForwardEntry) String() string {
+	if p.resource != "" {
+		return fmt.Sprintf("%s:%d", p.resource, p.port)
+	}
 	return fmt.Sprintf("%s/%s:%d", p.podName, p.containerName, p.port)
 }
@@ -17,14 +17,20 @@ limitations under the License.
 package kubernetes
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/GoogleContainerTools/skaffold/testutil"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 type testForwarder struct {
@@ -37,13 +43,13 @@ type testForwarder struct {
 
 func (f *testForwarder) Forward(pfe *portForwardEntry) error {
 	f.forwardedEntries[pfe.key()] = pfe
-	f.forwardedPorts[pfe.port] = true
+	f.forwardedPorts[pfe.localPort] = true
 	return f.forwardErr
 }
 
 func (f *testForwarder) Stop(pfe *portForwardEntry) error {
 	delete(f.forwardedEntries, pfe.key())
-	delete(f.forwardedPorts, pfe.port)
+	delete(f.forwardedPorts, pfe.localPort)
 	return f.stopErr
 }
 
@@ -57,6 +63,9 @@ func newTestForwarder(forwardErr, stopErr error) *testForwarder {
 }
 
 func TestPortForwardPod(t *testing.T) {
+	defer func(f func(int32) bool) { isPortFree = f }(isPortFree)
+	isPortFree = func(int32) bool { return true }
+
 	var tests = []struct {
 		description     string
 		pods            []*v1.Pod
@@ -76,6 +85,7 @@ func TestPortForwardPod(t *testing.T) {
 					podName:         "podname",
 					containerName:   "containername",
 					port:            8080,
+					localPort:       8080,
 				},
 			},
 			pods: []*v1.Pod{
@@ -138,6 +148,7 @@ func TestPortForwardPod(t *testing.T) {
 					podName:         "podname",
 					containerName:   "containername",
 					port:            8080,
+					localPort:       8080,
 				},
 			},
 			pods: []*v1.Pod{
@@ -173,12 +184,14 @@ func TestPortForwardPod(t *testing.T) {
 					podName:         "podname",
 					containerName:   "containername",
 					port:            8080,
+					localPort:       8080,
 				},
 				"containername2-50051": {
 					resourceVersion: 1,
 					podName:         "podname2",
 					containerName:   "containername2",
 					port:            50051,
+					localPort:       50051,
 				},
 			},
 			pods: []*v1.Pod{
@@ -224,6 +237,7 @@ func TestPortForwardPod(t *testing.T) {
 			description: "two same container ports",
 			expectedPorts: map[int32]bool{
 				8080: true,
+				8081: true,
 			},
 			expectedEntries: map[string]*portForwardEntry{
 				"containername-8080": {
@@ -231,6 +245,14 @@ func TestPortForwardPod(t *testing.T) {
 					podName:         "podname",
 					containerName:   "containername",
 					port:            8080,
+					localPort:       8080,
+				},
+				"containername2-8080": {
+					resourceVersion: 1,
+					podName:         "podname2",
+					containerName:   "containername2",
+					port:            8080,
+					localPort:       8081,
 				},
 			},
 			pods: []*v1.Pod{
@@ -283,6 +305,7 @@ func TestPortForwardPod(t *testing.T) {
 					podName:         "podname",
 					containerName:   "containername",
 					port:            8080,
+					localPort:       8080,
 				},
 			},
 			pods: []*v1.Pod{
@@ -328,7 +351,7 @@ func TestPortForwardPod(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
-			p := NewPortForwarder(ioutil.Discard, NewImageList())
+			p := NewPortForwarder(ioutil.Discard, NewImageList(), nil)
 			if test.forwarder == nil {
 				test.forwarder = newTestForwarder(nil, nil)
 			}
@@ -349,3 +372,216 @@ func TestPortForwardPod(t *testing.T) {
 		})
 	}
 }
+
+type resourceForwarder struct {
+	forwarded chan string
+	stopped   chan bool
+}
+
+func (f *resourceForwarder) Forward(pfe *portForwardEntry) error {
+	f.forwarded <- pfe.String()
+	<-f.stopped
+	return nil
+}
+
+func (f *resourceForwarder) Stop(pfe *portForwardEntry) error {
+	f.stopped <- true
+	return nil
+}
+
+func TestForwardResourceRestartsWhenPodIsDeleted(t *testing.T) {
+	defer func(d time.Duration) { retryForwardDelay = d }(retryForwardDelay)
+	retryForwardDelay = 0
+
+	defer func(c func() (kubernetes.Interface, error)) { Client = c }(Client)
+	Client = func() (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+			Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+		}), nil
+	}
+
+	forwarder := &resourceForwarder{
+		forwarded: make(chan string),
+		stopped:   make(chan bool),
+	}
+	p := NewPortForwarder(ioutil.Discard, NewImageList(), nil)
+	p.Forwarder = forwarder
+
+	entry := &portForwardEntry{
+		resource:  "service/web",
+		namespace: "ns",
+		port:      8080,
+		localPort: 9000,
+	}
+	p.forwardedResources.Store(entry.key(), entry)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go p.forwardResource(ctx, entry)
+
+	testutil.CheckDeepEqual(t, "service/web:8080", <-forwarder.forwarded)
+
+	// Pods in another namespace, or not selected by the service, don't affect the forward
+	p.restartResources(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "other", Labels: map[string]string{"app": "web"}}})
+	p.restartResources(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns", Labels: map[string]string{"app": "db"}}})
+	// A pod selected by the service restarts the forward
+	go p.restartResources(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns", Labels: map[string]string{"app": "web"}}})
+
+	testutil.CheckDeepEqual(t, "service/web:8080", <-forwarder.forwarded)
+}
+
+func TestResourceSelectsPod(t *testing.T) {
+	defer func(c func() (kubernetes.Interface, error)) { Client = c }(Client)
+	Client = func() (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(&v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+			Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+		}, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+		}), nil
+	}
+
+	web := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "ns", Labels: map[string]string{"app": "web"}}}
+	db := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "ns", Labels: map[string]string{"app": "db"}}}
+
+	var tests = []struct {
+		description string
+		resource    string
+		pod         *v1.Pod
+		shouldErr   bool
+		expected    bool
+	}{
+		{description: "pod by name", resource: "pod/web-1", pod: web, expected: true},
+		{description: "other pod", resource: "pod/web-1", pod: db},
+		{description: "service selects pod", resource: "service/web", pod: web, expected: true},
+		{description: "service doesn't select pod", resource: "service/web", pod: db},
+		{description: "deployment selects pod", resource: "deployment/web", pod: web, expected: true},
+		{description: "deployment doesn't select pod", resource: "deployment/web", pod: db},
+		{description: "unknown service", resource: "service/unknown", pod: web, shouldErr: true},
+		{description: "invalid resource", resource: "web", pod: web, shouldErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			selected, err := resourceSelectsPod(test.resource, test.pod)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, selected)
+		})
+	}
+}
+
+func TestRunningPod(t *testing.T) {
+	running := v1.PodStatus{Phase: v1.PodRunning}
+	client := fake.NewSimpleClientset(&v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "ns", Labels: map[string]string{"app": "web"}},
+		Status:     running,
+	}, &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-1", Namespace: "ns", Labels: map[string]string{"app": "db"}},
+		Status:     v1.PodStatus{Phase: v1.PodSucceeded},
+	}, &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+		Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	}, &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns"},
+		Spec:       v1.ServiceSpec{Selector: map[string]string{"app": "db"}},
+	})
+
+	var tests = []struct {
+		description string
+		entry       *portForwardEntry
+		shouldErr   bool
+		podGone     bool
+		expected    string
+	}{
+		{description: "running pod", entry: &portForwardEntry{podName: "web-1"}, expected: "web-1"},
+		{description: "deleted pod", entry: &portForwardEntry{podName: "web-2"}, shouldErr: true, podGone: true},
+		{description: "terminated pod", entry: &portForwardEntry{podName: "db-1"}, shouldErr: true, podGone: true},
+		{description: "pod resource", entry: &portForwardEntry{resource: "pod/web-1"}, expected: "web-1"},
+		{description: "service", entry: &portForwardEntry{resource: "service/web"}, expected: "web-1"},
+		{description: "service without running pods", entry: &portForwardEntry{resource: "service/db"}, shouldErr: true, podGone: true},
+		{description: "unknown service", entry: &portForwardEntry{resource: "service/unknown"}, shouldErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			pod, err := runningPod(client, "ns", test.entry)
+
+			testutil.CheckError(t, test.shouldErr, err)
+			testutil.CheckDeepEqual(t, test.podGone, err == errPodGone)
+			if !test.shouldErr {
+				testutil.CheckDeepEqual(t, test.expected, pod.Name)
+			}
+		})
+	}
+}
+
+func TestContainerPort(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "ns"},
+		Spec: v1.ServiceSpec{
+			Selector: map[string]string{"app": "web"},
+			Ports: []v1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromInt(8080)},
+				{Port: 81, TargetPort: intstr.FromString("http")},
+				{Port: 82},
+				{Port: 83, TargetPort: intstr.FromString("unknown")},
+			},
+		},
+	})
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "ns"},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:  "web",
+			Ports: []v1.ContainerPort{{Name: "http", ContainerPort: 9000}},
+		}}},
+	}
+
+	var tests = []struct {
+		description string
+		entry       *portForwardEntry
+		shouldErr   bool
+		expected    int32
+	}{
+		{description: "pod", entry: &portForwardEntry{podName: "web-1", port: 80}, expected: 80},
+		{description: "deployment", entry: &portForwardEntry{resource: "deployment/web", port: 80}, expected: 80},
+		{description: "service target port", entry: &portForwardEntry{resource: "service/web", port: 80}, expected: 8080},
+		{description: "service named target port", entry: &portForwardEntry{resource: "service/web", port: 81}, expected: 9000},
+		{description: "service without target port", entry: &portForwardEntry{resource: "service/web", port: 82}, expected: 82},
+		{description: "unknown named target port", entry: &portForwardEntry{resource: "service/web", port: 83}, shouldErr: true},
+		{description: "unknown service port", entry: &portForwardEntry{resource: "service/web", port: 90}, shouldErr: true},
+		{description: "unknown service", entry: &portForwardEntry{resource: "service/unknown", port: 80}, shouldErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			port, err := containerPort(client, "ns", test.entry, pod)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, port)
+		})
+	}
+}
+
+func TestStopBeforeForward(t *testing.T) {
+	forwarder := &clientForwarder{}
+	entry := &portForwardEntry{podName: "web-1", containerName: "web", port: 8080}
+
+	forwarder.Stop(entry)
+	err := forwarder.Forward(entry)
+
+	testutil.CheckError(t, false, err)
+	testutil.CheckDeepEqual(t, true, entry.stopChan == nil)
+}
+
+func TestAvailablePort(t *testing.T) {
+	defer func(f func(int32) bool) { isPortFree = f }(isPortFree)
+	isPortFree = func(port int32) bool { return port != 9000 }
+
+	p := NewPortForwarder(ioutil.Discard, NewImageList(), nil)
+	p.forwardedPorts.Store(int32(8080), "web")
+
+	testutil.CheckDeepEqual(t, int32(8081), p.availablePort(8080, "api"))
+	testutil.CheckDeepEqual(t, int32(8082), p.availablePort(8080, "db"))
+	testutil.CheckDeepEqual(t, int32(9001), p.availablePort(9000, "debug"))
+	testutil.CheckDeepEqual(t, int32(5000), p.availablePort(5000, "other"))
+}
@@ -35,3 +35,17 @@ func PodWatcher() (watch.Interface, error) {
 		TimeoutSeconds:       &forever,
 	})
 }
+
+// EventWatcher returns a watcher that will report on all the warning Events about Pods
+func EventWatcher() (watch.Interface, error) {
+	kubeclient, err := Client()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting k8s client")
+	}
+	client := kubeclient.CoreV1()
+	var forever int64 = 3600 * 24 * 365 * 100
+	return client.Events("").Watch(meta_v1.ListOptions{
+		FieldSelector:  "involvedObject.kind=Pod,type=Warning",
+		TimeoutSeconds: &forever,
+	})
+}
@@ -25,7 +25,7 @@ import (
 type changes struct {
 	dirtyArtifacts []*artifactChange
 	needsRebuild   []*latest.Artifact
-	needsResync    []*sync.Item
+	needsResync    []*artifactSync
 	needsRedeploy  bool
 	needsReload    bool
 }
@@ -35,16 +35,27 @@ type artifactChange struct {
 	events   watch.Events
 }
 
+type artifactSync struct {
+	artifact *latest.Artifact
+	item     *sync.Item
+}
+
 func (c *changes) AddDirtyArtifact(a *latest.Artifact, e watch.Events) {
 	c.dirtyArtifacts = append(c.dirtyArtifacts, &artifactChange{artifact: a, events: e})
 }
 
 func (c *changes) AddRebuild(a *latest.Artifact) {
+	// An artifact can be marked for rebuild both by local and remote changes.
+	for _, artifact := range c.needsRebuild {
+		if artifact == a {
+			return
+		}
+	}
 	c.needsRebuild = append(c.needsRebuild, a)
 }
 
-func (c *changes) AddResync(s *sync.Item) {
-	c.needsResync = append(c.needsResync, s)
+func (c *changes) AddResync(a *latest.Artifact, s *sync.Item) {
+	c.needsResync = append(c.needsResync, &artifactSync{artifact: a, item: s})
 }
 
 func (c *changes) reset() {
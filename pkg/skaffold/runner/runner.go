@@ -46,6 +46,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
 )
 
 // ErrorConfigurationChanged is a special error that's returned when the skaffold configuration was changed.
@@ -63,10 +64,13 @@ type SkaffoldRunner struct {
 	opts         *config.SkaffoldOptions
 	watchFactory watch.Factory
 	builds       []build.Artifact
+	backSyncer   *sync.BackSyncer
+	portForwards []*latest.PortForwardResource
 }
 
-// NewForConfig returns a new SkaffoldRunner for a SkaffoldPipeline
-func NewForConfig(opts *config.SkaffoldOptions, cfg *latest.SkaffoldPipeline) (*SkaffoldRunner, error) {
+// NewForConfig returns a new SkaffoldRunner for a SkaffoldPipeline.
+// The manifest transforms are applied by the deployer before the manifests are deployed.
+func NewForConfig(opts *config.SkaffoldOptions, cfg *latest.SkaffoldPipeline, manifestTransforms ...deploy.ManifestTransform) (*SkaffoldRunner, error) {
 	kubeContext, err := kubectx.CurrentContext()
 	if err != nil {
 		return nil, errors.Wrap(err, "getting current cluster context")
@@ -93,7 +97,7 @@ func NewForConfig(opts *config.SkaffoldOptions, cfg *latest.SkaffoldPipeline) (*
 		return nil, errors.Wrap(err, "parsing test config")
 	}
 
-	deployer, err := getDeployer(&cfg.Deploy, kubeContext, opts.Namespace, defaultRepo)
+	deployer, err := getDeployer(&cfg.Deploy, kubeContext, opts.Namespace, defaultRepo, manifestTransforms)
 	if err != nil {
 		return nil, errors.Wrap(err, "parsing deploy config")
 	}
@@ -115,12 +119,25 @@ func NewForConfig(opts *config.SkaffoldOptions, cfg *latest.SkaffoldPipeline) (*
 		Deployer:     deployer,
 		Tagger:       tagger,
 		Trigger:      trigger,
-		Syncer:       &kubectl.Syncer{},
+		Syncer:       kubectl.NewSyncer(),
 		opts:         opts,
 		watchFactory: watch.NewWatcher,
+		backSyncer:   kubectl.NewBackSyncer(),
+		portForwards: portForwardResources(cfg.PortForward, opts.Namespace),
 	}, nil
 }
 
+// portForwardResources defaults the namespace of user-defined port forwards
+// to the namespace the deployments are run in.
+func portForwardResources(resources []*latest.PortForwardResource, namespace string) []*latest.PortForwardResource {
+	for _, r := range resources {
+		if r.Namespace == "" {
+			r.Namespace = namespace
+		}
+	}
+	return resources
+}
+
 func getBuilder(cfg *latest.BuildConfig, kubeContext string) (build.Builder, error) {
 	switch {
 	case cfg.LocalBuild != nil:
@@ -144,7 +161,7 @@ func getTester(cfg *latest.TestConfig) (test.Tester, error) {
 	return test.NewTester(cfg)
 }
 
-func getDeployer(cfg *latest.DeployConfig, kubeContext string, namespace string, defaultRepo string) (deploy.Deployer, error) {
+func getDeployer(cfg *latest.DeployConfig, kubeContext string, namespace string, defaultRepo string, manifestTransforms []deploy.ManifestTransform) (deploy.Deployer, error) {
 	// TODO(dgageot): this should be the folder containing skaffold.yaml. Should also be moved elsewhere.
 	cwd, err := os.Getwd()
 	if err != nil {
@@ -153,13 +170,16 @@ func getDeployer(cfg *latest.DeployConfig, kubeContext string, namespace string,
 
 	switch {
 	case cfg.HelmDeploy != nil:
+		if len(manifestTransforms) > 0 {
+			return nil, errors.New("the helm deployer can't transform manifests, use the kubectl or kustomize deployer instead")
+		}
 		return deploy.NewHelmDeployer(cfg.HelmDeploy, kubeContext, namespace, defaultRepo), nil
 
 	case cfg.KubectlDeploy != nil:
-		return deploy.NewKubectlDeployer(cwd, cfg.KubectlDeploy, kubeContext, namespace, defaultRepo), nil
+		return deploy.NewKubectlDeployer(cwd, cfg.KubectlDeploy, kubeContext, namespace, defaultRepo, manifestTransforms...), nil
 
 	case cfg.KustomizeDeploy != nil:
-		return deploy.NewKustomizeDeployer(cfg.KustomizeDeploy, kubeContext, namespace, defaultRepo), nil
+		return deploy.NewKustomizeDeployer(cfg.KustomizeDeploy, kubeContext, namespace, defaultRepo, manifestTransforms...), nil
 
 	default:
 		return nil, fmt.Errorf("unknown deployer for config %+v", cfg)
@@ -206,9 +226,34 @@ func (r *SkaffoldRunner) Run(ctx context.Context, out io.Writer, artifacts []*la
 		return errors.Wrap(err, "deploy step")
 	}
 
+	if r.opts.PortForward {
+		return r.portForwardAndTailLogs(ctx, out, artifacts, bRes)
+	}
+
 	return r.TailLogs(ctx, out, artifacts, bRes)
 }
 
+// portForwardAndTailLogs port-forwards the deployed pods and the user-defined
+// resources until interrupted by the user.
+func (r *SkaffoldRunner) portForwardAndTailLogs(ctx context.Context, out io.Writer, artifacts []*latest.Artifact, bRes []build.Artifact) error {
+	imageList := kubernetes.NewImageList()
+	for _, b := range bRes {
+		imageList.Add(b.Tag)
+	}
+
+	portForwarder := kubernetes.NewPortForwarder(out, imageList, r.portForwards)
+	if err := portForwarder.Start(ctx); err != nil {
+		return errors.Wrap(err, "starting port-forwarder")
+	}
+
+	if err := r.TailLogs(ctx, out, artifacts, bRes); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
 // TailLogs prints the logs for deployed artifacts.
 func (r *SkaffoldRunner) TailLogs(ctx context.Context, out io.Writer, artifacts []*latest.Artifact, bRes []build.Artifact) error {
 	if !r.opts.Tail {
@@ -221,7 +266,10 @@ func (r *SkaffoldRunner) TailLogs(ctx context.Context, out io.Writer, artifacts
 	}
 
 	colorPicker := kubernetes.NewColorPicker(artifacts)
-	logger := kubernetes.NewLogAggregator(out, imageList, colorPicker)
+	logger, err := kubernetes.NewLogAggregator(out, imageList, colorPicker, r.opts.Logs)
+	if err != nil {
+		return errors.Wrap(err, "creating logger")
+	}
 	if err := logger.Start(ctx); err != nil {
 		return errors.Wrap(err, "starting logger")
 	}
@@ -235,8 +283,18 @@ func (r *SkaffoldRunner) TailLogs(ctx context.Context, out io.Writer, artifacts
 func (r *SkaffoldRunner) Dev(ctx context.Context, out io.Writer, artifacts []*latest.Artifact) ([]build.Artifact, error) {
 	imageList := kubernetes.NewImageList()
 	colorPicker := kubernetes.NewColorPicker(artifacts)
-	logger := kubernetes.NewLogAggregator(out, imageList, colorPicker)
-	portForwarder := kubernetes.NewPortForwarder(out, imageList)
+	logger, err := kubernetes.NewLogAggregator(out, imageList, colorPicker, r.opts.Logs)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating logger")
+	}
+	portForwarder := kubernetes.NewPortForwarder(out, imageList, r.portForwards)
+
+	// Bring new pods up to date with the files synced since the last build.
+	containerWatcher := kubernetes.NewContainerWatcher(imageList, func(ctx context.Context, pod *v1.Pod, c v1.Container) {
+		if err := r.Syncer.Replay(ctx, out, pod, c); err != nil {
+			logrus.Warnf("Resyncing files to pod %s: %s", pod.Name, err)
+		}
+	})
 
 	// Create watcher and register artifacts to build current state of files.
 	changed := changes{}
@@ -254,7 +312,7 @@ func (r *SkaffoldRunner) Dev(ctx context.Context, out io.Writer, artifacts []*la
 				return errors.Wrap(err, "sync")
 			}
 			if s != nil {
-				changed.AddResync(s)
+				changed.AddResync(a.artifact, s)
 			} else {
 				changed.AddRebuild(a.artifact)
 			}
@@ -266,13 +324,18 @@ func (r *SkaffoldRunner) Dev(ctx context.Context, out io.Writer, artifacts []*la
 			return ErrorConfigurationChanged
 		case len(changed.needsResync) > 0:
 			for _, s := range changed.needsResync {
-				color.Default.Fprintf(out, "Syncing %d files for %s\n", len(s.Copy)+len(s.Delete), s.Image)
+				color.Default.Fprintf(out, "Syncing %d files for %s\n", len(s.item.Copy)+len(s.item.Delete), s.item.Image)
 
-				if err := r.Syncer.Sync(ctx, s); err != nil {
-					logrus.Warnln("Skipping deploy due to sync error:", err)
-					return nil
+				if err := r.Syncer.Sync(ctx, out, s.item); err != nil {
+					logrus.Warnln("Rebuilding due to sync error:", err)
+					changed.AddRebuild(s.artifact)
 				}
 			}
+
+			if len(changed.needsRebuild) == 0 {
+				break
+			}
+			fallthrough
 		case len(changed.needsRebuild) > 0:
 			bRes, err := r.Build(ctx, out, r.Tagger, changed.needsRebuild)
 			if err != nil {
@@ -313,7 +376,14 @@ func (r *SkaffoldRunner) Dev(ctx context.Context, out io.Writer, artifacts []*la
 
 		if err := watcher.Register(
 			func() ([]string, error) { return DependenciesForArtifact(ctx, artifact) },
-			func(e watch.Events) { changed.AddDirtyArtifact(artifact, e) },
+			func(e watch.Events) {
+				// Changes to files that were just synced back from the containers are ignored.
+				filtered := r.backSyncer.Filter(e)
+				if e.HasChanged() && !filtered.HasChanged() {
+					return
+				}
+				changed.AddDirtyArtifact(artifact, filtered)
+			},
 		); err != nil {
 			return nil, errors.Wrapf(err, "watching files for artifact %s", artifact.ImageName)
 		}
@@ -343,6 +413,24 @@ func (r *SkaffoldRunner) Dev(ctx context.Context, out io.Writer, artifacts []*la
 		return nil, errors.Wrapf(err, "watching skaffold configuration %s", r.opts.ConfigurationFile)
 	}
 
+	// Watch remote files added by Dockerfiles
+	for i := range artifacts {
+		artifact := artifacts[i]
+
+		if artifact.DockerArtifact == nil || !r.shouldWatch(artifact) {
+			continue
+		}
+
+		if err := watcher.Register(
+			func() ([]string, error) {
+				return docker.RemoteDependencies(artifact.Workspace, artifact.DockerArtifact)
+			},
+			func(watch.Events) { changed.AddRebuild(artifact) },
+		); err != nil {
+			return nil, errors.Wrapf(err, "watching remote files for artifact %s", artifact.ImageName)
+		}
+	}
+
 	// First run
 	bRes, err := r.Build(ctx, out, r.Tagger, artifacts)
 	if err != nil {
@@ -359,6 +447,14 @@ func (r *SkaffoldRunner) Dev(ctx context.Context, out io.Writer, artifacts []*la
 		return nil, errors.Wrap(err, "exiting dev mode because the first deploy failed")
 	}
 
+	if syncsFiles(artifacts) {
+		if err := containerWatcher.Start(ctx); err != nil {
+			return nil, errors.Wrap(err, "starting container watcher")
+		}
+		// New containers show up in the logs once they are resynced.
+		logger.WaitForContainers(containerWatcher.Wait)
+	}
+
 	// Start logs
 	if r.opts.TailDev {
 		if err := logger.Start(ctx); err != nil {
@@ -372,10 +468,22 @@ func (r *SkaffoldRunner) Dev(ctx context.Context, out io.Writer, artifacts []*la
 		}
 	}
 
+	r.backSyncer.Start(ctx, artifacts)
+
 	r.Trigger.WatchForChanges(out)
 	return nil, watcher.Run(ctx, r.Trigger, onChange)
 }
 
+// syncsFiles says if files can be synced to the containers of any of the artifacts.
+func syncsFiles(artifacts []*latest.Artifact) bool {
+	for _, a := range artifacts {
+		if len(a.Sync) > 0 || len(a.InferSync) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *SkaffoldRunner) shouldWatch(artifact *latest.Artifact) bool {
 	if len(r.opts.Watch) == 0 {
 		return true
@@ -396,6 +504,20 @@ func (r *SkaffoldRunner) updateBuiltImages(images *kubernetes.ImageList, bRes []
 		images.Add(build.Tag)
 	}
 
+	for _, build := range bRes {
+		r.backSyncer.SetImage(build.ImageName, build.Tag)
+	}
+
+	// Files synced to previous images are now part of the new images.
+	for _, build := range bRes {
+		r.Syncer.Reset(build.Tag)
+		for _, previous := range r.builds {
+			if previous.ImageName == build.ImageName {
+				r.Syncer.Reset(previous.Tag)
+			}
+		}
+	}
+
 	// Make sure all artifacts are redeployed. Not only those that were just rebuilt.
 	r.builds = mergeWithPreviousBuilds(bRes, r.builds)
 }
@@ -27,6 +27,7 @@ import (
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build/tag"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/deploy/kubectl"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/test"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/watch"
@@ -170,6 +171,7 @@ func TestNewForConfig(t *testing.T) {
 		expectedBuilder  build.Builder
 		expectedTester   test.Tester
 		expectedDeployer deploy.Deployer
+		transforms       []deploy.ManifestTransform
 	}{
 		{
 			description: "local builder config",
@@ -231,6 +233,26 @@ func TestNewForConfig(t *testing.T) {
 			expectedTester:   &test.FullTester{},
 			expectedDeployer: &deploy.KubectlDeployer{},
 		},
+		{
+			description: "helm deployer with manifest transforms",
+			pipeline: &latest.SkaffoldPipeline{
+				Build: latest.BuildConfig{
+					TagPolicy: latest.TagPolicy{ShaTagger: &latest.ShaTagger{}},
+					BuildType: latest.BuildType{
+						LocalBuild: &latest.LocalBuild{},
+					},
+				},
+				Deploy: latest.DeployConfig{
+					DeployType: latest.DeployType{
+						HelmDeploy: &latest.HelmDeploy{},
+					},
+				},
+			},
+			transforms: []deploy.ManifestTransform{func(l kubectl.ManifestList, _ []build.Artifact) (kubectl.ManifestList, error) {
+				return l, nil
+			}},
+			shouldErr: true,
+		},
 		{
 			description: "unknown deployer",
 			pipeline: &latest.SkaffoldPipeline{
@@ -248,7 +270,7 @@ func TestNewForConfig(t *testing.T) {
 		t.Run(test.description, func(t *testing.T) {
 			cfg, err := NewForConfig(&config.SkaffoldOptions{
 				Trigger: "polling",
-			}, test.pipeline)
+			}, test.pipeline, test.transforms...)
 
 			testutil.CheckError(t, test.shouldErr, err)
 			if cfg != nil {
@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	cfg "github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	kubectx "github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes/context"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/pkg/errors"
+)
+
+// currentKubeContext is overridden for unit testing
+var currentKubeContext = kubectx.CurrentContext
+
+// activatedProfiles lists the profiles whose activation criteria match.
+func activatedProfiles(profiles []latest.Profile, opts *cfg.SkaffoldOptions) ([]string, error) {
+	var activated []string
+	var kubeContext *string
+
+	for _, profile := range profiles {
+		for _, cond := range profile.Activation {
+			if cond.KubeContext != "" && kubeContext == nil {
+				current, err := currentKubeContext()
+				if err != nil {
+					return nil, errors.Wrap(err, "getting current cluster context")
+				}
+				kubeContext = &current
+			}
+
+			matches, err := isActivated(cond, opts.Command, kubeContext)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid activation of profile %s", profile.Name)
+			}
+			if matches {
+				activated = append(activated, profile.Name)
+				break
+			}
+		}
+	}
+
+	return activated, nil
+}
+
+// isActivated checks that all the criteria of an activation match.
+func isActivated(cond latest.Activation, command string, kubeContext *string) (bool, error) {
+	if cond.Env != "" {
+		matches, err := envMatches(cond.Env)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+
+	if cond.KubeContext != "" {
+		matches, err := fullMatch(cond.KubeContext, *kubeContext)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+
+	if cond.Command != "" {
+		matches, err := fullMatch(cond.Command, command)
+		if err != nil || !matches {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// envMatches checks a `KEY=value` condition, where value is a regular expression.
+// A condition without value checks that the variable is set.
+func envMatches(env string) (bool, error) {
+	kv := strings.SplitN(env, "=", 2)
+
+	value, found := os.LookupEnv(kv[0])
+	if len(kv) == 1 {
+		return found, nil
+	}
+
+	return fullMatch(kv[1], value)
+}
+
+func fullMatch(pattern, value string) (bool, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(value), nil
+}
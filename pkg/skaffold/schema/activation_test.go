@@ -0,0 +1,135 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"os"
+	"testing"
+
+	cfg "github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestActivatedProfiles(t *testing.T) {
+	defer func(c func() (string, error)) { currentKubeContext = c }(currentKubeContext)
+	currentKubeContext = func() (string, error) { return "minikube", nil }
+
+	defer os.Unsetenv("SKAFFOLD_TEST_CI")
+	os.Setenv("SKAFFOLD_TEST_CI", "true")
+
+	profiles := []latest.Profile{
+		{Name: "ci", Activation: []latest.Activation{{Env: "SKAFFOLD_TEST_CI=true"}}},
+		{Name: "ci-set", Activation: []latest.Activation{{Env: "SKAFFOLD_TEST_CI"}}},
+		{Name: "not-ci", Activation: []latest.Activation{{Env: "SKAFFOLD_TEST_CI=false"}}},
+		{Name: "unset", Activation: []latest.Activation{{Env: "SKAFFOLD_TEST_UNSET"}}},
+		{Name: "minikube", Activation: []latest.Activation{{KubeContext: "minikube|docker-for-desktop"}}},
+		{Name: "gke", Activation: []latest.Activation{{KubeContext: "gke_.*"}}},
+		{Name: "dev", Activation: []latest.Activation{{Command: "dev"}}},
+		{Name: "run", Activation: []latest.Activation{{Command: "run"}}},
+		{Name: "dev-on-minikube", Activation: []latest.Activation{{Command: "dev", KubeContext: "minikube"}}},
+		{Name: "run-on-minikube", Activation: []latest.Activation{{Command: "run", KubeContext: "minikube"}}},
+		{Name: "run-or-ci", Activation: []latest.Activation{{Command: "run"}, {Env: "SKAFFOLD_TEST_CI=t.*"}}},
+		{Name: "manual"},
+	}
+
+	activated, err := activatedProfiles(profiles, &cfg.SkaffoldOptions{Command: "dev"})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"ci", "ci-set", "minikube", "dev", "dev-on-minikube", "run-or-ci"}, activated)
+}
+
+func TestInvalidActivation(t *testing.T) {
+	profiles := []latest.Profile{
+		{Name: "invalid", Activation: []latest.Activation{{Command: "("}}},
+	}
+
+	_, err := activatedProfiles(profiles, &cfg.SkaffoldOptions{Command: "dev"})
+
+	testutil.CheckError(t, true, err)
+}
+
+func TestApplyActivatedProfiles(t *testing.T) {
+	profiles := func() func(*latest.SkaffoldPipeline) {
+		return withProfiles(latest.Profile{
+			Name: "gcb",
+			Build: latest.BuildConfig{
+				BuildType: latest.BuildType{
+					GoogleCloudBuild: &latest.GoogleCloudBuild{
+						ProjectID: "my-project",
+					},
+				},
+			},
+			Activation: []latest.Activation{{Command: "run"}},
+		})
+	}
+
+	tests := []struct {
+		description string
+		opts        *cfg.SkaffoldOptions
+		expected    *latest.SkaffoldPipeline
+		shouldErr   bool
+	}{
+		{
+			description: "auto-activated",
+			opts:        &cfg.SkaffoldOptions{Command: "run"},
+			expected: config(
+				withGoogleCloudBuild("my-project", withGitTagger()),
+				withKubectlDeploy("k8s/*.yaml"),
+			),
+		},
+		{
+			description: "not activated",
+			opts:        &cfg.SkaffoldOptions{Command: "dev"},
+			expected: config(
+				withLocalBuild(withGitTagger()),
+				withKubectlDeploy("k8s/*.yaml"),
+				profiles(),
+			),
+		},
+		{
+			description: "deactivated",
+			opts:        &cfg.SkaffoldOptions{Command: "run", Profiles: []string{"-gcb"}},
+			expected: config(
+				withLocalBuild(withGitTagger()),
+				withKubectlDeploy("k8s/*.yaml"),
+				profiles(),
+			),
+		},
+		{
+			description: "unknown deactivated profile",
+			opts:        &cfg.SkaffoldOptions{Command: "run", Profiles: []string{"-unknown"}},
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			config := config(
+				withLocalBuild(withGitTagger()),
+				withKubectlDeploy("k8s/*.yaml"),
+				profiles(),
+			)
+
+			err := ApplyProfiles(config, test.opts)
+
+			testutil.CheckError(t, test.shouldErr, err)
+			if !test.shouldErr {
+				testutil.CheckDeepEqual(t, test.expected, config)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	draft          = "http://json-schema.org/draft-07/schema#"
+	definitionsRef = "#/definitions/"
+)
+
+// Definition is a JSON Schema definition.
+type Definition struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Items                *Definition            `json:"items,omitempty"`
+	Properties           map[string]*Definition `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	AllOf                []*Definition          `json:"allOf,omitempty"`
+	OneOf                []*Definition          `json:"oneOf,omitempty"`
+	AnyOf                []*Definition          `json:"anyOf,omitempty"`
+	Not                  *Definition            `json:"not,omitempty"`
+	Definitions          map[string]*Definition `json:"definitions,omitempty"`
+}
+
+// Generate generates the JSON Schema of a configuration type, for a given api version.
+// It honors `yaml` tags, and the `required`, `default`, `oneOf`, `pattern`, `enum`,
+// `min` and `max` yamltags.
+func Generate(apiVersion string, config interface{}) ([]byte, error) {
+	t := reflect.TypeOf(config)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct, got %s", t.Kind())
+	}
+
+	g := &generator{definitions: map[string]*Definition{}}
+	root := g.newDefinition(t)
+
+	if property := g.definitions[t.Name()].Properties["apiVersion"]; property != nil && apiVersion != "" {
+		property.Enum = []string{apiVersion}
+	}
+	root.Schema = draft
+	root.Definitions = g.definitions
+
+	buf, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '\n'), nil
+}
+
+type generator struct {
+	definitions map[string]*Definition
+}
+
+func (g *generator) newDefinition(t reflect.Type) *Definition {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.newDefinition(t.Elem())
+	case reflect.Bool:
+		return &Definition{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Definition{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Definition{Type: "number"}
+	case reflect.String:
+		return &Definition{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Definition{Type: "array", Items: g.newDefinition(t.Elem())}
+	case reflect.Map:
+		return &Definition{Type: "object", AdditionalProperties: g.newDefinition(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		if _, found := g.definitions[name]; !found {
+			// Register first, to support recursive types.
+			g.definitions[name] = &Definition{}
+			*g.definitions[name] = *g.structDefinition(t)
+		}
+		return &Definition{Ref: definitionsRef + name}
+	default:
+		// interface{} accepts any value.
+		return &Definition{}
+	}
+}
+
+func (g *generator) structDefinition(t reflect.Type) *Definition {
+	definition := &Definition{
+		Type:                 "object",
+		Properties:           map[string]*Definition{},
+		AdditionalProperties: false,
+	}
+	oneOfs := map[string][]string{}
+	var groups []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, inline := yamlName(field)
+		if name == "-" {
+			continue
+		}
+
+		if inline {
+			inlined := g.structDefinition(derefType(field.Type))
+			for k, v := range inlined.Properties {
+				definition.Properties[k] = v
+			}
+			definition.Required = append(definition.Required, inlined.Required...)
+			definition.AllOf = append(definition.AllOf, inlined.AllOf...)
+			continue
+		}
+
+		property := g.newDefinition(field.Type)
+		for _, tag := range strings.Split(field.Tag.Get("yamltags"), ",") {
+			parts := strings.SplitN(tag, "=", 2)
+			switch {
+			case parts[0] == "required":
+				definition.Required = append(definition.Required, name)
+			case parts[0] == "default" && len(parts) == 2:
+				property.Default = defaultValue(derefType(field.Type), parts[1])
+			case parts[0] == "oneOf" && len(parts) == 2:
+				if _, found := oneOfs[parts[1]]; !found {
+					groups = append(groups, parts[1])
+				}
+				oneOfs[parts[1]] = append(oneOfs[parts[1]], name)
+			case parts[0] == "pattern" && len(parts) == 2:
+				property.Pattern = parts[1]
+			case parts[0] == "enum" && len(parts) == 2:
+				property.Enum = strings.Split(parts[1], "|")
+			case parts[0] == "min" && len(parts) == 2:
+				property.Minimum = bound(parts[1])
+			case parts[0] == "max" && len(parts) == 2:
+				property.Maximum = bound(parts[1])
+			}
+		}
+		definition.Properties[name] = property
+	}
+
+	// At most one of the fields of a oneOf group can be set.
+	sort.Strings(groups)
+	for _, group := range groups {
+		var anyOf []*Definition
+		var oneOf []*Definition
+		for _, name := range oneOfs[group] {
+			oneOf = append(oneOf, &Definition{Required: []string{name}})
+			anyOf = append(anyOf, &Definition{Required: []string{name}})
+		}
+		oneOf = append(oneOf, &Definition{Not: &Definition{AnyOf: anyOf}})
+		definition.AllOf = append(definition.AllOf, &Definition{OneOf: oneOf})
+	}
+
+	return definition
+}
+
+// yamlName returns the name of a field, as serialized by gopkg.in/yaml.v2.
+func yamlName(field reflect.StructField) (string, bool) {
+	parts := strings.Split(field.Tag.Get("yaml"), ",")
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+
+	if parts[0] != "" {
+		return parts[0], false
+	}
+	return strings.ToLower(field.Name), false
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func defaultValue(t reflect.Type, value string) interface{} {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(value, 0, 64); err == nil {
+			return i
+		}
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+func bound(value string) *float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil
+	}
+	return &f
+}
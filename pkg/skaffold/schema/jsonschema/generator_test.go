@@ -0,0 +1,154 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+type testConfig struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Name       string            `yaml:"name" yamltags:"required,pattern=^[a-z]+$"`
+	Replicas   int               `yaml:"replicas,omitempty" yamltags:"default=3,min=1,max=10"`
+	Mode       string            `yaml:"mode,omitempty" yamltags:"enum=fast|slow"`
+	Labels     map[string]string `yaml:"labels,omitempty"`
+	Ignored    string            `yaml:"-"`
+	Items      []*testItem       `yaml:"items,omitempty"`
+	testType   `yaml:",inline"`
+}
+
+type testItem struct {
+	Value string
+}
+
+type testType struct {
+	Docker *testItem `yaml:"docker,omitempty" yamltags:"oneOf=type"`
+	Bazel  *testItem `yaml:"bazel,omitempty" yamltags:"oneOf=type"`
+}
+
+const expectedSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$ref": "#/definitions/testConfig",
+  "definitions": {
+    "testConfig": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {
+          "type": "string",
+          "enum": [
+            "test/v1"
+          ]
+        },
+        "bazel": {
+          "$ref": "#/definitions/testItem"
+        },
+        "docker": {
+          "$ref": "#/definitions/testItem"
+        },
+        "items": {
+          "type": "array",
+          "items": {
+            "$ref": "#/definitions/testItem"
+          }
+        },
+        "labels": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "string"
+          }
+        },
+        "mode": {
+          "type": "string",
+          "enum": [
+            "fast",
+            "slow"
+          ]
+        },
+        "name": {
+          "type": "string",
+          "pattern": "^[a-z]+$"
+        },
+        "replicas": {
+          "type": "integer",
+          "minimum": 1,
+          "maximum": 10,
+          "default": 3
+        }
+      },
+      "required": [
+        "name"
+      ],
+      "additionalProperties": false,
+      "allOf": [
+        {
+          "oneOf": [
+            {
+              "required": [
+                "docker"
+              ]
+            },
+            {
+              "required": [
+                "bazel"
+              ]
+            },
+            {
+              "not": {
+                "anyOf": [
+                  {
+                    "required": [
+                      "docker"
+                    ]
+                  },
+                  {
+                    "required": [
+                      "bazel"
+                    ]
+                  }
+                ]
+              }
+            }
+          ]
+        }
+      ]
+    },
+    "testItem": {
+      "type": "object",
+      "properties": {
+        "value": {
+          "type": "string"
+        }
+      },
+      "additionalProperties": false
+    }
+  }
+}
+`
+
+func TestGenerate(t *testing.T) {
+	schema, err := Generate("test/v1", &testConfig{})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, expectedSchema, string(schema))
+}
+
+func TestGenerateNotAStruct(t *testing.T) {
+	_, err := Generate("test/v1", "config")
+
+	testutil.CheckError(t, true, err)
+}
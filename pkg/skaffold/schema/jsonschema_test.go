@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchemasAreUpToDate(t *testing.T) {
+	for _, apiVersion := range APIVersions() {
+		t.Run(apiVersion, func(t *testing.T) {
+			expected, err := GetJSONSchema(apiVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "schemas", strings.TrimPrefix(apiVersion, "skaffold/")+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(expected) != string(actual) {
+				t.Errorf("JSON Schema for %s is stale. Run `go run hack/schemas/main.go` to update it", apiVersion)
+			}
+		})
+	}
+}
+
+func TestGetJSONSchemaUnknownVersion(t *testing.T) {
+	if _, err := GetJSONSchema("skaffold/v0"); err == nil {
+		t.Error("expected an error for an unknown version")
+	}
+}
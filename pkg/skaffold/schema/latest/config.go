@@ -42,7 +42,42 @@ type SkaffoldPipeline struct {
 	Build    BuildConfig  `yaml:"build,omitempty"`
 	Test     TestConfig   `yaml:"test,omitempty"`
 	Deploy   DeployConfig `yaml:"deploy,omitempty"`
-	Profiles []Profile    `yaml:"profiles,omitempty"`
+	Profiles []Profile    `yaml:"profiles,omitempty" yamltags:"skipTemplate"`
+
+	PortForward []*PortForwardResource `yaml:"portForward,omitempty"`
+
+	// Requires lists other pipeline files whose artifacts, tests and
+	// deployers are merged into this pipeline.
+	Requires []ConfigDependency `yaml:"requires,omitempty"`
+}
+
+// ConfigDependency is a pipeline file required by another pipeline file.
+type ConfigDependency struct {
+	// Path of the required pipeline file, relative to the requiring file.
+	Path string `yaml:"path,omitempty"`
+
+	// Name of the module, used to select it with `--module`.
+	// Defaults to the name of the directory holding the file.
+	Name string `yaml:"name,omitempty"`
+}
+
+// PortForwardResource describes a resource that is port forwarded
+// during `dev` and `run --port-forward`.
+type PortForwardResource struct {
+	// Type is the kind of resource: `service`, `deployment` or `pod`.
+	Type string `yaml:"resourceType,omitempty" yamltags:"required,enum=service|deployment|pod"`
+
+	// Name is the name of the resource.
+	Name string `yaml:"resourceName,omitempty" yamltags:"required"`
+
+	// Namespace of the resource. Defaults to the current context's namespace.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// Port is the remote port.
+	Port int32 `yaml:"port,omitempty" yamltags:"required,min=1,max=65535"`
+
+	// LocalPort is the local port. Defaults to the remote port.
+	LocalPort int32 `yaml:"localPort,omitempty" yamltags:"min=1,max=65535"`
 }
 
 func (c *SkaffoldPipeline) GetVersion() string {
@@ -72,7 +107,7 @@ type GitTagger struct{}
 
 // EnvTemplateTagger contains the configuration for the envTemplate tagger.
 type EnvTemplateTagger struct {
-	Template string `yaml:"template,omitempty"`
+	Template string `yaml:"template,omitempty" yamltags:"skipTemplate"`
 }
 
 // DateTimeTagger contains the configuration for the DateTime tagger.
@@ -101,7 +136,7 @@ type LocalBuild struct {
 // Google Cloud Build.
 type GoogleCloudBuild struct {
 	ProjectID   string `yaml:"projectId,omitempty"`
-	DiskSizeGb  int64  `yaml:"diskSizeGb,omitempty"`
+	DiskSizeGb  int64  `yaml:"diskSizeGb,omitempty" yamltags:"min=10,max=1000"`
 	MachineType string `yaml:"machineType,omitempty"`
 	Timeout     string `yaml:"timeout,omitempty"`
 	DockerImage string `yaml:"dockerImage,omitempty"`
@@ -185,14 +220,14 @@ type KustomizeDeploy struct {
 }
 
 type HelmRelease struct {
-	Name              string                 `yaml:"name,omitempty"`
+	Name              string                 `yaml:"name,omitempty" yamltags:"pattern=^[a-z0-9]([-a-z0-9]*[a-z0-9])?$"`
 	ChartPath         string                 `yaml:"chartPath,omitempty"`
 	ValuesFiles       []string               `yaml:"valuesFiles,omitempty"`
 	Values            map[string]string      `yaml:"values,omitempty,omitempty"`
 	Namespace         string                 `yaml:"namespace,omitempty"`
 	Version           string                 `yaml:"version,omitempty"`
 	SetValues         map[string]string      `yaml:"setValues,omitempty"`
-	SetValueTemplates map[string]string      `yaml:"setValueTemplates,omitempty"`
+	SetValueTemplates map[string]string      `yaml:"setValueTemplates,omitempty" yamltags:"skipTemplate"`
 	Wait              bool                   `yaml:"wait,omitempty"`
 	RecreatePods      bool                   `yaml:"recreatePods,omitempty"`
 	Overrides         map[string]interface{} `yaml:"overrides,omitempty"`
@@ -230,19 +265,81 @@ type HelmConventionConfig struct {
 // Artifact represents items that need to be built, along with the context in which
 // they should be built.
 type Artifact struct {
-	ImageName    string            `yaml:"image,omitempty"`
-	Workspace    string            `yaml:"context,omitempty"`
-	Sync         map[string]string `yaml:"sync,omitempty"`
+	ImageName string            `yaml:"image,omitempty" yamltags:"pattern=^[a-z0-9]+([._/:-]+[a-z0-9]+)*$"`
+	Workspace string            `yaml:"context,omitempty"`
+	Sync      map[string]string `yaml:"sync,omitempty"`
+
+	// InferSync lists patterns of files that are synced to the destinations
+	// inferred from the Dockerfile of a docker artifact.
+	InferSync    []string        `yaml:"inferSync,omitempty"`
+	SyncHooks    *SyncHooks      `yaml:"syncHooks,omitempty"`
+	SyncBack     []*SyncBackRule `yaml:"syncBack,omitempty"`
 	ArtifactType `yaml:",inline"`
 }
 
+// SyncBackRule describes files generated inside the container that
+// are copied back to the workspace.
+type SyncBackRule struct {
+	// Src is a file or a folder in the container.
+	Src string `yaml:"src,omitempty"`
+
+	// Dest is where the files are copied, relative to the artifact's context.
+	Dest string `yaml:"dest,omitempty"`
+}
+
+// SyncHooks describes the commands that are run when files are synced.
+type SyncHooks struct {
+	// Before are run on the host, in the artifact's context, before the files are synced.
+	Before []SyncHook `yaml:"before,omitempty"`
+
+	// OnSync are run inside each synced container, after the files are synced.
+	OnSync []SyncHook `yaml:"onSync,omitempty"`
+}
+
+// SyncHook is a command run when files are synced.
+type SyncHook struct {
+	Command []string `yaml:"command,omitempty"`
+}
+
 // Profile is additional configuration that overrides default
 // configuration when it is activated.
 type Profile struct {
-	Name   string       `yaml:"name,omitempty"`
-	Build  BuildConfig  `yaml:"build,omitempty"`
-	Test   TestConfig   `yaml:"test,omitempty"`
-	Deploy DeployConfig `yaml:"deploy,omitempty"`
+	Name       string       `yaml:"name,omitempty"`
+	Build      BuildConfig  `yaml:"build,omitempty"`
+	Test       TestConfig   `yaml:"test,omitempty"`
+	Deploy     DeployConfig `yaml:"deploy,omitempty"`
+	Activation []Activation `yaml:"activation,omitempty"`
+	Patches    []JSONPatch  `yaml:"patches,omitempty"`
+}
+
+// JSONPatch is a JSON-Patch style operation applied by a profile to the pipeline.
+type JSONPatch struct {
+	// Op is the operation: `add`, `remove`, `replace`, `move`, `copy` or `test`. Defaults to `replace`.
+	Op string `yaml:"op,omitempty" yamltags:"enum=add|remove|replace|move|copy|test"`
+
+	// Path is the position in the pipeline, like `/build/artifacts/0/docker/dockerfile`.
+	// Array elements are selected by index, or by the value of their `image` or `name` field.
+	Path string `yaml:"path,omitempty"`
+
+	// From is the source position of a `move` or `copy`.
+	From string `yaml:"from,omitempty"`
+
+	// Value is the value used by `add`, `replace` and `test`.
+	Value interface{} `yaml:"value,omitempty"`
+}
+
+// Activation criteria by which a profile is automatically activated.
+// All the criteria that are set must match.
+type Activation struct {
+	// Env is a `KEY=value` condition on an environment variable. The value is a regular expression.
+	// Without a value, the variable only has to be set.
+	Env string `yaml:"env,omitempty"`
+
+	// KubeContext is a regular expression matched against the current kube-context.
+	KubeContext string `yaml:"kubeContext,omitempty"`
+
+	// Command is a regular expression matched against the command, like `dev` or `run`.
+	Command string `yaml:"command,omitempty"`
 }
 
 type ArtifactType struct {
@@ -269,13 +366,13 @@ type BazelArtifact struct {
 
 type JibMavenArtifact struct {
 	// Only multi-module
-	Module  string `yaml:"module"`
-	Profile string `yaml:"profile"`
+	Module  string `yaml:"module,omitempty"`
+	Profile string `yaml:"profile,omitempty"`
 }
 
 type JibGradleArtifact struct {
 	// Only multi-module
-	Project string `yaml:"project"`
+	Project string `yaml:"project,omitempty"`
 }
 
 // Parse reads a SkaffoldPipeline from yaml.
@@ -31,10 +31,8 @@ import (
 func (c *SkaffoldPipeline) SetDefaultValues() error {
 	c.defaultToLocalBuild()
 	c.defaultToKubectlDeploy()
-	c.setDefaultCloudBuildDockerImage()
 	c.setDefaultTagger()
-	c.setDefaultKustomizePath()
-	c.setDefaultKubectlManifests()
+	c.SetDefaultSectionValues()
 
 	if err := c.withKanikoConfig(
 		setDefaultKanikoTimeout,
@@ -46,13 +44,23 @@ func (c *SkaffoldPipeline) SetDefaultValues() error {
 		return err
 	}
 
+	return nil
+}
+
+// SetDefaultSectionValues sets the default values of the artifacts and of the builder and
+// deployer sections present in the pipeline. Unlike SetDefaultValues, it doesn't choose a
+// build type, a deployer or a tagger, and doesn't need to access the cluster.
+func (c *SkaffoldPipeline) SetDefaultSectionValues() {
+	c.setDefaultCloudBuildDockerImage()
+	c.setDefaultKustomizePath()
+	c.setDefaultKubectlManifests()
+
 	for _, a := range c.Build.Artifacts {
 		c.defaultToDockerArtifact(a)
 		c.setDefaultDockerfile(a)
 		c.setDefaultWorkspace(a)
+		c.setDefaultSyncBackDest(a)
 	}
-
-	return nil
 }
 
 func (c *SkaffoldPipeline) defaultToLocalBuild() {
@@ -123,6 +131,12 @@ func (c *SkaffoldPipeline) setDefaultWorkspace(a *Artifact) {
 	a.Workspace = valueOrDefault(a.Workspace, ".")
 }
 
+func (c *SkaffoldPipeline) setDefaultSyncBackDest(a *Artifact) {
+	for _, rule := range a.SyncBack {
+		rule.Dest = valueOrDefault(rule.Dest, ".")
+	}
+}
+
 func (c *SkaffoldPipeline) withKanikoConfig(operations ...func(kaniko *KanikoBuild) error) error {
 	if kaniko := c.Build.KanikoBuild; kaniko != nil {
 		for _, operation := range operations {
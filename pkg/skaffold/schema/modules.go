@@ -0,0 +1,341 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	cfg "github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yamltags"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// LoadPipeline reads a pipeline file, upgrades it to the latest version and applies
+// the profiles. The pipelines of the files it requires are merged into it, with their
+// paths made relative to the directory of the main file.
+func LoadPipeline(out io.Writer, filename string, opts *cfg.SkaffoldOptions) (*latest.SkaffoldPipeline, error) {
+	return newPipelineLoader(out, opts).loadPipeline(filename)
+}
+
+type pipelineLoader struct {
+	out  io.Writer
+	opts *cfg.SkaffoldOptions
+
+	// loaded keeps track of the files already merged into the pipeline.
+	loaded map[string]bool
+	// profiles is the set of profile names defined across all the files.
+	profiles map[string]bool
+	// images maps each image name to the file that builds it.
+	images map[string]string
+	// vars are the values of the var file, available to templates.
+	vars map[string]string
+
+	// origins, if not nil, records the sections changed by profiles.
+	origins Origins
+	// validate enables the validation of each file.
+	validate bool
+	errors   []ValidationError
+}
+
+func newPipelineLoader(out io.Writer, opts *cfg.SkaffoldOptions) *pipelineLoader {
+	return &pipelineLoader{
+		out:      out,
+		opts:     opts,
+		loaded:   map[string]bool{},
+		profiles: map[string]bool{},
+		images:   map[string]string{},
+	}
+}
+
+func (l *pipelineLoader) loadPipeline(filename string) (*latest.SkaffoldPipeline, error) {
+	vars, err := readVarFile(l.opts.VarFile)
+	if err != nil {
+		return nil, err
+	}
+	l.vars = vars
+
+	config, err := l.load(filename, nil, l.opts.Modules)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range l.opts.Profiles {
+		if !l.profiles[strings.TrimPrefix(name, "-")] {
+			return nil, fmt.Errorf("couldn't find profile %s", strings.TrimPrefix(name, "-"))
+		}
+	}
+
+	return config, nil
+}
+
+// load reads a pipeline file and merges the files it requires. It returns nil
+// if the file was already loaded.
+func (l *pipelineLoader) load(filename string, chain []string, modules []string) (*latest.SkaffoldPipeline, error) {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving %s", filename)
+	}
+	for _, required := range chain {
+		if required == abs {
+			return nil, fmt.Errorf("circular requirement: %s", strings.Join(append(chain, abs), " -> "))
+		}
+	}
+	if l.loaded[abs] {
+		logrus.Debugf("%s is already part of the pipeline", filename)
+		return nil, nil
+	}
+	l.loaded[abs] = true
+	chain = append(chain, abs)
+
+	// Default values are set once the required files are merged.
+	// Inspection validates the pipeline at the end, to report all the errors.
+	parsed, err := parseConfig(filename, false, !l.validate)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing skaffold config %s", filename)
+	}
+
+	// automatically upgrade older config
+	parsed, err = UpgradeToLatest(l.out, parsed)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid config %s", filename)
+	}
+
+	config := parsed.(*latest.SkaffoldPipeline)
+	origins := Origins{}
+	if err := applyProfiles(config, l.profileOptions(config), origins); err != nil {
+		return nil, errors.Wrapf(err, "applying profiles to %s", filename)
+	}
+	l.recordOrigins(origins, filename, len(chain) == 1)
+
+	t := &templater{dir: filepath.Dir(filename), vars: l.vars}
+	if err := t.applyTemplates(config); err != nil {
+		return nil, errors.Wrapf(err, "templating %s", filename)
+	}
+	// Templated values are validated once resolved. Inspection reports all the errors at the end.
+	if !l.validate {
+		if err := yamltags.ProcessStruct(config); err != nil {
+			return nil, errors.Wrapf(err, "invalid config %s", filename)
+		}
+	}
+
+	if l.validate {
+		dir := ""
+		if len(chain) > 1 {
+			dir = filepath.Dir(filename)
+		}
+		l.errors = append(l.errors, validateFile(filename, dir, config)...)
+	}
+
+	for _, a := range config.Build.Artifacts {
+		if other, present := l.images[a.ImageName]; present {
+			return nil, fmt.Errorf("image %s is built by both %s and %s", a.ImageName, other, filename)
+		}
+		l.images[a.ImageName] = filename
+	}
+
+	requires, err := selectModules(config.Requires, modules)
+	if err != nil {
+		return nil, err
+	}
+	config.Requires = nil
+
+	for _, dep := range requires {
+		path := filepath.Join(filepath.Dir(filename), dep.Path)
+		module, err := l.load(path, chain, nil)
+		if err != nil {
+			return nil, err
+		}
+		if module == nil {
+			continue
+		}
+
+		// The paths of the main file are relative to the current directory,
+		// those of the other files to the directory of the file.
+		dir := filepath.Dir(dep.Path)
+		if len(chain) == 1 {
+			dir = filepath.Dir(path)
+		}
+		relocate(module, dir)
+		if err := merge(config, module); err != nil {
+			return nil, errors.Wrapf(err, "merging %s into %s", path, filename)
+		}
+	}
+
+	if err := config.SetDefaultValues(); err != nil {
+		return nil, errors.Wrap(err, "applying default values")
+	}
+
+	return config, nil
+}
+
+// recordOrigins adds the sections changed by the profiles of a file.
+// Profiles of required files are suffixed with the file name.
+func (l *pipelineLoader) recordOrigins(origins Origins, filename string, main bool) {
+	if l.origins == nil {
+		return
+	}
+
+	for section, profiles := range origins {
+		for _, profile := range profiles {
+			if !main {
+				profile = fmt.Sprintf("%s (%s)", profile, filename)
+			}
+			l.origins[section] = append(l.origins[section], profile)
+		}
+	}
+}
+
+// profileOptions restricts the requested profiles to the ones defined in a file.
+func (l *pipelineLoader) profileOptions(config *latest.SkaffoldPipeline) *cfg.SkaffoldOptions {
+	defined := map[string]bool{}
+	for _, profile := range config.Profiles {
+		defined[profile.Name] = true
+		l.profiles[profile.Name] = true
+	}
+
+	opts := *l.opts
+	opts.Profiles = nil
+	for _, name := range l.opts.Profiles {
+		if defined[strings.TrimPrefix(name, "-")] {
+			opts.Profiles = append(opts.Profiles, name)
+		}
+	}
+	return &opts
+}
+
+// selectModules returns the required files with the given module names,
+// or all of them if no name is given.
+func selectModules(requires []latest.ConfigDependency, names []string) ([]latest.ConfigDependency, error) {
+	if len(names) == 0 {
+		return requires, nil
+	}
+
+	var selected []latest.ConfigDependency
+	for _, name := range names {
+		found := false
+		for _, dep := range requires {
+			if moduleName(dep) == name {
+				selected = append(selected, dep)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("couldn't find module %s", name)
+		}
+	}
+	return selected, nil
+}
+
+// moduleName defaults to the name of the directory holding the required file.
+func moduleName(dep latest.ConfigDependency) string {
+	if dep.Name != "" {
+		return dep.Name
+	}
+
+	dir := filepath.Base(filepath.Dir(dep.Path))
+	if dir == "." || dir == string(filepath.Separator) {
+		return strings.TrimSuffix(filepath.Base(dep.Path), filepath.Ext(dep.Path))
+	}
+	return dir
+}
+
+// relocate makes the paths of a pipeline relative to its parent directory.
+func relocate(c *latest.SkaffoldPipeline, dir string) {
+	if dir == "." {
+		return
+	}
+
+	for _, a := range c.Build.Artifacts {
+		a.Workspace = relocatePath(dir, a.Workspace)
+	}
+	for _, test := range c.Test {
+		test.StructureTests = relocatePaths(dir, test.StructureTests)
+	}
+	if kubectl := c.Deploy.KubectlDeploy; kubectl != nil {
+		kubectl.Manifests = relocatePaths(dir, kubectl.Manifests)
+	}
+	if kustomize := c.Deploy.KustomizeDeploy; kustomize != nil {
+		kustomize.KustomizePath = relocatePath(dir, kustomize.KustomizePath)
+	}
+	if helm := c.Deploy.HelmDeploy; helm != nil {
+		for i := range helm.Releases {
+			helm.Releases[i].ChartPath = relocatePath(dir, helm.Releases[i].ChartPath)
+			helm.Releases[i].ValuesFiles = relocatePaths(dir, helm.Releases[i].ValuesFiles)
+		}
+	}
+}
+
+func relocatePaths(dir string, paths []string) []string {
+	var relocated []string
+	for _, path := range paths {
+		relocated = append(relocated, relocatePath(dir, path))
+	}
+	return relocated
+}
+
+func relocatePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// merge adds the artifacts, tests, deployers and port forwards of a
+// required pipeline to a pipeline. The build type and tag policy of the
+// pipeline are kept if it has any.
+func merge(c *latest.SkaffoldPipeline, module *latest.SkaffoldPipeline) error {
+	c.Build.Artifacts = append(c.Build.Artifacts, module.Build.Artifacts...)
+	if c.Build.BuildType == (latest.BuildType{}) {
+		c.Build.BuildType = module.Build.BuildType
+	}
+	if c.Build.TagPolicy == (latest.TagPolicy{}) {
+		c.Build.TagPolicy = module.Build.TagPolicy
+	}
+	c.Test = append(c.Test, module.Test...)
+	c.PortForward = append(c.PortForward, module.PortForward...)
+
+	deploy := &c.Deploy
+	switch {
+	case deploy.DeployType == (latest.DeployType{}):
+		deploy.DeployType = module.Deploy.DeployType
+	case deploy.KubectlDeploy != nil && module.Deploy.KubectlDeploy != nil:
+		deploy.KubectlDeploy.Manifests = append(deploy.KubectlDeploy.Manifests, module.Deploy.KubectlDeploy.Manifests...)
+		deploy.KubectlDeploy.RemoteManifests = append(deploy.KubectlDeploy.RemoteManifests, module.Deploy.KubectlDeploy.RemoteManifests...)
+	case deploy.HelmDeploy != nil && module.Deploy.HelmDeploy != nil:
+		deploy.HelmDeploy.Releases = append(deploy.HelmDeploy.Releases, module.Deploy.HelmDeploy.Releases...)
+	default:
+		return fmt.Errorf("can't merge a %s deployer with a %s deployer", deployerName(deploy.DeployType), deployerName(module.Deploy.DeployType))
+	}
+
+	return nil
+}
+
+func deployerName(d latest.DeployType) string {
+	switch {
+	case d.HelmDeploy != nil:
+		return "helm"
+	case d.KustomizeDeploy != nil:
+		return "kustomize"
+	default:
+		return "kubectl"
+	}
+}
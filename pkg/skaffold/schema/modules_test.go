@@ -0,0 +1,202 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	cfg "github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+const (
+	mainPipeline = `apiVersion: skaffold/v1beta1
+kind: Config
+requires:
+- path: api/skaffold.yaml
+- path: frontend/skaffold.yaml
+  name: web
+`
+	apiPipeline = `apiVersion: skaffold/v1beta1
+kind: Config
+build:
+  artifacts:
+  - image: api
+profiles:
+- name: prod
+  deploy:
+    kubectl:
+      manifests: [prod/*.yaml]
+`
+	webPipeline = `apiVersion: skaffold/v1beta1
+kind: Config
+build:
+  artifacts:
+  - image: web
+    context: src
+deploy:
+  kubectl:
+    manifests: [deployment.yaml]
+`
+)
+
+func TestLoadPipeline(t *testing.T) {
+	var tests = []struct {
+		description string
+		files       map[string]string
+		opts        cfg.SkaffoldOptions
+		shouldErr   bool
+		expected    *latest.SkaffoldPipeline
+	}{
+		{
+			description: "merge required files",
+			files: map[string]string{
+				"skaffold.yaml":          mainPipeline,
+				"api/skaffold.yaml":      apiPipeline,
+				"frontend/skaffold.yaml": webPipeline,
+			},
+			expected: config(
+				withLocalBuild(
+					withGitTagger(),
+					withDockerArtifact("api", "api", "Dockerfile"),
+					withDockerArtifact("web", "frontend/src", "Dockerfile"),
+				),
+				withKubectlDeploy("api/k8s/*.yaml", "frontend/deployment.yaml"),
+			),
+		},
+		{
+			description: "profiles are applied to required files",
+			files: map[string]string{
+				"skaffold.yaml":          mainPipeline,
+				"api/skaffold.yaml":      apiPipeline,
+				"frontend/skaffold.yaml": webPipeline,
+			},
+			opts: cfg.SkaffoldOptions{Profiles: []string{"prod"}},
+			expected: config(
+				withLocalBuild(
+					withGitTagger(),
+					withDockerArtifact("api", "api", "Dockerfile"),
+					withDockerArtifact("web", "frontend/src", "Dockerfile"),
+				),
+				withKubectlDeploy("api/prod/*.yaml", "frontend/deployment.yaml"),
+			),
+		},
+		{
+			description: "select a module",
+			files: map[string]string{
+				"skaffold.yaml":          mainPipeline,
+				"api/skaffold.yaml":      apiPipeline,
+				"frontend/skaffold.yaml": webPipeline,
+			},
+			opts: cfg.SkaffoldOptions{Modules: []string{"web"}},
+			expected: config(
+				withLocalBuild(
+					withGitTagger(),
+					withDockerArtifact("web", "frontend/src", "Dockerfile"),
+				),
+				withKubectlDeploy("frontend/deployment.yaml"),
+			),
+		},
+		{
+			description: "unknown module",
+			files: map[string]string{
+				"skaffold.yaml":          mainPipeline,
+				"api/skaffold.yaml":      apiPipeline,
+				"frontend/skaffold.yaml": webPipeline,
+			},
+			opts:      cfg.SkaffoldOptions{Modules: []string{"frontend"}},
+			shouldErr: true,
+		},
+		{
+			description: "unknown profile",
+			files: map[string]string{
+				"skaffold.yaml":          mainPipeline,
+				"api/skaffold.yaml":      apiPipeline,
+				"frontend/skaffold.yaml": webPipeline,
+			},
+			opts:      cfg.SkaffoldOptions{Profiles: []string{"staging"}},
+			shouldErr: true,
+		},
+		{
+			description: "duplicate image",
+			files: map[string]string{
+				"skaffold.yaml":          mainPipeline,
+				"api/skaffold.yaml":      apiPipeline,
+				"frontend/skaffold.yaml": apiPipeline,
+			},
+			shouldErr: true,
+		},
+		{
+			description: "circular requirement",
+			files: map[string]string{
+				"skaffold.yaml":     "apiVersion: skaffold/v1beta1\nkind: Config\nrequires:\n- path: api/skaffold.yaml\n",
+				"api/skaffold.yaml": "apiVersion: skaffold/v1beta1\nkind: Config\nrequires:\n- path: ../skaffold.yaml\n",
+			},
+			shouldErr: true,
+		},
+		{
+			description: "different deployers",
+			files: map[string]string{
+				"skaffold.yaml":          mainPipeline,
+				"api/skaffold.yaml":      apiPipeline,
+				"frontend/skaffold.yaml": "apiVersion: skaffold/v1beta1\nkind: Config\ndeploy:\n  helm: {}\n",
+			},
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmp, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+			for path, content := range test.files {
+				tmp.Write(path, content)
+			}
+
+			pipeline, err := LoadPipeline(ioutil.Discard, tmp.Path("skaffold.yaml"), &test.opts)
+
+			testutil.CheckError(t, test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+			for _, a := range pipeline.Build.Artifacts {
+				a.Workspace = relativeTo(t, tmp.Root(), a.Workspace)
+			}
+			for i, manifest := range pipeline.Deploy.KubectlDeploy.Manifests {
+				pipeline.Deploy.KubectlDeploy.Manifests[i] = relativeTo(t, tmp.Root(), manifest)
+			}
+			testutil.CheckDeepEqual(t, test.expected, pipeline)
+		})
+	}
+}
+
+func relativeTo(t *testing.T, root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rel
+}
+
+func TestModuleName(t *testing.T) {
+	testutil.CheckDeepEqual(t, "api", moduleName(latest.ConfigDependency{Path: "services/api/skaffold.yaml"}))
+	testutil.CheckDeepEqual(t, "backend", moduleName(latest.ConfigDependency{Path: "services/api/skaffold.yaml", Name: "backend"}))
+	testutil.CheckDeepEqual(t, "skaffold-api", moduleName(latest.ConfigDependency{Path: "skaffold-api.yaml"}))
+}
@@ -0,0 +1,305 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// applyPatches applies the JSON-Patch style operations of a profile to the YAML tree of a pipeline.
+// The default values of the sections present in the pipeline are set first, so that patches can
+// refer to implicit sections, like the docker section of an artifact, and to omitted fields.
+func applyPatches(config *latest.SkaffoldPipeline, profile latest.Profile) error {
+	if len(profile.Patches) == 0 {
+		return nil
+	}
+
+	config.SetDefaultSectionValues()
+
+	buf, err := yaml.Marshal(config)
+	if err != nil {
+		return errors.Wrap(err, "marshalling pipeline")
+	}
+
+	var tree interface{}
+	if err := yaml.Unmarshal(buf, &tree); err != nil {
+		return errors.Wrap(err, "unmarshalling pipeline")
+	}
+
+	for i, patch := range profile.Patches {
+		tree, err = applyPatch(tree, patch)
+		if err != nil {
+			return errors.Wrapf(err, "applying patch %d of profile %s", i, profile.Name)
+		}
+	}
+
+	if buf, err = yaml.Marshal(tree); err != nil {
+		return errors.Wrap(err, "marshalling patched pipeline")
+	}
+
+	patched := latest.SkaffoldPipeline{}
+	if err := yaml.UnmarshalStrict(buf, &patched); err != nil {
+		return errors.Wrapf(err, "invalid pipeline after applying the patches of profile %s", profile.Name)
+	}
+
+	*config = patched
+	return nil
+}
+
+func applyPatch(tree interface{}, patch latest.JSONPatch) (interface{}, error) {
+	switch patch.Op {
+	case "add":
+		return setValue(tree, patch.Path, patch.Value, true)
+	case "", "replace":
+		return setValue(tree, patch.Path, patch.Value, false)
+	case "remove":
+		tree, _, err := removeValue(tree, patch.Path)
+		return tree, err
+	case "move":
+		tree, value, err := removeValue(tree, patch.From)
+		if err != nil {
+			return nil, err
+		}
+		return setValue(tree, patch.Path, value, true)
+	case "copy":
+		value, err := getValue(tree, patch.From)
+		if err != nil {
+			return nil, err
+		}
+		return setValue(tree, patch.Path, value, true)
+	case "test":
+		value, err := getValue(tree, patch.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(normalize(value), normalize(patch.Value)) {
+			return nil, fmt.Errorf("test failed: %s is %v, not %v", patch.Path, value, patch.Value)
+		}
+		return tree, nil
+	default:
+		return nil, fmt.Errorf("unknown patch operation %q", patch.Op)
+	}
+}
+
+// parsePath splits a JSON pointer into its unescaped segments.
+func parsePath(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid path %q, it should start with /", path)
+	}
+
+	segments := strings.Split(path[1:], "/")
+	for i, segment := range segments {
+		segments[i] = strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1)
+	}
+	return segments, nil
+}
+
+func getValue(tree interface{}, path string) (interface{}, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	current := tree
+	for i, segment := range segments {
+		switch node := current.(type) {
+		case map[interface{}]interface{}:
+			value, found := node[segment]
+			if !found {
+				return nil, fmt.Errorf("%s not found", joinPath(segments[:i+1]))
+			}
+			current = value
+		case []interface{}:
+			index, err := arrayIndex(node, segment, false)
+			if err != nil {
+				return nil, errors.Wrap(err, joinPath(segments[:i+1]))
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("%s not found", joinPath(segments[:i+1]))
+		}
+	}
+
+	return current, nil
+}
+
+// setValue sets the value at the given path. With insert, values are inserted
+// into arrays, otherwise they replace existing elements. Returns the updated tree.
+func setValue(tree interface{}, path string, value interface{}, insert bool) (interface{}, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	return update(tree, segments, 0, func(parent interface{}, segment string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[interface{}]interface{}:
+			if _, found := node[segment]; !found && !insert {
+				return nil, fmt.Errorf("%s not found", joinPath(segments))
+			}
+			node[segment] = value
+			return node, nil
+		case []interface{}:
+			if insert {
+				if segment == "-" {
+					return append(node, value), nil
+				}
+				index, err := arrayIndex(node, segment, true)
+				if err != nil {
+					return nil, errors.Wrap(err, joinPath(segments))
+				}
+				node = append(node, nil)
+				copy(node[index+1:], node[index:])
+				node[index] = value
+				return node, nil
+			}
+			index, err := arrayIndex(node, segment, false)
+			if err != nil {
+				return nil, errors.Wrap(err, joinPath(segments))
+			}
+			node[index] = value
+			return node, nil
+		default:
+			return nil, fmt.Errorf("%s not found", joinPath(segments[:len(segments)-1]))
+		}
+	})
+}
+
+// removeValue removes the value at the given path. Returns the updated tree and the removed value.
+func removeValue(tree interface{}, path string) (interface{}, interface{}, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(segments) == 0 {
+		return nil, nil, errors.New("can't remove the whole pipeline")
+	}
+
+	var removed interface{}
+	tree, err = update(tree, segments, 0, func(parent interface{}, segment string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[interface{}]interface{}:
+			value, found := node[segment]
+			if !found {
+				return nil, fmt.Errorf("%s not found", joinPath(segments))
+			}
+			removed = value
+			delete(node, segment)
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(node, segment, false)
+			if err != nil {
+				return nil, errors.Wrap(err, joinPath(segments))
+			}
+			removed = node[index]
+			return append(node[:index], node[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%s not found", joinPath(segments[:len(segments)-1]))
+		}
+	})
+
+	return tree, removed, err
+}
+
+// update walks down to the parent of the last segment and replaces it with the result of fn.
+func update(current interface{}, segments []string, i int, fn func(parent interface{}, segment string) (interface{}, error)) (interface{}, error) {
+	if i == len(segments)-1 {
+		return fn(current, segments[i])
+	}
+
+	segment := segments[i]
+	switch node := current.(type) {
+	case map[interface{}]interface{}:
+		child, found := node[segment]
+		if !found {
+			return nil, fmt.Errorf("%s not found", joinPath(segments[:i+1]))
+		}
+		updated, err := update(child, segments, i+1, fn)
+		if err != nil {
+			return nil, err
+		}
+		node[segment] = updated
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(node, segment, false)
+		if err != nil {
+			return nil, errors.Wrap(err, joinPath(segments[:i+1]))
+		}
+		updated, err := update(node[index], segments, i+1, fn)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = updated
+		return node, nil
+	default:
+		return nil, fmt.Errorf("%s not found", joinPath(segments[:i+1]))
+	}
+}
+
+// arrayIndex finds an element of an array, either by index or by the value
+// of its `image` or `name` field. When inserting, the index can be the array's length.
+func arrayIndex(array []interface{}, segment string, insert bool) (int, error) {
+	if index, err := strconv.Atoi(segment); err == nil {
+		max := len(array) - 1
+		if insert {
+			max = len(array)
+		}
+		if index < 0 || index > max {
+			return 0, fmt.Errorf("index %d out of bounds", index)
+		}
+		return index, nil
+	}
+
+	for i, element := range array {
+		if node, ok := element.(map[interface{}]interface{}); ok {
+			if node["image"] == segment || node["name"] == segment {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no element named %s", segment)
+}
+
+func joinPath(segments []string) string {
+	return "/" + strings.Join(segments, "/")
+}
+
+// normalize makes values read from the pipeline comparable to values read from patches.
+func normalize(value interface{}) interface{} {
+	buf, err := yaml.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized interface{}
+	if err := yaml.Unmarshal(buf, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
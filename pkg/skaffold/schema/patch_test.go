@@ -0,0 +1,166 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestApplyPatches(t *testing.T) {
+	tests := []struct {
+		description string
+		patches     []latest.JSONPatch
+		expected    *latest.SkaffoldPipeline
+		shouldErr   bool
+	}{
+		{
+			description: "replace by index",
+			patches: []latest.JSONPatch{
+				{Path: "/build/artifacts/0/docker/dockerfile", Value: "Dockerfile.dev"},
+			},
+			expected: config(
+				withLocalBuild(
+					withGitTagger(),
+					withDockerArtifact("app", ".", "Dockerfile.dev"),
+					withDockerArtifact("web", "web", "Dockerfile"),
+				),
+				withKubectlDeploy("k8s/*.yaml"),
+			),
+		},
+		{
+			description: "replace by image name",
+			patches: []latest.JSONPatch{
+				{Op: "replace", Path: "/build/artifacts/web/docker/dockerfile", Value: "Dockerfile.dev"},
+			},
+			expected: config(
+				withLocalBuild(
+					withGitTagger(),
+					withDockerArtifact("app", ".", "Dockerfile"),
+					withDockerArtifact("web", "web", "Dockerfile.dev"),
+				),
+				withKubectlDeploy("k8s/*.yaml"),
+			),
+		},
+		{
+			description: "add and remove",
+			patches: []latest.JSONPatch{
+				{Op: "add", Path: "/deploy/kubectl/manifests/-", Value: "dev/*.yaml"},
+				{Op: "remove", Path: "/build/artifacts/0"},
+			},
+			expected: config(
+				withLocalBuild(
+					withGitTagger(),
+					withDockerArtifact("web", "web", "Dockerfile"),
+				),
+				withKubectlDeploy("k8s/*.yaml", "dev/*.yaml"),
+			),
+		},
+		{
+			description: "add object, move and test",
+			patches: []latest.JSONPatch{
+				{Op: "test", Path: "/build/artifacts/1/context", Value: "web"},
+				{Op: "move", From: "/build/artifacts/1", Path: "/build/artifacts/0"},
+				{Op: "add", Path: "/build/artifacts/0/docker/buildArgs", Value: map[interface{}]interface{}{"ENV": "dev"}},
+			},
+			expected: config(
+				withLocalBuild(
+					withGitTagger(),
+					func(cfg *latest.BuildConfig) {
+						env := "dev"
+						withDockerArtifact("web", "web", "Dockerfile")(cfg)
+						cfg.Artifacts[0].DockerArtifact.BuildArgs = map[string]*string{"ENV": &env}
+					},
+					withDockerArtifact("app", ".", "Dockerfile"),
+				),
+				withKubectlDeploy("k8s/*.yaml"),
+			),
+		},
+		{
+			description: "failed test",
+			patches:     []latest.JSONPatch{{Op: "test", Path: "/build/artifacts/0/context", Value: "web"}},
+			shouldErr:   true,
+		},
+		{
+			description: "unknown path",
+			patches:     []latest.JSONPatch{{Path: "/build/artifacts/db/context", Value: "db"}},
+			shouldErr:   true,
+		},
+		{
+			description: "index out of bounds",
+			patches:     []latest.JSONPatch{{Op: "remove", Path: "/build/artifacts/2"}},
+			shouldErr:   true,
+		},
+		{
+			description: "unknown field",
+			patches:     []latest.JSONPatch{{Op: "add", Path: "/build/artifacts/0/unknown", Value: "value"}},
+			shouldErr:   true,
+		},
+		{
+			description: "unknown operation",
+			patches:     []latest.JSONPatch{{Op: "merge", Path: "/build"}},
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			config := config(
+				withLocalBuild(
+					withGitTagger(),
+					withDockerArtifact("app", ".", "Dockerfile"),
+					withDockerArtifact("web", "web", "Dockerfile"),
+				),
+				withKubectlDeploy("k8s/*.yaml"),
+			)
+
+			err := applyPatches(config, latest.Profile{Name: "patches", Patches: test.patches})
+
+			testutil.CheckError(t, test.shouldErr, err)
+			if !test.shouldErr {
+				testutil.CheckDeepEqual(t, test.expected, config)
+			}
+		})
+	}
+}
+
+func TestApplyPatchesToImplicitSections(t *testing.T) {
+	pipeline := config(
+		withLocalBuild(
+			withGitTagger(),
+			func(cfg *latest.BuildConfig) {
+				cfg.Artifacts = append(cfg.Artifacts, &latest.Artifact{ImageName: "app"})
+			},
+		),
+		withKubectlDeploy("k8s/*.yaml"),
+	)
+
+	err := applyPatches(pipeline, latest.Profile{Name: "patches", Patches: []latest.JSONPatch{
+		{Op: "test", Path: "/build/artifacts/0/docker/dockerfile", Value: "Dockerfile"},
+		{Op: "replace", Path: "/build/artifacts/0/docker/dockerfile", Value: "Dockerfile.dev"},
+	}})
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, config(
+		withLocalBuild(
+			withGitTagger(),
+			withDockerArtifact("app", ".", "Dockerfile.dev"),
+		),
+		withKubectlDeploy("k8s/*.yaml"),
+	), pipeline)
+}
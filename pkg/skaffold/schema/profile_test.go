@@ -19,6 +19,7 @@ package schema
 import (
 	"testing"
 
+	cfg "github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	"github.com/GoogleContainerTools/skaffold/testutil"
 )
@@ -146,7 +147,7 @@ func TestApplyProfiles(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.description, func(t *testing.T) {
-			err := ApplyProfiles(test.config, []string{test.profile})
+			err := ApplyProfiles(test.config, &cfg.SkaffoldOptions{Profiles: []string{test.profile}})
 
 			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, test.config)
 		})
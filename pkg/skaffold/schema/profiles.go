@@ -24,29 +24,80 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	cfg "github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
 )
 
 // ApplyProfiles returns configuration modified by the application
-// of a list of profiles.
-func ApplyProfiles(c *latest.SkaffoldPipeline, profiles []string) error {
+// of a list of profiles. Profiles whose activation criteria match are applied
+// first, followed by the profiles explicitly requested. A profile named `-name`
+// is deactivated.
+func ApplyProfiles(c *latest.SkaffoldPipeline, opts *cfg.SkaffoldOptions) error {
+	if err := applyProfiles(c, opts, nil); err != nil {
+		return err
+	}
+	if err := c.SetDefaultValues(); err != nil {
+		return errors.Wrap(err, "applying default values")
+	}
+
+	return nil
+}
+
+// applyProfiles applies the profiles without setting default values.
+// The sections changed by each profile are recorded in origins, if not nil.
+func applyProfiles(c *latest.SkaffoldPipeline, opts *cfg.SkaffoldOptions, origins Origins) error {
 	byName := profilesByName(c.Profiles)
-	for _, name := range profiles {
-		profile, present := byName[name]
-		if !present {
+
+	requested, deactivated := splitProfiles(opts.Profiles)
+	for _, name := range append(requested, deactivated...) {
+		if _, present := byName[name]; !present {
 			return fmt.Errorf("couldn't find profile %s", name)
 		}
+	}
 
-		applyProfile(c, profile)
+	activated, err := activatedProfiles(c.Profiles, opts)
+	if err != nil {
+		return errors.Wrap(err, "finding auto-activated profiles")
 	}
-	if err := c.SetDefaultValues(); err != nil {
-		return errors.Wrap(err, "applying default values")
+
+	var autoActivated []string
+	for _, name := range activated {
+		if !util.StrSliceContains(requested, name) && !util.StrSliceContains(deactivated, name) {
+			autoActivated = append(autoActivated, name)
+		}
+	}
+	if len(autoActivated) > 0 {
+		logrus.Infof("Automatically activated profiles: %s", strings.Join(autoActivated, ", "))
+	}
+
+	for _, name := range append(autoActivated, requested...) {
+		if util.StrSliceContains(deactivated, name) {
+			continue
+		}
+		if err := applyProfile(c, byName[name]); err != nil {
+			return err
+		}
+		origins.record(byName[name])
 	}
 
 	return nil
 }
 
-func applyProfile(config *latest.SkaffoldPipeline, profile latest.Profile) {
+// splitProfiles separates the requested profiles from the deactivated ones.
+func splitProfiles(profiles []string) ([]string, []string) {
+	var requested, deactivated []string
+	for _, name := range profiles {
+		if strings.HasPrefix(name, "-") {
+			deactivated = append(deactivated, strings.TrimPrefix(name, "-"))
+		} else {
+			requested = append(requested, name)
+		}
+	}
+	return requested, deactivated
+}
+
+func applyProfile(config *latest.SkaffoldPipeline, profile latest.Profile) error {
 	logrus.Infof("applying profile: %s", profile.Name)
 
 	// this intentionally removes the Profiles field from the returned config
@@ -56,6 +107,42 @@ func applyProfile(config *latest.SkaffoldPipeline, profile latest.Profile) {
 		Build:      overlayProfileField(config.Build, profile.Build).(latest.BuildConfig),
 		Deploy:     overlayProfileField(config.Deploy, profile.Deploy).(latest.DeployConfig),
 		Test:       overlayProfileField(config.Test, profile.Test).(latest.TestConfig),
+
+		PortForward: config.PortForward,
+		Requires:    config.Requires,
+	}
+
+	return applyPatches(config, profile)
+}
+
+// Origins maps the top-level sections of a pipeline, like `build` or `deploy`,
+// to the profiles that changed them.
+type Origins map[string][]string
+
+// record adds the sections changed by a profile.
+func (o Origins) record(profile latest.Profile) {
+	if o == nil {
+		return
+	}
+
+	var sections []string
+	if !reflect.DeepEqual(profile.Build, latest.BuildConfig{}) {
+		sections = append(sections, "build")
+	}
+	if len(profile.Test) > 0 {
+		sections = append(sections, "test")
+	}
+	if !reflect.DeepEqual(profile.Deploy, latest.DeployConfig{}) {
+		sections = append(sections, "deploy")
+	}
+	for _, patch := range profile.Patches {
+		sections = append(sections, strings.SplitN(strings.TrimPrefix(patch.Path, "/"), "/", 2)[0])
+	}
+
+	for _, section := range sections {
+		if !util.StrSliceContains(o[section], profile.Name) {
+			o[section] = append(o[section], profile.Name)
+		}
 	}
 }
 
@@ -0,0 +1,200 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/pkg/errors"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+// RewriteConfig rewrites an original pipeline file so that it has the content of
+// an upgraded one. The YAML tree of the original file is updated in place, so that
+// comments, key ordering, anchors and the style of the values, like flow collections
+// or block scalars, are kept for all the parts of the file whose content didn't change.
+func RewriteConfig(original, upgraded []byte) ([]byte, error) {
+	var before, after yaml.Node
+	if err := yaml.Unmarshal(original, &before); err != nil {
+		return nil, errors.Wrap(err, "parsing original config")
+	}
+	if err := yaml.Unmarshal(upgraded, &after); err != nil {
+		return nil, errors.Wrap(err, "parsing upgraded config")
+	}
+	if len(before.Content) == 0 || len(after.Content) == 0 {
+		return upgraded, nil
+	}
+
+	rewritten, err := rewriteNode(before.Content[0], after.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	before.Content[0] = rewritten
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	encoder.CompactSeqIndent()
+	if err := encoder.Encode(&before); err != nil {
+		return nil, errors.Wrap(err, "marshalling rewritten config")
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, errors.Wrap(err, "marshalling rewritten config")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// rewriteNode returns the node of the original file, updated to have the content
+// of the upgraded node. Unchanged nodes are kept as is, mappings and sequences are
+// rewritten recursively and other nodes are replaced.
+func rewriteNode(before, after *yaml.Node) (*yaml.Node, error) {
+	same, err := sameContent(before, after)
+	if err != nil {
+		return nil, err
+	}
+	if same {
+		return before, nil
+	}
+
+	switch {
+	case before.Kind == yaml.MappingNode && after.Kind == yaml.MappingNode:
+		return rewriteMapping(before, after)
+	case before.Kind == yaml.SequenceNode && after.Kind == yaml.SequenceNode && len(before.Content) == len(after.Content):
+		for i := range before.Content {
+			item, err := rewriteNode(before.Content[i], after.Content[i])
+			if err != nil {
+				return nil, err
+			}
+			before.Content[i] = item
+		}
+		return before, nil
+	default:
+		return replaceNode(before, after), nil
+	}
+}
+
+// rewriteMapping rewrites the values of a mapping, removes the keys that are not
+// in the upgraded mapping and adds the new keys.
+// A removed key and an added key at the same position, with the same value, are
+// considered a rename: the new key keeps the comments of the original key and value.
+func rewriteMapping(before, after *yaml.Node) (*yaml.Node, error) {
+	var content []*yaml.Node
+	renamed := map[string]bool{}
+	for i := 0; i+1 < len(before.Content); i += 2 {
+		key, value := before.Content[i], before.Content[i+1]
+		newValue := lookup(after, key.Value)
+		if newValue == nil {
+			newKey, err := renamedKey(before, after, i)
+			if err != nil {
+				return nil, err
+			}
+			if newKey == nil {
+				continue
+			}
+
+			newKey.HeadComment = key.HeadComment
+			newKey.LineComment = key.LineComment
+			newKey.FootComment = key.FootComment
+			renamed[newKey.Value] = true
+			key, newValue = newKey, after.Content[i+1]
+		}
+
+		rewritten, err := rewriteNode(value, newValue)
+		if err != nil {
+			return nil, err
+		}
+		// The line comment of a scalar replaced by a block collection goes after the key.
+		if rewritten.Kind != yaml.ScalarNode && rewritten.Style&yaml.FlowStyle == 0 && key.LineComment == "" {
+			key.LineComment, rewritten.LineComment = rewritten.LineComment, ""
+		}
+		content = append(content, key, rewritten)
+	}
+
+	// Added keys are inserted after the key that precedes them in the upgraded config.
+	for i := 0; i+1 < len(after.Content); i += 2 {
+		key, value := after.Content[i], after.Content[i+1]
+		if lookup(before, key.Value) != nil || renamed[key.Value] {
+			continue
+		}
+
+		position := 0
+		for j := i - 2; j >= 0 && position == 0; j -= 2 {
+			for k := 0; k+1 < len(content); k += 2 {
+				if content[k].Value == after.Content[j].Value {
+					position = k + 2
+					break
+				}
+			}
+		}
+		content = append(content[:position], append([]*yaml.Node{key, value}, content[position:]...)...)
+	}
+
+	before.Content = content
+	return before, nil
+}
+
+// renamedKey returns the key of the upgraded mapping that replaces the removed key
+// at index i of the original mapping, or nil if it was not renamed.
+func renamedKey(before, after *yaml.Node, i int) (*yaml.Node, error) {
+	if i+1 >= len(after.Content) || lookup(before, after.Content[i].Value) != nil {
+		return nil, nil
+	}
+
+	same, err := sameContent(before.Content[i+1], after.Content[i+1])
+	if err != nil || !same {
+		return nil, err
+	}
+	return after.Content[i], nil
+}
+
+// replaceNode returns the upgraded node with the comments, the anchor and,
+// when the kinds match, the style of the original node.
+func replaceNode(before, after *yaml.Node) *yaml.Node {
+	after.HeadComment = before.HeadComment
+	after.LineComment = before.LineComment
+	after.FootComment = before.FootComment
+	after.Anchor = before.Anchor
+
+	if before.Kind == after.Kind && (before.Kind != yaml.ScalarNode || before.ShortTag() == after.ShortTag()) {
+		after.Style = before.Style
+	}
+	return after
+}
+
+// sameContent checks whether two nodes hold the same value, once aliases are resolved.
+func sameContent(before, after *yaml.Node) (bool, error) {
+	var oldValue, newValue interface{}
+	if err := before.Decode(&oldValue); err != nil {
+		return false, errors.Wrap(err, "decoding original config")
+	}
+	if err := after.Decode(&newValue); err != nil {
+		return false, errors.Wrap(err, "decoding upgraded config")
+	}
+	return reflect.DeepEqual(oldValue, newValue), nil
+}
+
+// lookup returns the value of a key in a mapping, or nil.
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
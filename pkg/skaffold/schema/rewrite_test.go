@@ -0,0 +1,144 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestRewriteConfig(t *testing.T) {
+	var tests = []struct {
+		description string
+		original    string
+		upgraded    string
+		expected    string
+	}{
+		{
+			description: "unchanged",
+			original: `# Pipeline
+build:
+  artifacts:
+  - image: app # main image
+`,
+			upgraded: `build:
+  artifacts:
+  - image: app
+`,
+			expected: `# Pipeline
+build:
+  artifacts:
+  - image: app # main image
+`,
+		},
+		{
+			description: "renamed and added keys",
+			original: `build:
+  # Artifacts
+  artifacts:
+  - imageName: app
+    workspace: app
+  tagPolicy: sha256 # default
+`,
+			upgraded: `build:
+  artifacts:
+  - image: app
+    context: app
+    docker: {}
+  tagPolicy:
+    sha256: {}
+`,
+			expected: `build:
+  # Artifacts
+  artifacts:
+  - image: app
+    context: app
+    docker: {}
+  tagPolicy: # default
+    sha256: {}
+`,
+		},
+		{
+			description: "renamed keys keep their comments",
+			original: `build:
+  artifacts:
+  # The app
+  - imageName: foo # the image
+    # Sources
+    workspace: app
+`,
+			upgraded: `build:
+  artifacts:
+  - image: foo
+    context: app
+`,
+			expected: `build:
+  artifacts:
+  # The app
+  - image: foo # the image
+    # Sources
+    context: app
+`,
+		},
+		{
+			description: "block scalars, flow style and anchors",
+			original: `deploy:
+  kubectl:
+    manifests: &manifests [k8s/app.yaml, k8s/db.yaml]
+    flags:
+      global: *manifests
+  script: |
+    echo first
+    echo second
+build:
+  tagPolicy: {envTemplate: {template: "{{.IMAGE_NAME}}"}}
+`,
+			upgraded: `deploy:
+  kubectl:
+    manifests: [k8s/app.yaml, k8s/db.yaml]
+    flags:
+      global: [k8s/app.yaml, k8s/db.yaml]
+  script: |
+    echo first
+    echo second
+build:
+  tagPolicy:
+    envTemplate:
+      template: "{{.IMAGE_NAME}}:dev"
+`,
+			expected: `deploy:
+  kubectl:
+    manifests: &manifests [k8s/app.yaml, k8s/db.yaml]
+    flags:
+      global: *manifests
+  script: |
+    echo first
+    echo second
+build:
+  tagPolicy: {envTemplate: {template: "{{.IMAGE_NAME}}:dev"}}
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			rewritten, err := RewriteConfig([]byte(test.original), []byte(test.upgraded))
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, string(rewritten))
+		})
+	}
+}
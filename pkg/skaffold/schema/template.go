@@ -0,0 +1,180 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"os/user"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yamltags"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// gitBranch is overridden for unit testing
+var gitBranch = func(dir string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = dir
+	out, err := util.RunCmdOut(cmd)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// templater executes the templates found in the string fields of a pipeline.
+// Templates can reference environment variables, `GIT_BRANCH`, `USER` and the
+// values of a var file. Referencing an unknown key is an error.
+type templater struct {
+	dir  string
+	vars map[string]string
+
+	// values are computed when the first template is found.
+	values map[string]string
+}
+
+// readVarFile reads a YAML file of `KEY: value` pairs.
+func readVarFile(filename string) (map[string]string, error) {
+	if filename == "" {
+		return nil, nil
+	}
+
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading var file")
+	}
+
+	vars := map[string]string{}
+	if err := yaml.Unmarshal(buf, &vars); err != nil {
+		return nil, errors.Wrapf(err, "parsing var file %s", filename)
+	}
+	return vars, nil
+}
+
+// applyTemplates templates all the string fields of a pipeline, except
+// those tagged with `skipTemplate`. Profiles are skipped: the active ones
+// are already applied and the others can reference values that aren't set.
+func (t *templater) applyTemplates(config interface{}) error {
+	return t.walk(reflect.ValueOf(config).Elem(), "")
+}
+
+func (t *templater) walk(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.String:
+		s, err := t.execute(path, v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return t.walk(v.Elem(), path)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" || strings.Contains(field.Tag.Get("yamltags"), "skipTemplate") {
+				continue
+			}
+			if err := t.walk(v.Field(i), yamltags.YAMLPath(path, field)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := t.walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			elem := v.MapIndex(key)
+			elemPath := fmt.Sprintf("%s.%v", path, key.Interface())
+			switch {
+			case elem.Kind() == reflect.String:
+				s, err := t.execute(elemPath, elem.String())
+				if err != nil {
+					return err
+				}
+				v.SetMapIndex(key, reflect.ValueOf(s).Convert(elem.Type()))
+			case elem.Kind() == reflect.Ptr && !elem.IsNil():
+				if err := t.walk(elem.Elem(), elemPath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (t *templater) execute(path, s string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	if t.values == nil {
+		t.values = t.environment()
+	}
+
+	tmpl, err := template.New(path).Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing template of %s", path)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, t.values); err != nil {
+		return "", errors.Wrapf(err, "executing template of %s", path)
+	}
+	return buf.String(), nil
+}
+
+// environment lists the values available to templates. The values of the
+// var file take precedence over environment variables.
+func (t *templater) environment() map[string]string {
+	values := map[string]string{}
+	for _, env := range util.OSEnviron() {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+
+	if _, present := values["USER"]; !present {
+		if u, err := user.Current(); err == nil {
+			values["USER"] = u.Username
+		}
+	}
+	if _, present := values["GIT_BRANCH"]; !present {
+		if branch, err := gitBranch(t.dir); err == nil {
+			values["GIT_BRANCH"] = branch
+		} else {
+			logrus.Debugf("Unable to find the current git branch: %s", err)
+		}
+	}
+
+	for k, v := range t.vars {
+		values[k] = v
+	}
+	return values
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestApplyTemplates(t *testing.T) {
+	defer func(f func() []string) { util.OSEnviron = f }(util.OSEnviron)
+	util.OSEnviron = func() []string { return []string{"PROJECT=my-project", "USER=jane"} }
+	defer func(f func(string) (string, error)) { gitBranch = f }(gitBranch)
+	gitBranch = func(string) (string, error) { return "feature", nil }
+
+	var tests = []struct {
+		description string
+		vars        map[string]string
+		config      *latest.SkaffoldPipeline
+		expected    *latest.SkaffoldPipeline
+		shouldErr   bool
+	}{
+		{
+			description: "no template",
+			config:      config(withKubectlDeploy("k8s/*.yaml")),
+			expected:    config(withKubectlDeploy("k8s/*.yaml")),
+		},
+		{
+			description: "env, git branch, user and var file",
+			vars:        map[string]string{"ENV": "staging"},
+			config: config(
+				withLocalBuild(
+					withDockerArtifact("gcr.io/{{.PROJECT}}/app", ".", "Dockerfile.{{.ENV}}"),
+					withTagPolicy(latest.TagPolicy{EnvTemplateTagger: &latest.EnvTemplateTagger{Template: "{{.IMAGE_NAME}}:{{.GIT_BRANCH}}"}}),
+				),
+				withKubectlDeploy("k8s/{{.ENV}}/*.yaml", "k8s/{{.USER}}-{{.GIT_BRANCH}}.yaml"),
+			),
+			expected: config(
+				withLocalBuild(
+					withDockerArtifact("gcr.io/my-project/app", ".", "Dockerfile.staging"),
+					withTagPolicy(latest.TagPolicy{EnvTemplateTagger: &latest.EnvTemplateTagger{Template: "{{.IMAGE_NAME}}:{{.GIT_BRANCH}}"}}),
+				),
+				withKubectlDeploy("k8s/staging/*.yaml", "k8s/jane-feature.yaml"),
+			),
+		},
+		{
+			description: "var file overrides env",
+			vars:        map[string]string{"PROJECT": "other"},
+			config:      config(withLocalBuild(withDockerArtifact("gcr.io/{{.PROJECT}}/app", ".", "Dockerfile"))),
+			expected:    config(withLocalBuild(withDockerArtifact("gcr.io/other/app", ".", "Dockerfile"))),
+		},
+		{
+			description: "inactive profiles are not templated",
+			config: config(
+				withKubectlDeploy("k8s/{{.PROJECT}}/*.yaml"),
+				withProfiles(latest.Profile{
+					Name:  "ci",
+					Build: latest.BuildConfig{Artifacts: []*latest.Artifact{{ImageName: "gcr.io/{{.CI_PROJECT}}/app"}}},
+				}),
+			),
+			expected: config(
+				withKubectlDeploy("k8s/my-project/*.yaml"),
+				withProfiles(latest.Profile{
+					Name:  "ci",
+					Build: latest.BuildConfig{Artifacts: []*latest.Artifact{{ImageName: "gcr.io/{{.CI_PROJECT}}/app"}}},
+				}),
+			),
+		},
+		{
+			description: "unknown key",
+			config:      config(withKubectlDeploy("k8s/{{.UNKNOWN}}/*.yaml")),
+			shouldErr:   true,
+		},
+		{
+			description: "invalid template",
+			config:      config(withKubectlDeploy("k8s/{{.ENV")),
+			shouldErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			templater := &templater{vars: test.vars}
+
+			err := templater.applyTemplates(test.config)
+
+			testutil.CheckError(t, test.shouldErr, err)
+			if !test.shouldErr {
+				testutil.CheckDeepEqual(t, test.expected, test.config)
+			}
+		})
+	}
+}
+
+func TestApplyTemplatesMaps(t *testing.T) {
+	defer func(f func() []string) { util.OSEnviron = f }(util.OSEnviron)
+	util.OSEnviron = func() []string { return []string{"VERSION=1.2"} }
+
+	version := "{{.VERSION}}"
+	artifact := &latest.Artifact{
+		ImageName: "app",
+		ArtifactType: latest.ArtifactType{
+			DockerArtifact: &latest.DockerArtifact{
+				BuildArgs: map[string]*string{"VERSION": &version},
+			},
+		},
+	}
+	pipeline := config(func(c *latest.SkaffoldPipeline) { c.Build.Artifacts = []*latest.Artifact{artifact} })
+
+	err := (&templater{}).applyTemplates(pipeline)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, "1.2", *artifact.DockerArtifact.BuildArgs["VERSION"])
+}
+
+func TestTemplateErrorNamesField(t *testing.T) {
+	err := (&templater{}).applyTemplates(config(withKubectlDeploy("a.yaml", "{{.UNKNOWN_KEY_FOR_TEST}}")))
+
+	testutil.CheckDeepEqual(t, true, err != nil && strings.Contains(err.Error(), "deploy.kubectl.manifests[1]"))
+}
+
+func TestReadVarFile(t *testing.T) {
+	tmp, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmp.Write("vars.yaml", "ENV: prod\nREPLICAS: 3\n")
+
+	vars, err := readVarFile(tmp.Path("vars.yaml"))
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, map[string]string{"ENV": "prod", "REPLICAS": "3"}, vars)
+}
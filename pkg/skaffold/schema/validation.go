@@ -0,0 +1,240 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	cfg "github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	misc "github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/yamltags"
+	"github.com/sirupsen/logrus"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+// ValidationError is a problem found in a pipeline file.
+type ValidationError struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Message)
+}
+
+// Inspection is the effective pipeline, once profiles and default values are applied.
+type Inspection struct {
+	Pipeline *latest.SkaffoldPipeline
+	Origins  Origins
+	Errors   []ValidationError
+}
+
+// InspectPipeline loads a pipeline like LoadPipeline does, keeping track of the
+// sections changed by profiles, and validates it.
+func InspectPipeline(out io.Writer, filename string, opts *cfg.SkaffoldOptions) (*Inspection, error) {
+	l := newPipelineLoader(out, opts)
+	l.origins = Origins{}
+	l.validate = true
+
+	config, err := l.loadPipeline(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := l.errors
+	if err := yamltags.ProcessStruct(config); err != nil {
+		if fieldErrs, ok := err.(yamltags.FieldErrors); ok {
+			for _, fieldErr := range fieldErrs {
+				errs = append(errs, ValidationError{File: filename, Message: fieldErr.Error()})
+			}
+		} else {
+			errs = append(errs, ValidationError{File: filename, Message: err.Error()})
+		}
+	}
+
+	return &Inspection{
+		Pipeline: config,
+		Origins:  l.origins,
+		Errors:   errs,
+	}, nil
+}
+
+// validateFile checks that the files referenced by a pipeline file exist.
+// Relative paths are resolved against dir. Each error points to the line
+// of the first field of the file that holds the faulty value.
+func validateFile(filename, dir string, c *latest.SkaffoldPipeline) []ValidationError {
+	var root yaml.Node
+	if source, err := misc.ReadConfiguration(filename); err == nil {
+		if err := yaml.Unmarshal(source, &root); err != nil {
+			logrus.Debugf("unable to find line numbers in %s: %s", filename, err)
+		}
+	}
+
+	var errs []ValidationError
+	report := func(message string, fields ...field) {
+		errs = append(errs, ValidationError{
+			File:    filename,
+			Line:    findLine(&root, fields...),
+			Message: message,
+		})
+	}
+
+	for _, a := range c.Build.Artifacts {
+		context := a.Workspace
+		if context == "" {
+			context = "."
+		}
+		workspace := resolvePath(dir, context)
+		if !exists(workspace) {
+			report(fmt.Sprintf("context %s of image %s doesn't exist", context, a.ImageName), field{key: "context", value: a.Workspace}, field{key: "image", value: a.ImageName})
+			continue
+		}
+
+		if a.DockerArtifact == nil && a.ArtifactType != (latest.ArtifactType{}) {
+			continue
+		}
+		dockerfile := constants.DefaultDockerfilePath
+		if a.DockerArtifact != nil && a.DockerArtifact.DockerfilePath != "" {
+			dockerfile = a.DockerArtifact.DockerfilePath
+		}
+		if !exists(resolvePath(workspace, dockerfile)) {
+			report(fmt.Sprintf("dockerfile %s of image %s doesn't exist", dockerfile, a.ImageName), field{key: "dockerfile", value: dockerfile}, field{key: "image", value: a.ImageName})
+		}
+	}
+
+	for _, test := range c.Test {
+		for _, pattern := range test.StructureTests {
+			if !matchesFiles(dir, pattern) {
+				report(fmt.Sprintf("structure tests %s don't match any file", pattern), field{key: "structureTests", value: pattern})
+			}
+		}
+	}
+
+	if kubectl := c.Deploy.KubectlDeploy; kubectl != nil {
+		for _, pattern := range kubectl.Manifests {
+			if !matchesFiles(dir, pattern) {
+				report(fmt.Sprintf("manifests %s don't match any file", pattern), field{key: "manifests", value: pattern})
+			}
+		}
+	}
+
+	if helm := c.Deploy.HelmDeploy; helm != nil {
+		for _, release := range helm.Releases {
+			if !exists(resolvePath(dir, release.ChartPath)) {
+				report(fmt.Sprintf("chart %s of release %s doesn't exist", release.ChartPath, release.Name), field{key: "chartPath", value: release.ChartPath}, field{key: "name", value: release.Name})
+			}
+			for _, values := range release.ValuesFiles {
+				if !exists(resolvePath(dir, values)) {
+					report(fmt.Sprintf("values file %s of release %s doesn't exist", values, release.Name), field{key: "valuesFiles", value: values}, field{key: "name", value: release.Name})
+				}
+			}
+		}
+	}
+
+	if kustomize := c.Deploy.KustomizeDeploy; kustomize != nil {
+		path := kustomize.KustomizePath
+		if path == "" {
+			path = constants.DefaultKustomizationPath
+		}
+		if !exists(filepath.Join(resolvePath(dir, path), "kustomization.yaml")) {
+			report(fmt.Sprintf("no kustomization.yaml found in %s", path), field{key: "path", value: kustomize.KustomizePath}, field{key: "kustomize", anyValue: true})
+		}
+	}
+
+	return errs
+}
+
+// field is a key of a pipeline file, with the value it holds. With anyValue,
+// the key matches whatever its value.
+type field struct {
+	key      string
+	value    string
+	anyValue bool
+}
+
+// findLine returns the line of the first field found in a parsed YAML
+// document, trying the fields in order, or 0 if none is found.
+func findLine(root *yaml.Node, fields ...field) int {
+	for _, f := range fields {
+		if f.value == "" && !f.anyValue {
+			continue
+		}
+		if line := fieldLine(root, f); line > 0 {
+			return line
+		}
+	}
+	return 0
+}
+
+// fieldLine returns the line of the first node that holds a field, or 0.
+// Values in sequences match individually.
+func fieldLine(node *yaml.Node, f field) int {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value != f.key {
+				continue
+			}
+			if f.anyValue {
+				return key.Line
+			}
+			if value.Kind == yaml.ScalarNode && value.Value == f.value {
+				return value.Line
+			}
+			if value.Kind == yaml.SequenceNode {
+				for _, item := range value.Content {
+					if item.Kind == yaml.ScalarNode && item.Value == f.value {
+						return item.Line
+					}
+				}
+			}
+		}
+	}
+
+	for _, child := range node.Content {
+		if line := fieldLine(child, f); line > 0 {
+			return line
+		}
+	}
+	return 0
+}
+
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func matchesFiles(dir, pattern string) bool {
+	matches, err := filepath.Glob(resolvePath(dir, pattern))
+	return err == nil && len(matches) > 0
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"io/ioutil"
+	"testing"
+
+	cfg "github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+const inspectedPipeline = `apiVersion: skaffold/v1beta1
+kind: Config
+build:
+  artifacts:
+  - image: app
+    context: app
+  - image: other
+    context: other
+    docker:
+      dockerfile: Dockerfile.dev
+deploy:
+  kubectl:
+    manifests:
+    - k8s/*.yaml
+profiles:
+- name: prod
+  deploy:
+    helm:
+      releases:
+      - name: app
+        chartPath: charts/app
+- name: tag
+  patches:
+  - op: add
+    path: /build/tagPolicy
+    value:
+      sha256: {}
+`
+
+func TestInspectPipeline(t *testing.T) {
+	tmp, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmp.Write("skaffold.yaml", inspectedPipeline).
+		Write("app/Dockerfile", "FROM scratch").
+		Write("other/Dockerfile", "FROM scratch")
+
+	inspection, err := InspectPipeline(ioutil.Discard, tmp.Path("skaffold.yaml"), &cfg.SkaffoldOptions{
+		Profiles: []string{"prod", "tag"},
+	})
+	testutil.CheckError(t, false, err)
+
+	testutil.CheckDeepEqual(t, Origins{
+		"deploy": {"prod"},
+		"build":  {"tag"},
+	}, inspection.Origins)
+	testutil.CheckDeepEqual(t, "app", inspection.Pipeline.Deploy.HelmDeploy.Releases[0].Name)
+}
+
+func TestInspectPipelineReportsAllInvalidFields(t *testing.T) {
+	tmp, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmp.Write("skaffold.yaml", `apiVersion: skaffold/v1beta1
+kind: Config
+portForward:
+- resourceType: job
+  resourceName: web
+  port: 70000
+`)
+
+	inspection, err := InspectPipeline(ioutil.Discard, tmp.Path("skaffold.yaml"), &cfg.SkaffoldOptions{})
+	testutil.CheckError(t, false, err)
+
+	testutil.CheckDeepEqual(t, []ValidationError{
+		{File: tmp.Path("skaffold.yaml"), Message: `portForward[0].resourceType: "job" should be one of service, deployment, pod`},
+		{File: tmp.Path("skaffold.yaml"), Message: "portForward[0].port: 70000 should be at most 65535"},
+	}, inspection.Errors)
+}
+
+func TestValidateFile(t *testing.T) {
+	tmp, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+	tmp.Write("skaffold.yaml", inspectedPipeline).
+		Write("app/Dockerfile", "FROM scratch").
+		Write("other/Dockerfile", "FROM scratch")
+
+	parsed, err := ParseConfig(tmp.Path("skaffold.yaml"), false)
+	testutil.CheckError(t, false, err)
+	config := parsed.(*latest.SkaffoldPipeline)
+
+	errs := validateFile(tmp.Path("skaffold.yaml"), tmp.Root(), config)
+
+	testutil.CheckDeepEqual(t, []ValidationError{
+		{File: tmp.Path("skaffold.yaml"), Line: 10, Message: "dockerfile Dockerfile.dev of image other doesn't exist"},
+		{File: tmp.Path("skaffold.yaml"), Line: 14, Message: "manifests k8s/*.yaml don't match any file"},
+	}, errs)
+
+	tmp.Write("other/Dockerfile.dev", "FROM scratch").
+		Write("k8s/deployment.yaml", "")
+	errs = validateFile(tmp.Path("skaffold.yaml"), tmp.Root(), config)
+
+	testutil.CheckDeepEqual(t, 0, len(errs))
+}
+
+func TestFindLine(t *testing.T) {
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(`build:
+  artifacts:
+  - image: app-v2 # app
+    context: app
+deploy:
+  kubectl:
+    manifests: [k8s/app.yaml, k8s/db.yaml]
+`), &root)
+	testutil.CheckError(t, false, err)
+
+	testutil.CheckDeepEqual(t, 4, findLine(&root, field{key: "context", value: "app"}))
+	testutil.CheckDeepEqual(t, 0, findLine(&root, field{key: "image", value: "app"}))
+	testutil.CheckDeepEqual(t, 3, findLine(&root, field{key: "context"}, field{key: "image", value: "app-v2"}))
+	testutil.CheckDeepEqual(t, 7, findLine(&root, field{key: "manifests", value: "k8s/db.yaml"}))
+	testutil.CheckDeepEqual(t, 6, findLine(&root, field{key: "kubectl", anyValue: true}))
+	testutil.CheckDeepEqual(t, 0, findLine(&root, field{key: "missing", value: "app"}))
+}
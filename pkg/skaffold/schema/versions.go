@@ -25,6 +25,7 @@ import (
 	yaml "gopkg.in/yaml.v2"
 
 	apiversion "github.com/GoogleContainerTools/skaffold/pkg/skaffold/apiversion"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/jsonschema"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/util"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/v1alpha1"
@@ -69,6 +70,12 @@ func (v *versions) Find(apiVersion string) (func() util.VersionedConfig, bool) {
 
 // ParseConfig reads a configuration file.
 func ParseConfig(filename string, applyDefaults bool) (util.VersionedConfig, error) {
+	return parseConfig(filename, applyDefaults, true)
+}
+
+// parseConfig reads a configuration file. Without validate, the yamltags are not
+// processed, so that the caller can report all the invalid fields at once.
+func parseConfig(filename string, applyDefaults, validate bool) (util.VersionedConfig, error) {
 	buf, err := misc.ReadConfiguration(filename)
 	if err != nil {
 		return nil, errors.Wrap(err, "read skaffold config")
@@ -89,8 +96,10 @@ func ParseConfig(filename string, applyDefaults bool) (util.VersionedConfig, err
 		return nil, errors.Wrap(err, "unable to parse config")
 	}
 
-	if err := yamltags.ProcessStruct(cfg); err != nil {
-		return nil, errors.Wrap(err, "invalid config")
+	if validate {
+		if err := yamltags.ProcessStruct(cfg); err != nil {
+			return nil, errors.Wrap(err, "invalid config")
+		}
 	}
 
 	return cfg, nil
@@ -123,3 +132,22 @@ func UpgradeToLatest(out io.Writer, vc util.VersionedConfig) (util.VersionedConf
 
 	return vc, nil
 }
+
+// GetJSONSchema generates the JSON Schema for a given api version.
+func GetJSONSchema(apiVersion string) ([]byte, error) {
+	factory, present := schemaVersions.Find(apiVersion)
+	if !present {
+		return nil, errors.Errorf("unknown api version: '%s'", apiVersion)
+	}
+
+	return jsonschema.Generate(apiVersion, factory())
+}
+
+// APIVersions lists the supported api versions.
+func APIVersions() []string {
+	var apiVersions []string
+	for _, version := range schemaVersions {
+		apiVersions = append(apiVersions, version.apiVersion)
+	}
+	return apiVersions
+}
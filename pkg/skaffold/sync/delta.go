@@ -0,0 +1,126 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	gosync "sync"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+)
+
+// Delta accumulates, for each image, the files synced since the image was built.
+// It's used to bring new pods up to date with the pods that were synced.
+type Delta struct {
+	gosync.Mutex
+	items map[string]*Item
+}
+
+// NewDelta creates an empty Delta.
+func NewDelta() *Delta {
+	return &Delta{
+		items: map[string]*Item{},
+	}
+}
+
+// Add records the files of a sync.
+// A copied file cancels a previous deletion of the same file and vice versa.
+func (d *Delta) Add(s *Item) {
+	d.Lock()
+	defer d.Unlock()
+
+	acc, found := d.items[s.Image]
+	if !found {
+		acc = &Item{
+			Image:  s.Image,
+			Copy:   map[string]string{},
+			Delete: map[string]string{},
+		}
+		d.items[s.Image] = acc
+	}
+
+	for src, dst := range s.Copy {
+		acc.Copy[src] = dst
+		delete(acc.Delete, src)
+	}
+	for src, dst := range s.Delete {
+		acc.Delete[src] = dst
+		delete(acc.Copy, src)
+	}
+	acc.Hooks = s.Hooks
+}
+
+// Get returns a copy of the files synced to an image, or nil if nothing was synced.
+func (d *Delta) Get(image string) *Item {
+	d.Lock()
+	defer d.Unlock()
+
+	acc, found := d.items[image]
+	if !found {
+		return nil
+	}
+
+	item := &Item{
+		Image:  acc.Image,
+		Copy:   map[string]string{},
+		Delete: map[string]string{},
+		Hooks:  acc.Hooks,
+	}
+	for src, dst := range acc.Copy {
+		item.Copy[src] = dst
+	}
+	for src, dst := range acc.Delete {
+		item.Delete[src] = dst
+	}
+
+	return item
+}
+
+// Reset forgets the files synced to an image, usually because it was rebuilt.
+func (d *Delta) Reset(image string) {
+	d.Lock()
+	delete(d.items, image)
+	d.Unlock()
+}
+
+// Replay copies and deletes the files of an Item in a single container,
+// then runs the onSync hooks in that container.
+func Replay(ctx context.Context, out io.Writer, pod v1.Pod, container v1.Container, s *Item,
+	copyFn, deleteFn func(context.Context, v1.Pod, v1.Container, string, string) *exec.Cmd,
+	hookFn func(context.Context, v1.Pod, v1.Container, []string) *exec.Cmd) error {
+	for src, dst := range s.Copy {
+		if err := util.RunCmd(copyFn(ctx, pod, container, src, dst)); err != nil {
+			return errors.Wrapf(err, "copying %s", src)
+		}
+	}
+
+	for src, dst := range s.Delete {
+		if err := util.RunCmd(deleteFn(ctx, pod, container, src, dst)); err != nil {
+			return errors.Wrapf(err, "deleting %s", dst)
+		}
+	}
+
+	if s.Hooks == nil {
+		return nil
+	}
+
+	return runContainerHooks(ctx, out, pod, container, s.Hooks.OnSync, hookFn)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/api/core/v1"
+)
+
+func TestDelta(t *testing.T) {
+	delta := NewDelta()
+	testutil.CheckDeepEqual(t, (*Item)(nil), delta.Get("image:tag"))
+
+	delta.Add(&Item{
+		Image:  "image:tag",
+		Copy:   map[string]string{"a.js": "/a.js", "b.js": "/b.js"},
+		Delete: map[string]string{"c.js": "/c.js"},
+	})
+	delta.Add(&Item{
+		Image:  "image:tag",
+		Copy:   map[string]string{"c.js": "/c.js"},
+		Delete: map[string]string{"b.js": "/b.js"},
+	})
+
+	testutil.CheckDeepEqual(t, &Item{
+		Image:  "image:tag",
+		Copy:   map[string]string{"a.js": "/a.js", "c.js": "/c.js"},
+		Delete: map[string]string{"b.js": "/b.js"},
+	}, delta.Get("image:tag"))
+
+	delta.Reset("image:tag")
+	testutil.CheckDeepEqual(t, (*Item)(nil), delta.Get("image:tag"))
+}
+
+func TestReplay(t *testing.T) {
+	cmdRecord := &TestCmdRecorder{}
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = cmdRecord
+
+	deleteFn := func(ctx context.Context, p v1.Pod, c v1.Container, src, dst string) *exec.Cmd {
+		return exec.CommandContext(ctx, "rm", dst)
+	}
+
+	err := Replay(context.Background(), &bytes.Buffer{}, *pod, pod.Spec.Containers[0], &Item{
+		Image:  "gcr.io/k8s-skaffold:123",
+		Copy:   map[string]string{"a.js": "/a.js"},
+		Delete: map[string]string{"b.js": "/b.js"},
+		Hooks: &Hooks{
+			OnSync: []latest.SyncHook{{Command: []string{"kill", "-HUP", "1"}}},
+		},
+	}, fakeCmd, deleteFn, fakeHookCmd)
+
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{
+		"copy a.js /a.js",
+		"rm /b.js",
+		"exec podname container_name kill -HUP 1",
+	}, cmdRecord.cmds)
+}
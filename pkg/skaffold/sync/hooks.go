@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+)
+
+// RunBeforeHooks runs, on the host, the hooks that precede the sync of an Item.
+func RunBeforeHooks(ctx context.Context, out io.Writer, s *Item) error {
+	if s.Hooks == nil {
+		return nil
+	}
+
+	for _, hook := range s.Hooks.Before {
+		if len(hook.Command) == 0 {
+			return errors.New("sync hook has an empty command")
+		}
+
+		cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+		cmd.Dir = s.Hooks.Workspace
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if err := util.RunCmd(cmd); err != nil {
+			return errors.Wrapf(err, "running %v", hook.Command)
+		}
+	}
+
+	return nil
+}
+
+// RunOnSyncHooks runs the onSync hooks of an Item inside each container running the synced image.
+// The output of each hook is prefixed with the pod's and the container's name.
+func RunOnSyncHooks(ctx context.Context, out io.Writer, s *Item, cmdFn func(context.Context, v1.Pod, v1.Container, []string) *exec.Cmd) error {
+	if s.Hooks == nil || len(s.Hooks.OnSync) == 0 {
+		return nil
+	}
+
+	return forEachContainer(s.Image, func(p v1.Pod, c v1.Container) error {
+		return runContainerHooks(ctx, out, p, c, s.Hooks.OnSync, cmdFn)
+	})
+}
+
+func runContainerHooks(ctx context.Context, out io.Writer, p v1.Pod, c v1.Container, hooks []latest.SyncHook, cmdFn func(context.Context, v1.Pod, v1.Container, []string) *exec.Cmd) error {
+	w := &prefixWriter{
+		out:         out,
+		prefix:      prefix(p, c),
+		atLineStart: true,
+	}
+
+	for _, hook := range hooks {
+		if len(hook.Command) == 0 {
+			return errors.New("sync hook has an empty command")
+		}
+
+		cmd := cmdFn(ctx, p, c, hook.Command)
+		cmd.Stdout = w
+		cmd.Stderr = w
+		if err := util.RunCmd(cmd); err != nil {
+			return errors.Wrapf(err, "running %v in pod %s", hook.Command, p.Name)
+		}
+	}
+
+	return nil
+}
+
+func prefix(pod v1.Pod, container v1.Container) string {
+	if pod.Name != container.Name {
+		return fmt.Sprintf("[%s %s]", pod.Name, container.Name)
+	}
+	return fmt.Sprintf("[%s]", container.Name)
+}
+
+// prefixWriter prefixes every line written to it.
+type prefixWriter struct {
+	out         io.Writer
+	prefix      string
+	atLineStart bool
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		if w.atLineStart {
+			if _, err := fmt.Fprintf(w.out, "%s ", w.prefix); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := w.out.Write(line); err != nil {
+			return 0, err
+		}
+
+		w.atLineStart = bytes.HasSuffix(line, []byte("\n"))
+	}
+
+	return len(p), nil
+}
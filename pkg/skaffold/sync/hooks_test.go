@@ -0,0 +1,127 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	pkgkubernetes "github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func fakeHookCmd(ctx context.Context, p v1.Pod, c v1.Container, command []string) *exec.Cmd {
+	return exec.CommandContext(ctx, "exec", append([]string{p.Name, c.Name}, command...)...)
+}
+
+func TestRunOnSyncHooks(t *testing.T) {
+	var tests = []struct {
+		description string
+		image       string
+		hooks       *Hooks
+		cmdErr      error
+		expected    []string
+		shouldErr   bool
+	}{
+		{
+			description: "no hooks",
+			image:       "gcr.io/k8s-skaffold:123",
+		},
+		{
+			description: "run hooks in matching containers",
+			image:       "gcr.io/k8s-skaffold:123",
+			hooks: &Hooks{
+				OnSync: []latest.SyncHook{
+					{Command: []string{"kill", "-HUP", "1"}},
+					{Command: []string{"npm", "run", "build"}},
+				},
+			},
+			expected: []string{"exec podname container_name kill -HUP 1", "exec podname container_name npm run build"},
+		},
+		{
+			description: "no matching container",
+			image:       "gcr.io/different-pod:123",
+			hooks: &Hooks{
+				OnSync: []latest.SyncHook{{Command: []string{"kill", "-HUP", "1"}}},
+			},
+		},
+		{
+			description: "empty command",
+			image:       "gcr.io/k8s-skaffold:123",
+			hooks: &Hooks{
+				OnSync: []latest.SyncHook{{}},
+			},
+			shouldErr: true,
+		},
+		{
+			description: "failing hook",
+			image:       "gcr.io/k8s-skaffold:123",
+			hooks: &Hooks{
+				OnSync: []latest.SyncHook{{Command: []string{"false"}}},
+			},
+			cmdErr:    fmt.Errorf(""),
+			shouldErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmdRecord := &TestCmdRecorder{err: test.cmdErr}
+			defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+			util.DefaultExecCommand = cmdRecord
+
+			defer func(c func() (kubernetes.Interface, error)) { pkgkubernetes.Client = c }(pkgkubernetes.GetClientset)
+			pkgkubernetes.Client = func() (kubernetes.Interface, error) {
+				return fake.NewSimpleClientset(pod), nil
+			}
+
+			err := RunOnSyncHooks(context.Background(), &bytes.Buffer{}, &Item{Image: test.image, Hooks: test.hooks}, fakeHookCmd)
+
+			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, cmdRecord.cmds)
+		})
+	}
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := &prefixWriter{
+		out:         &out,
+		prefix:      "[pod container]",
+		atLineStart: true,
+	}
+
+	for _, chunk := range []string{"first line\nsec", "ond line\n", "\nlast"} {
+		fmt.Fprint(w, chunk)
+	}
+
+	expected := strings.Join([]string{
+		"[pod container] first line",
+		"[pod container] second line",
+		"[pod container] ",
+		"[pod container] last",
+	}, "\n")
+	testutil.CheckDeepEqual(t, expected, out.String())
+}
@@ -19,8 +19,10 @@ package kubectl
 import (
 	"context"
 	"fmt"
+	"io"
 	"os/exec"
 
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/sync"
 
 	"github.com/pkg/errors"
@@ -28,9 +30,31 @@ import (
 	"k8s.io/api/core/v1"
 )
 
-type Syncer struct{}
+type Syncer struct {
+	synced *sync.Delta
+}
+
+// NewSyncer creates a Syncer that copies files with kubectl.
+func NewSyncer() *Syncer {
+	return &Syncer{
+		synced: sync.NewDelta(),
+	}
+}
+
+// NewBackSyncer creates a BackSyncer that lists and copies files with kubectl.
+func NewBackSyncer() *sync.BackSyncer {
+	return sync.NewBackSyncer(listFilesFn, copyBackFileFn)
+}
+
+func (k *Syncer) Sync(ctx context.Context, out io.Writer, s *sync.Item) error {
+	if err := sync.RunBeforeHooks(ctx, out, s); err != nil {
+		return errors.Wrap(err, "running hooks before sync")
+	}
+
+	// The files are recorded before they are copied so that the pods
+	// started while they are copied get them too.
+	k.synced.Add(s)
 
-func (k *Syncer) Sync(ctx context.Context, s *sync.Item) error {
 	logrus.Infoln("Copying files:", s.Copy, "to", s.Image)
 
 	if err := sync.Perform(ctx, s.Image, s.Copy, copyFileFn); err != nil {
@@ -43,9 +67,30 @@ func (k *Syncer) Sync(ctx context.Context, s *sync.Item) error {
 		return errors.Wrap(err, "deleting files")
 	}
 
+	if err := sync.RunOnSyncHooks(ctx, out, s, execHookFn); err != nil {
+		return errors.Wrap(err, "running onSync hooks")
+	}
+
 	return nil
 }
 
+// Replay copies the files synced since the last build into a new container.
+func (k *Syncer) Replay(ctx context.Context, out io.Writer, pod *v1.Pod, container v1.Container) error {
+	s := k.synced.Get(container.Image)
+	if s == nil {
+		return nil
+	}
+
+	color.Default.Fprintf(out, "Resyncing %d files to pod %s\n", len(s.Copy)+len(s.Delete), pod.Name)
+
+	return sync.Replay(ctx, out, *pod, container, s, copyFileFn, deleteFileFn, execHookFn)
+}
+
+// Reset forgets the files synced to an image.
+func (k *Syncer) Reset(image string) {
+	k.synced.Reset(image)
+}
+
 func deleteFileFn(ctx context.Context, pod v1.Pod, container v1.Container, src, dst string) *exec.Cmd {
 	return exec.CommandContext(ctx, "kubectl", "exec", pod.Name, "--namespace", pod.Namespace, "-c", container.Name, "--", "rm", "-rf", dst)
 }
@@ -53,3 +98,16 @@ func deleteFileFn(ctx context.Context, pod v1.Pod, container v1.Container, src,
 func copyFileFn(ctx context.Context, pod v1.Pod, container v1.Container, src, dst string) *exec.Cmd {
 	return exec.CommandContext(ctx, "kubectl", "cp", src, fmt.Sprintf("%s/%s:%s", pod.Namespace, pod.Name, dst), "-c", container.Name)
 }
+
+func execHookFn(ctx context.Context, pod v1.Pod, container v1.Container, command []string) *exec.Cmd {
+	args := append([]string{"exec", pod.Name, "--namespace", pod.Namespace, "-c", container.Name, "--"}, command...)
+	return exec.CommandContext(ctx, "kubectl", args...)
+}
+
+func listFilesFn(ctx context.Context, pod v1.Pod, container v1.Container, src string) *exec.Cmd {
+	return exec.CommandContext(ctx, "kubectl", "exec", pod.Name, "--namespace", pod.Namespace, "-c", container.Name, "--", "find", src, "-type", "f", "-exec", "md5sum", "{}", "+")
+}
+
+func copyBackFileFn(ctx context.Context, pod v1.Pod, container v1.Container, src, dst string) *exec.Cmd {
+	return exec.CommandContext(ctx, "kubectl", "cp", fmt.Sprintf("%s/%s:%s", pod.Namespace, pod.Name, src), dst, "-c", container.Name)
+}
@@ -19,6 +19,7 @@ package sync
 import (
 	"context"
 	"fmt"
+	"io"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -27,6 +28,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
@@ -37,27 +39,46 @@ import (
 )
 
 type Syncer interface {
-	Sync(context.Context, *Item) error
+	Sync(context.Context, io.Writer, *Item) error
+
+	// Replay brings a new container up to date with the files synced since its image was built.
+	Replay(context.Context, io.Writer, *v1.Pod, v1.Container) error
+
+	// Reset forgets the files synced to an image.
+	Reset(image string)
 }
 
 type Item struct {
 	Image  string
 	Copy   map[string]string
 	Delete map[string]string
+	Hooks  *Hooks
+}
+
+// Hooks are the commands run when the files of an Item are synced.
+type Hooks struct {
+	Workspace string
+	Before    []latest.SyncHook
+	OnSync    []latest.SyncHook
 }
 
 func NewItem(a *latest.Artifact, e watch.Events, builds []build.Artifact) (*Item, error) {
 	// If there are no changes, short circuit and don't sync anything
-	if !e.HasChanged() || len(a.Sync) == 0 {
+	if !e.HasChanged() || (len(a.Sync) == 0 && len(a.InferSync) == 0) {
 		return nil, nil
 	}
 
-	toCopy, err := intersect(a.Workspace, a.Sync, append(e.Added, e.Modified...))
+	syncMap, err := syncMapForArtifact(a, e)
+	if err != nil {
+		return nil, errors.Wrap(err, "inferring sync map")
+	}
+
+	toCopy, err := intersect(a.Workspace, syncMap, append(e.Added, e.Modified...))
 	if err != nil {
 		return nil, errors.Wrap(err, "intersecting sync map and added, modified files")
 	}
 
-	toDelete, err := intersect(a.Workspace, a.Sync, e.Deleted)
+	toDelete, err := intersect(a.Workspace, syncMap, e.Deleted)
 	if err != nil {
 		return nil, errors.Wrap(err, "intersecting sync map and deleted files")
 	}
@@ -72,11 +93,102 @@ func NewItem(a *latest.Artifact, e watch.Events, builds []build.Artifact) (*Item
 		return nil, fmt.Errorf("could not find latest tag for image %s in builds: %v", a.ImageName, builds)
 	}
 
-	return &Item{
+	item := &Item{
 		Image:  tag,
 		Copy:   toCopy,
 		Delete: toDelete,
-	}, nil
+	}
+
+	if a.SyncHooks != nil {
+		item.Hooks = &Hooks{
+			Workspace: a.Workspace,
+			Before:    a.SyncHooks.Before,
+			OnSync:    a.SyncHooks.OnSync,
+		}
+	}
+
+	return item, nil
+}
+
+// syncMapForArtifact completes the user defined sync map with the destinations
+// inferred from the Dockerfile, for the changed files that match the inferSync patterns.
+// Files that are copied to more than one destination are left out and will trigger a rebuild.
+func syncMapForArtifact(a *latest.Artifact, e watch.Events) (map[string]string, error) {
+	if len(a.InferSync) == 0 || a.DockerArtifact == nil {
+		return a.Sync, nil
+	}
+
+	syncMap := map[string]string{}
+	for src, dst := range a.Sync {
+		syncMap[src] = dst
+	}
+
+	var toInfer []string
+	for _, f := range append(append(e.Added, e.Modified...), e.Deleted...) {
+		relPath, err := filepath.Rel(a.Workspace, f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "changed file %s can't be found relative to context %s", f, a.Workspace)
+		}
+
+		inferred, err := matchesAny(a.InferSync, relPath)
+		if err != nil {
+			return nil, err
+		}
+		if !inferred {
+			continue
+		}
+
+		manual, err := matchesAny(keys(a.Sync), relPath)
+		if err != nil {
+			return nil, err
+		}
+		if !manual {
+			toInfer = append(toInfer, relPath)
+		}
+	}
+
+	if len(toInfer) == 0 {
+		return syncMap, nil
+	}
+
+	destinations, err := docker.SyncDestinations(a.Workspace, a.DockerArtifact, toInfer)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, relPath := range toInfer {
+		switch dsts := destinations[relPath]; len(dsts) {
+		case 0:
+			logrus.Infof("Changed file %s is not copied by the Dockerfile", relPath)
+		case 1:
+			syncMap[relPath] = dsts[0]
+		default:
+			logrus.Infof("Changed file %s is copied to several destinations %v", relPath, dsts)
+		}
+	}
+
+	return syncMap, nil
+}
+
+func matchesAny(patterns []string, relPath string) (bool, error) {
+	for _, p := range patterns {
+		match, err := doublestar.PathMatch(filepath.FromSlash(p), relPath)
+		if err != nil {
+			return false, errors.Wrapf(err, "pattern error for %s", relPath)
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func keys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
 }
 
 func latestTag(image string, builds []build.Artifact) string {
@@ -135,6 +247,31 @@ func Perform(ctx context.Context, image string, files map[string]string, cmdFn f
 		return nil
 	}
 
+	synced := map[string]bool{}
+
+	if err := forEachContainer(image, func(p v1.Pod, c v1.Container) error {
+		for src, dst := range files {
+			cmd := cmdFn(ctx, p, c, src, dst)
+			if err := util.RunCmd(cmd); err != nil {
+				return err
+			}
+
+			synced[src] = true
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if len(synced) != len(files) {
+		return errors.New("couldn't sync all the files")
+	}
+
+	return nil
+}
+
+// forEachContainer calls fn for every container, in every pod, that runs the given image.
+func forEachContainer(image string, fn func(v1.Pod, v1.Container) error) error {
 	client, err := kubernetes.Client()
 	if err != nil {
 		return errors.Wrap(err, "getting k8s client")
@@ -145,28 +282,17 @@ func Perform(ctx context.Context, image string, files map[string]string, cmdFn f
 		return errors.Wrap(err, "getting pods")
 	}
 
-	synced := map[string]bool{}
-
 	for _, p := range pods.Items {
 		for _, c := range p.Spec.Containers {
 			if c.Image != image {
 				continue
 			}
 
-			for src, dst := range files {
-				cmd := cmdFn(ctx, p, c, src, dst)
-				if err := util.RunCmd(cmd); err != nil {
-					return err
-				}
-
-				synced[src] = true
+			if err := fn(p, c); err != nil {
+				return err
 			}
 		}
 	}
 
-	if len(synced) != len(files) {
-		return errors.New("couldn't sync all the files")
-	}
-
 	return nil
 }
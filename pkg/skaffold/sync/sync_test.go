@@ -273,6 +273,107 @@ func TestNewSyncItem(t *testing.T) {
 
 }
 
+func TestNewSyncItemInferred(t *testing.T) {
+	var tests = []struct {
+		description string
+		sync        map[string]string
+		evt         watch.Events
+		expected    *Item
+	}{
+		{
+			description: "infer destinations",
+			evt: watch.Events{
+				Modified: []string{"server.js", filepath.Join("static", "index.html")},
+				Deleted:  []string{filepath.Join("static", "old.html")},
+			},
+			expected: &Item{
+				Image: "test:123",
+				Copy: map[string]string{
+					"server.js":                           "/app/server.js",
+					filepath.Join("static", "index.html"): "/var/www/index.html",
+				},
+				Delete: map[string]string{
+					filepath.Join("static", "old.html"): "/var/www/old.html",
+				},
+			},
+		},
+		{
+			description: "manual sync rules take precedence",
+			sync: map[string]string{
+				"server.js": "/override/server.js",
+			},
+			evt: watch.Events{
+				Modified: []string{"server.js"},
+			},
+			expected: &Item{
+				Image: "test:123",
+				Copy: map[string]string{
+					"server.js": "/override/server.js",
+				},
+				Delete: map[string]string{},
+			},
+		},
+		{
+			description: "file not matching infer patterns triggers a rebuild",
+			evt: watch.Events{
+				Modified: []string{"package.json"},
+			},
+		},
+		{
+			description: "file not copied triggers a rebuild",
+			evt: watch.Events{
+				Modified: []string{"README.js"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			tmpDir, cleanup := testutil.NewTempDir(t)
+			defer cleanup()
+
+			tmpDir.Write("Dockerfile", "FROM scratch\nWORKDIR /app\nCOPY server.js package.json ./\nCOPY static /var/www\n")
+
+			var evt watch.Events
+			for _, f := range test.evt.Modified {
+				evt.Modified = append(evt.Modified, tmpDir.Path(f))
+			}
+			for _, f := range test.evt.Deleted {
+				evt.Deleted = append(evt.Deleted, tmpDir.Path(f))
+			}
+
+			var expected *Item
+			if test.expected != nil {
+				expected = &Item{
+					Image:  test.expected.Image,
+					Copy:   map[string]string{},
+					Delete: map[string]string{},
+				}
+				for src, dst := range test.expected.Copy {
+					expected.Copy[tmpDir.Path(src)] = dst
+				}
+				for src, dst := range test.expected.Delete {
+					expected.Delete[tmpDir.Path(src)] = dst
+				}
+			}
+
+			actual, err := NewItem(&latest.Artifact{
+				ImageName: "test",
+				Workspace: tmpDir.Root(),
+				Sync:      test.sync,
+				InferSync: []string{"**/*.js", "**/*.html"},
+				ArtifactType: latest.ArtifactType{
+					DockerArtifact: &latest.DockerArtifact{
+						DockerfilePath: "Dockerfile",
+					},
+				},
+			}, evt, []build.Artifact{{ImageName: "test", Tag: "test:123"}})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, expected, actual)
+		})
+	}
+}
+
 func TestIntersect(t *testing.T) {
 	var tests = []struct {
 		description  string
@@ -0,0 +1,305 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/constants"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/watch"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+)
+
+// BackSyncer copies the files that change inside containers back to the workspace.
+// The first listing of a container is only used as a reference: only the files
+// added or modified afterwards are copied back. Deletions are not propagated.
+// Local files that already have the same content, like the files synced to the
+// containers, and local files modified since the previous listing are kept.
+type BackSyncer struct {
+	gosync.Mutex
+
+	listFn func(context.Context, v1.Pod, v1.Container, string) *exec.Cmd
+	copyFn func(context.Context, v1.Pod, v1.Container, string, string) *exec.Cmd
+
+	images   map[string]string
+	listings map[string]listing
+	written  map[string]time.Time
+}
+
+// listing is the checksums of the files of a container, by path, at a given time.
+type listing struct {
+	checksums map[string]string
+	time      time.Time
+}
+
+// NewBackSyncer creates a BackSyncer. listFn lists the files under a path in a container,
+// with one `checksum  path` per line. copyFn copies a file from a container to a local path.
+func NewBackSyncer(listFn func(context.Context, v1.Pod, v1.Container, string) *exec.Cmd, copyFn func(context.Context, v1.Pod, v1.Container, string, string) *exec.Cmd) *BackSyncer {
+	return &BackSyncer{
+		listFn:   listFn,
+		copyFn:   copyFn,
+		images:   map[string]string{},
+		listings: map[string]listing{},
+		written:  map[string]time.Time{},
+	}
+}
+
+// SetImage sets the latest tag built for an image.
+func (b *BackSyncer) SetImage(imageName, tag string) {
+	b.Lock()
+	b.images[imageName] = tag
+	b.Unlock()
+}
+
+// Start periodically copies back the files changed in the containers
+// of the given artifacts, until the context is cancelled.
+func (b *BackSyncer) Start(ctx context.Context, artifacts []*latest.Artifact) {
+	var toSyncBack []*latest.Artifact
+	for _, a := range artifacts {
+		if len(a.SyncBack) > 0 {
+			toSyncBack = append(toSyncBack, a)
+		}
+	}
+	if len(toSyncBack) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(constants.SyncBackInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, a := range toSyncBack {
+					if err := b.SyncBack(ctx, a); err != nil {
+						logrus.Warnln("Syncing back files:", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// SyncBack copies back the files that changed, under the syncBack rules of an artifact,
+// in the containers running the latest image built for this artifact.
+func (b *BackSyncer) SyncBack(ctx context.Context, a *latest.Artifact) error {
+	if len(a.SyncBack) == 0 {
+		return nil
+	}
+
+	b.Lock()
+	image, found := b.images[a.ImageName]
+	b.Unlock()
+	if !found {
+		return nil
+	}
+
+	// A single container is enough since all of them should be in sync.
+	var synced bool
+	return forEachContainer(image, func(p v1.Pod, c v1.Container) error {
+		if synced || p.Status.Phase != v1.PodRunning {
+			return nil
+		}
+		synced = true
+
+		for _, rule := range a.SyncBack {
+			if err := b.syncBackRule(ctx, a.Workspace, rule, p, c); err != nil {
+				return errors.Wrapf(err, "syncing back %s from pod %s", rule.Src, p.Name)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *BackSyncer) syncBackRule(ctx context.Context, workspace string, rule *latest.SyncBackRule, p v1.Pod, c v1.Container) error {
+	listedAt := time.Now()
+	out, err := util.RunCmdOut(b.listFn(ctx, p, c, rule.Src))
+	if err != nil {
+		return errors.Wrap(err, "listing files")
+	}
+
+	current := listing{
+		checksums: parseChecksums(out),
+		time:      listedAt,
+	}
+
+	key := strings.Join([]string{p.Namespace, p.Name, c.Name, rule.Src}, "/")
+	b.Lock()
+	previous, found := b.listings[key]
+	b.listings[key] = current
+	b.Unlock()
+
+	if !found {
+		return nil
+	}
+
+	for file, checksum := range current.checksums {
+		if previous.checksums[file] == checksum {
+			continue
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(file, path.Clean(rule.Src)), "/")
+		if rel == "" {
+			rel = path.Base(file)
+		}
+		dst := filepath.Join(workspace, rule.Dest, filepath.FromSlash(rel))
+		if b.keepLocal(dst, checksum, previous.time) {
+			logrus.Debugf("Not syncing back %s: %s is up to date or was modified locally", file, dst)
+			continue
+		}
+
+		logrus.Infof("Syncing back %s to %s", file, dst)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return errors.Wrapf(err, "creating folder for %s", dst)
+		}
+		if err := util.RunCmd(b.copyFn(ctx, p, c, file, dst)); err != nil {
+			return errors.Wrapf(err, "copying %s", file)
+		}
+
+		if err := b.recordWritten(dst); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keepLocal says if a local file must not be overwritten by the file of a container,
+// given its checksum. It's the case if the local file has the same content, or if it
+// was modified, but not synced back, after the previous listing of the container.
+func (b *BackSyncer) keepLocal(file, checksum string, listedAt time.Time) bool {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return false
+	}
+
+	b.Lock()
+	written, found := b.written[absPath(file)]
+	b.Unlock()
+	if !stat.ModTime().Before(listedAt) && !(found && written.Equal(stat.ModTime())) {
+		return true
+	}
+
+	localChecksum, err := md5sum(file)
+	return err == nil && localChecksum == checksum
+}
+
+// md5sum computes the checksum of a file, like `md5sum` does.
+func md5sum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (b *BackSyncer) recordWritten(file string) error {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(abs)
+	if err != nil {
+		return errors.Wrapf(err, "stating %s", abs)
+	}
+
+	b.Lock()
+	b.written[abs] = stat.ModTime()
+	b.Unlock()
+
+	return nil
+}
+
+// Filter removes from watch events the files that were synced back
+// and not modified since. Those must not trigger a sync or a rebuild.
+func (b *BackSyncer) Filter(e watch.Events) watch.Events {
+	return watch.Events{
+		Added:    b.filter(e.Added),
+		Modified: b.filter(e.Modified),
+		Deleted:  e.Deleted,
+	}
+}
+
+func (b *BackSyncer) filter(files []string) []string {
+	b.Lock()
+	defer b.Unlock()
+
+	var kept []string
+	for _, file := range files {
+		if modTime, found := b.written[absPath(file)]; found {
+			if stat, err := os.Stat(file); err == nil && stat.ModTime().Equal(modTime) {
+				continue
+			}
+		}
+
+		kept = append(kept, file)
+	}
+
+	return kept
+}
+
+func absPath(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	return abs
+}
+
+// parseChecksums parses the output of `md5sum`.
+func parseChecksums(out []byte) map[string]string {
+	checksums := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "  ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		checksums[parts[1]] = parts[0]
+	}
+
+	return checksums
+}
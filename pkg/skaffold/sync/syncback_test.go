@@ -0,0 +1,129 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sync
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"os/exec"
+	"strings"
+	"testing"
+
+	pkgkubernetes "github.com/GoogleContainerTools/skaffold/pkg/skaffold/kubernetes"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/util"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/watch"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeContainerFiles lists fake checksums and copies files by writing them locally.
+type fakeContainerFiles struct {
+	listing string
+	tmpDir  *testutil.TempDir
+	copied  []string
+}
+
+func (f *fakeContainerFiles) RunCmdOut(cmd *exec.Cmd) ([]byte, error) {
+	return []byte(f.listing), nil
+}
+
+func (f *fakeContainerFiles) RunCmd(cmd *exec.Cmd) error {
+	src, dst := cmd.Args[1], cmd.Args[2]
+	f.copied = append(f.copied, src)
+	f.tmpDir.Write(strings.TrimPrefix(dst, f.tmpDir.Root()), src)
+	return nil
+}
+
+func fakeListFn(ctx context.Context, p v1.Pod, c v1.Container, src string) *exec.Cmd {
+	return exec.CommandContext(ctx, "list", src)
+}
+
+func TestSyncBack(t *testing.T) {
+	tmpDir, cleanup := testutil.NewTempDir(t)
+	defer cleanup()
+
+	files := &fakeContainerFiles{tmpDir: tmpDir}
+	defer func(c util.Command) { util.DefaultExecCommand = c }(util.DefaultExecCommand)
+	util.DefaultExecCommand = files
+
+	defer func(c func() (kubernetes.Interface, error)) { pkgkubernetes.Client = c }(pkgkubernetes.GetClientset)
+	pkgkubernetes.Client = func() (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(pod), nil
+	}
+
+	artifact := &latest.Artifact{
+		ImageName: "gcr.io/k8s-skaffold",
+		Workspace: tmpDir.Root(),
+		SyncBack: []*latest.SyncBackRule{
+			{Src: "/app/db/migrate", Dest: "db/migrate"},
+		},
+	}
+
+	backSyncer := NewBackSyncer(fakeListFn, fakeCmd)
+	backSyncer.SetImage("gcr.io/k8s-skaffold", "gcr.io/k8s-skaffold:123")
+
+	// The first listing is only a reference
+	files.listing = "aaa  /app/db/migrate/001_init.rb\n"
+	err := backSyncer.SyncBack(context.Background(), artifact)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string(nil), files.copied)
+
+	files.listing = "aaa  /app/db/migrate/001_init.rb\nbbb  /app/db/migrate/002_users.rb\n"
+	err = backSyncer.SyncBack(context.Background(), artifact)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"/app/db/migrate/002_users.rb"}, files.copied)
+
+	// Files synced back are filtered out of watch events
+	syncedBack := tmpDir.Path("db/migrate/002_users.rb")
+	other := tmpDir.Path("other.rb")
+	tmpDir.Write("other.rb", "")
+
+	events := backSyncer.Filter(watch.Events{
+		Added: []string{syncedBack, other},
+	})
+	testutil.CheckDeepEqual(t, []string{other}, events.Added)
+
+	// Files synced back are copied again when they change in the container
+	tmpDir.Write("db/migrate/003_posts.rb", "posts")
+	files.copied = nil
+	files.listing = "aaa  /app/db/migrate/001_init.rb\nccc  /app/db/migrate/002_users.rb\n"
+	err = backSyncer.SyncBack(context.Background(), artifact)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string{"/app/db/migrate/002_users.rb"}, files.copied)
+
+	// Local files modified since the last listing, or with the same content, are kept
+	tmpDir.Write("db/migrate/001_init.rb", "edited")
+	files.copied = nil
+	files.listing = "ddd  /app/db/migrate/001_init.rb\nccc  /app/db/migrate/002_users.rb\n" + md5Hex("posts") + "  /app/db/migrate/003_posts.rb\n"
+	err = backSyncer.SyncBack(context.Background(), artifact)
+	testutil.CheckErrorAndDeepEqual(t, false, err, []string(nil), files.copied)
+}
+
+func md5Hex(content string) string {
+	sum := md5.Sum([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseChecksums(t *testing.T) {
+	checksums := parseChecksums([]byte("aaa  /app/file one\nbbb  /app/other\ninvalid\n"))
+
+	testutil.CheckDeepEqual(t, map[string]string{
+		"/app/file one": "aaa",
+		"/app/other":    "bbb",
+	}, checksums)
+}
@@ -31,6 +31,7 @@ import (
 type FileMap map[string]time.Time
 
 // Stat returns the modification times for a list of files.
+// Remote files, given as http or https urls, are checked with HEAD requests.
 func Stat(deps func() ([]string, error)) (FileMap, error) {
 	state := FileMap{}
 	paths, err := deps()
@@ -38,6 +39,13 @@ func Stat(deps func() ([]string, error)) (FileMap, error) {
 		return state, errors.Wrap(err, "listing files")
 	}
 	for _, path := range paths {
+		if isURL(path) {
+			if modTime, found := remoteModTime(path); found {
+				state[path] = modTime
+			}
+			continue
+		}
+
 		stat, err := os.Stat(path)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -175,3 +175,106 @@ func checkListInMap(t *testing.T, list []string, m FileMap) {
 		t.Errorf("List and map length differ %s, %s", list, m)
 	}
 }
+
+func TestStatRemote(t *testing.T) {
+	var tests = []struct {
+		description string
+		cached      map[string]remoteFile
+		modTimes    map[string]time.Time
+		expected    FileMap
+		checked     []string
+	}{
+		{
+			description: "check remote files",
+			modTimes:    map[string]time.Time{"https://example.com/a": today},
+			expected:    FileMap{"https://example.com/a": today},
+			checked:     []string{"https://example.com/a", "https://example.com/b"},
+		},
+		{
+			description: "recently checked",
+			cached: map[string]remoteFile{
+				"https://example.com/a": {checked: time.Now(), modTime: yesterday, found: true},
+				"https://example.com/b": {checked: time.Now()},
+			},
+			modTimes: map[string]time.Time{"https://example.com/a": today},
+			expected: FileMap{"https://example.com/a": yesterday},
+		},
+		{
+			description: "keep last known modification time",
+			cached: map[string]remoteFile{
+				"https://example.com/b": {modTime: yesterday, found: true},
+			},
+			modTimes: map[string]time.Time{"https://example.com/a": today},
+			expected: FileMap{"https://example.com/a": today, "https://example.com/b": yesterday},
+			checked:  []string{"https://example.com/a", "https://example.com/b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			var checked []string
+			lastModified = func(url string) (time.Time, error) {
+				checked = append(checked, url)
+				if modTime, found := test.modTimes[url]; found {
+					return modTime, nil
+				}
+				return time.Time{}, fmt.Errorf("not found: %s", url)
+			}
+			remoteFiles = map[string]remoteFile{}
+			for url, file := range test.cached {
+				remoteFiles[url] = file
+			}
+			defer func() {
+				lastModified = headLastModified
+				remoteFiles = map[string]remoteFile{}
+			}()
+
+			state, err := Stat(func() ([]string, error) {
+				return []string{"https://example.com/a", "https://example.com/b"}, nil
+			})
+
+			testutil.CheckErrorAndDeepEqual(t, false, err, test.expected, state)
+			testutil.CheckDeepEqual(t, test.checked, checked)
+		})
+	}
+}
+
+func TestRemoteModTimeDoesntBlock(t *testing.T) {
+	started := make(chan bool)
+	release := make(chan bool)
+	lastModified = func(url string) (time.Time, error) {
+		started <- true
+		<-release
+		return today, nil
+	}
+	remoteFiles = map[string]remoteFile{
+		"https://example.com/b": {checked: time.Now(), modTime: yesterday, found: true},
+	}
+	defer func() {
+		lastModified = headLastModified
+		remoteFiles = map[string]remoteFile{}
+	}()
+
+	done := make(chan time.Time)
+	go func() {
+		modTime, _ := remoteModTime("https://example.com/a")
+		done <- modTime
+	}()
+	<-started
+
+	checked := make(chan time.Time)
+	go func() {
+		modTime, _ := remoteModTime("https://example.com/b")
+		checked <- modTime
+	}()
+
+	select {
+	case modTime := <-checked:
+		testutil.CheckDeepEqual(t, yesterday, modTime)
+	case <-time.After(5 * time.Second):
+		t.Fatal("remoteModTime blocked on a pending check of another url")
+	}
+
+	close(release)
+	testutil.CheckDeepEqual(t, today, <-done)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// remoteCheckInterval is the minimum time between two checks of the same remote file.
+const remoteCheckInterval = 30 * time.Second
+
+var (
+	// lastModified is overridden for unit testing
+	lastModified = headLastModified
+
+	remoteFilesLock sync.Mutex
+	remoteFiles     = map[string]remoteFile{}
+)
+
+type remoteFile struct {
+	checked time.Time
+	modTime time.Time
+	found   bool
+}
+
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// remoteModTime returns the modification time of a remote file.
+// To avoid hammering the server, a given url is checked at most every remoteCheckInterval.
+// If a check fails, the last known modification time is kept.
+// The lock is not held during the check so that a slow server doesn't block the other urls.
+func remoteModTime(url string) (time.Time, bool) {
+	remoteFilesLock.Lock()
+	now := time.Now()
+	file, present := remoteFiles[url]
+	if present && now.Sub(file.checked) < remoteCheckInterval {
+		remoteFilesLock.Unlock()
+		return file.modTime, file.found
+	}
+
+	// Concurrent callers use the last known value until this check is done.
+	file.checked = now
+	remoteFiles[url] = file
+	remoteFilesLock.Unlock()
+
+	modTime, err := lastModified(url)
+
+	remoteFilesLock.Lock()
+	defer remoteFilesLock.Unlock()
+
+	file = remoteFiles[url]
+	if err != nil {
+		logrus.Debugf("could not check remote dependency: %s", err)
+	} else {
+		file.modTime = modTime
+		file.found = true
+	}
+	remoteFiles[url] = file
+
+	return file.modTime, file.found
+}
+
+// headLastModified reads the Last-Modified header of a remote file.
+func headLastModified(url string) (time.Time, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	resp, err := client.Head(url)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(err, "checking %s", url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return time.Time{}, fmt.Errorf("checking %s: %s", url, resp.Status)
+	}
+
+	header := resp.Header.Get("Last-Modified")
+	if header == "" {
+		return time.Time{}, fmt.Errorf("checking %s: no Last-Modified header", url)
+	}
+
+	return http.ParseTime(header)
+}
@@ -20,39 +20,103 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
 // ProcessStruct validates and processes the provided pointer to a struct.
+// Pointers, slices and nested structs are processed recursively. All the
+// validation errors are returned, as FieldErrors.
 func ProcessStruct(s interface{}) error {
-	parentStruct := reflect.ValueOf(s).Elem()
+	var errs FieldErrors
+	processStruct(reflect.ValueOf(s).Elem(), "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// FieldError is the validation error of a field, identified by its YAML
+// path, like `build.artifacts[2].docker.dockerfile`.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+// FieldErrors lists all the validation errors of a struct.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func processStruct(parentStruct reflect.Value, path string, errs *FieldErrors) {
 	t := parentStruct.Type()
 
 	// Loop through the fields on the struct, looking for tags.
 	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
+		field := t.Field(i)
 		val := parentStruct.Field(i)
-		field := parentStruct.Type().Field(i)
-		if tags, ok := f.Tag.Lookup("yamltags"); ok {
+		fieldPath := YAMLPath(path, field)
+		if tags, ok := field.Tag.Lookup("yamltags"); ok {
 			if err := ProcessTags(tags, val, parentStruct, field); err != nil {
-				return err
+				*errs = append(*errs, FieldError{Path: fieldPath, Err: err})
 			}
 		}
-		// Recurse down the struct
-		if val.Kind() == reflect.Struct {
-			if err := ProcessStruct(val.Addr().Interface()); err != nil {
-				return err
-			}
+		processValue(val, fieldPath, errs)
+	}
+}
+
+// processValue recurses down structs, pointers and slices.
+func processValue(val reflect.Value, path string, errs *FieldErrors) {
+	switch val.Kind() {
+	case reflect.Struct:
+		processStruct(val, path, errs)
+	case reflect.Ptr:
+		if !val.IsNil() {
+			processValue(val.Elem(), path, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			processValue(val.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
 		}
 	}
-	return nil
 }
 
+// YAMLPath appends the YAML name of a field to a path. Inlined fields
+// don't appear in the path.
+func YAMLPath(path string, field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("yaml"), ",")
+	for _, option := range tag[1:] {
+		if option == "inline" {
+			return path
+		}
+	}
+
+	name := tag[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// ProcessTags processes the yamltags of a field. Tags are separated by commas.
 func ProcessTags(yamltags string, val reflect.Value, parentStruct reflect.Value, field reflect.StructField) error {
 	tags := strings.Split(yamltags, ",")
 	for _, tag := range tags {
-		tagParts := strings.Split(tag, "=")
+		tagParts := strings.SplitN(tag, "=", 2)
 		var yt YamlTag
 		switch tagParts[0] {
 		case "required":
@@ -64,6 +128,17 @@ func ProcessTags(yamltags string, val reflect.Value, parentStruct reflect.Value,
 				Field:  field,
 				Parent: parentStruct,
 			}
+		case "pattern":
+			yt = &PatternTag{}
+		case "enum":
+			yt = &EnumTag{}
+		case "min", "max":
+			yt = &RangeTag{}
+		case "skipTemplate":
+			// Used by the templating of the pipeline.
+			continue
+		default:
+			return fmt.Errorf("unknown yamltag: %s", tagParts[0])
 		}
 		if err := yt.Load(tagParts); err != nil {
 			return err
@@ -124,6 +199,104 @@ func (dt *DefaultTag) Process(val reflect.Value) error {
 	return nil
 }
 
+// PatternTag checks that a string matches a regular expression.
+// The expression can't contain commas.
+type PatternTag struct {
+	pattern *regexp.Regexp
+}
+
+func (pt *PatternTag) Load(s []string) error {
+	if len(s) != 2 {
+		return fmt.Errorf("invalid pattern tag: %v, expected pattern=regexp", s)
+	}
+	pattern, err := regexp.Compile(s[1])
+	if err != nil {
+		return fmt.Errorf("invalid pattern tag: %s", err)
+	}
+	pt.pattern = pattern
+	return nil
+}
+
+func (pt *PatternTag) Process(val reflect.Value) error {
+	if val.Kind() != reflect.String || isZeroValue(val) || isTemplate(val) {
+		return nil
+	}
+	if !pt.pattern.MatchString(val.String()) {
+		return fmt.Errorf("%q doesn't match %s", val.String(), pt.pattern)
+	}
+	return nil
+}
+
+// EnumTag checks that a string is one of a list of values, separated by `|`.
+type EnumTag struct {
+	values []string
+}
+
+func (et *EnumTag) Load(s []string) error {
+	if len(s) != 2 {
+		return fmt.Errorf("invalid enum tag: %v, expected enum=a|b", s)
+	}
+	et.values = strings.Split(s[1], "|")
+	return nil
+}
+
+func (et *EnumTag) Process(val reflect.Value) error {
+	if val.Kind() != reflect.String || isZeroValue(val) || isTemplate(val) {
+		return nil
+	}
+	for _, value := range et.values {
+		if val.String() == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q should be one of %s", val.String(), strings.Join(et.values, ", "))
+}
+
+// RangeTag checks that a number is within bounds, with `min=value` or `max=value`.
+type RangeTag struct {
+	min   bool
+	bound float64
+}
+
+func (rt *RangeTag) Load(s []string) error {
+	if len(s) != 2 {
+		return fmt.Errorf("invalid %s tag: %v, expected %s=value", s[0], s, s[0])
+	}
+	bound, err := strconv.ParseFloat(s[1], 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s tag: %s", s[0], err)
+	}
+	rt.min = s[0] == "min"
+	rt.bound = bound
+	return nil
+}
+
+func (rt *RangeTag) Process(val reflect.Value) error {
+	if isZeroValue(val) {
+		return nil
+	}
+
+	var number float64
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number = float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number = float64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		number = val.Float()
+	default:
+		return nil
+	}
+
+	if rt.min && number < rt.bound {
+		return fmt.Errorf("%v should be at least %v", number, rt.bound)
+	}
+	if !rt.min && number > rt.bound {
+		return fmt.Errorf("%v should be at most %v", number, rt.bound)
+	}
+	return nil
+}
+
 // A program can have many structs, that each have many oneOfSets
 // each oneOfSet is a map of a set name to the list of fields that belong to that set
 // only one field in that list can have a non-zero value.
@@ -151,11 +324,17 @@ func (oot *OneOfTag) Load(s []string) error {
 	}
 	oot.setName = s[1]
 
-	// Fetch the right oneOfSet for the struct.
-	structName := oot.Parent.Type().Name()
+	// Fetch the right oneOfSet for the struct. Structs of different
+	// schema versions share the same name.
+	structName := oot.Parent.Type().PkgPath() + "." + oot.Parent.Type().Name()
 	oot.oneOfSets = getOneOfSetsForStruct(structName)
 
 	// Add this field to the oneOfSet
+	for _, field := range oot.oneOfSets[oot.setName] {
+		if field == oot.Field.Name {
+			return nil
+		}
+	}
 	oot.oneOfSets[oot.setName] = append(oot.oneOfSets[oot.setName], oot.Field.Name)
 	return nil
 }
@@ -179,6 +358,11 @@ func (oot *OneOfTag) Process(val reflect.Value) error {
 	return nil
 }
 
+// isTemplate returns true for strings that are checked once templated.
+func isTemplate(val reflect.Value) bool {
+	return strings.Contains(val.String(), "{{")
+}
+
 func isZeroValue(val reflect.Value) bool {
 	zv := reflect.Zero(val.Type()).Interface()
 	return reflect.DeepEqual(zv, val.Interface())
@@ -17,6 +17,7 @@ limitations under the License.
 package yamltags
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -217,3 +218,75 @@ func TestOneOf(t *testing.T) {
 		})
 	}
 }
+
+type validatedStruct struct {
+	Image    string            `yaml:"image,omitempty" yamltags:"pattern=^[a-z]+$"`
+	Trigger  string            `yaml:"trigger,omitempty" yamltags:"enum=polling|manual"`
+	DiskSize int64             `yaml:"diskSize,omitempty" yamltags:"min=10,max=1000"`
+	Items    []*validatedItem  `yaml:"items,omitempty"`
+	Inlined  validatedInlined  `yaml:",inline"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+}
+
+type validatedItem struct {
+	Name string `yaml:"name" yamltags:"required"`
+}
+
+type validatedInlined struct {
+	Port int32 `yaml:"port,omitempty" yamltags:"max=65535"`
+}
+
+func TestValidationTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        *validatedStruct
+		expected FieldErrors
+	}{
+		{
+			name: "valid",
+			s: &validatedStruct{
+				Image:    "app",
+				Trigger:  "manual",
+				DiskSize: 100,
+				Items:    []*validatedItem{{Name: "item"}},
+				Inlined:  validatedInlined{Port: 8080},
+			},
+		},
+		{
+			name: "zero values are valid",
+			s:    &validatedStruct{},
+		},
+		{
+			name: "all errors are reported",
+			s: &validatedStruct{
+				Image:    "App",
+				Trigger:  "always",
+				DiskSize: 5,
+				Items:    []*validatedItem{{Name: "item"}, {}},
+				Inlined:  validatedInlined{Port: 70000},
+			},
+			expected: FieldErrors{
+				{Path: "image", Err: errors.New(`"App" doesn't match ^[a-z]+$`)},
+				{Path: "trigger", Err: errors.New(`"always" should be one of polling, manual`)},
+				{Path: "diskSize", Err: errors.New("5 should be at least 10")},
+				{Path: "items[1].name", Err: errors.New("required value not set")},
+				{Path: "port", Err: errors.New("70000 should be at most 65535")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ProcessStruct(tt.s)
+
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("ProcessStruct() unexpected error = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected.Error() {
+				t.Errorf("ProcessStruct() error = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+}